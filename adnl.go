@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+)
+
+// ADNL and lite API tags, little-endian as they appear on the wire.
+const (
+	tagADNLQuery                 = 0xb48bf97a
+	tagADNLAnswer                = 0x0fac8416
+	tagTCPPing                   = 0x4d082b9a
+	tagTCPPong                   = 0xdc69fb03
+	tagLiteServerQuery           = 0x798c06df
+	tagWaitMasterchainSeqno      = 0xbaeab892
+	tagGetMasterchainInfo        = 0x89b5e62e
+	tagSendMessage               = 0x690ad482
+	tagLiteServerError           = 0xbba9e148
+	tagLiteServerMasterchainInfo = 0x85832881
+	tagLiteServerSendMsgStatus   = 0x3950e597
+)
+
+// adnlConn is one side of an ADNL TCP connection; unlike liteclient.Connection it can be closed.
+type adnlConn struct {
+	conn    net.Conn
+	mu      sync.Mutex
+	encrypt cipher.Stream
+	decrypt cipher.Stream
+}
+
+func (c *adnlConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *adnlConn) send(payload []byte) error {
+	var nonce [32]byte
+	rand.Read(nonce[:])
+	h := sha256.New()
+	h.Write(nonce[:])
+	h.Write(payload)
+	b := binary.LittleEndian.AppendUint32(nil, uint32(len(payload)+64))
+	b = append(b, nonce[:]...)
+	b = append(b, payload...)
+	b = h.Sum(b)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.encrypt.XORKeyStream(b, b)
+	_, err := c.conn.Write(b)
+	return err
+}
+
+func adnlKeyID(pub ed25519.PublicKey) [32]byte {
+	return sha256.Sum256(append([]byte{0xc6, 0xb4, 0x13, 0x48}, pub...))
+}
+
+// x25519Shared derives the ADNL shared secret from our ed25519 key and the peer's ed25519 public key.
+func x25519Shared(key ed25519.PrivateKey, peer []byte) ([]byte, error) {
+	h := sha512.Sum512(key.Seed())
+	priv, err := ecdh.X25519().NewPrivateKey(h[:32])
+	if err != nil {
+		return nil, err
+	}
+	// birational map from the Edwards y coordinate: u = (1 + y) / (1 - y) mod p
+	p := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	le := append([]byte{}, peer...)
+	le[31] &= 0x7f
+	for i, j := 0, len(le)-1; i < j; i, j = i+1, j-1 {
+		le[i], le[j] = le[j], le[i]
+	}
+	y := new(big.Int).SetBytes(le)
+	num := new(big.Int).Add(big.NewInt(1), y)
+	den := new(big.Int).Sub(big.NewInt(1), y)
+	den.Mod(den, p)
+	if den.Sign() == 0 {
+		return nil, errors.New("adnl: invalid peer key")
+	}
+	u := num.Mul(num, den.ModInverse(den, p))
+	u.Mod(u, p)
+	ub := u.FillBytes(make([]byte, 32))
+	for i, j := 0, len(ub)-1; i < j; i, j = i+1, j-1 {
+		ub[i], ub[j] = ub[j], ub[i]
+	}
+	pub, err := ecdh.X25519().NewPublicKey(ub)
+	if err != nil {
+		return nil, err
+	}
+	return priv.ECDH(pub)
+}
+
+// handshakeCipher is the AES-CTR stream that protects the 160-byte session parameters.
+func handshakeCipher(shared, paramsHash []byte) (cipher.Stream, error) {
+	key := append(append([]byte{}, shared[:16]...), paramsHash[16:32]...)
+	nonce := append(append([]byte{}, paramsHash[0:4]...), shared[20:32]...)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewCTR(block, nonce), nil
+}
+
+// sessionCiphers builds the stream pair from session parameters; client reports which side we are.
+func sessionCiphers(params []byte, client bool) (encrypt, decrypt cipher.Stream, err error) {
+	rx, err := aes.NewCipher(params[0:32])
+	if err != nil {
+		return nil, nil, err
+	}
+	tx, err := aes.NewCipher(params[32:64])
+	if err != nil {
+		return nil, nil, err
+	}
+	rxStream, txStream := cipher.NewCTR(rx, params[64:80]), cipher.NewCTR(tx, params[80:96])
+	if client {
+		return txStream, rxStream, nil
+	}
+	return rxStream, txStream, nil
+}
+
+// adnlHandshake runs the client side of the handshake and returns once the server confirmed the session.
+func adnlHandshake(ctx context.Context, conn net.Conn, serverKey ed25519.PublicKey) (*adnlConn, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	params := make([]byte, 160)
+	rand.Read(params)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := x25519Shared(priv, serverKey)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(params)
+	hc, err := handshakeCipher(shared, hash[:])
+	if err != nil {
+		return nil, err
+	}
+	id := adnlKeyID(serverKey)
+	req := make([]byte, 0, 256)
+	req = append(req, id[:]...)
+	req = append(req, pub...)
+	req = append(req, hash[:]...)
+	enc := make([]byte, 160)
+	hc.XORKeyStream(enc, params)
+	req = append(req, enc...)
+
+	c := &adnlConn{conn: conn}
+	if c.encrypt, c.decrypt, err = sessionCiphers(params, true); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+	if _, err := liteclient.ParsePacket(conn, c.decrypt); err != nil {
+		return nil, fmt.Errorf("adnl handshake: %w", err)
+	}
+	return c, nil
+}
+
+// queryMasterchainInfo sends liteServer.getMasterchainInfo and returns the answer and its size in bytes.
+func (c *adnlConn) queryMasterchainInfo(ctx context.Context) (liteclient.LiteServerMasterchainInfoC, int, error) {
+	var info liteclient.LiteServerMasterchainInfoC
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+		defer c.conn.SetDeadline(time.Time{})
+	}
+	inner, _ := tl.Marshal(binary.LittleEndian.AppendUint32(nil, tagGetMasterchainInfo))
+	query, _ := tl.Marshal(append(binary.LittleEndian.AppendUint32(nil, tagLiteServerQuery), inner...))
+	var id [32]byte
+	rand.Read(id[:])
+	payload := binary.LittleEndian.AppendUint32(nil, tagADNLQuery)
+	payload = append(payload, id[:]...)
+	payload = append(payload, query...)
+	if err := c.send(payload); err != nil {
+		return info, 0, err
+	}
+	for {
+		p, err := liteclient.ParsePacket(c.conn, c.decrypt)
+		if err != nil {
+			return info, 0, err
+		}
+		if p.MagicType() != tagADNLAnswer || len(p.Payload) < 36 || !bytes.Equal(p.Payload[4:36], id[:]) {
+			continue
+		}
+		var answer []byte
+		if err := tl.Unmarshal(bytes.NewReader(p.Payload[36:]), &answer); err != nil {
+			return info, 0, err
+		}
+		if len(answer) < 4 {
+			return info, len(answer), errors.New("adnl: short answer")
+		}
+		switch binary.LittleEndian.Uint32(answer) {
+		case tagLiteServerError:
+			var lsErr liteclient.LiteServerErrorC
+			if err := tl.Unmarshal(bytes.NewReader(answer[4:]), &lsErr); err != nil {
+				return info, len(answer), err
+			}
+			return info, len(answer), lsErr
+		case tagLiteServerMasterchainInfo:
+			err := tl.Unmarshal(bytes.NewReader(answer[4:]), &info)
+			return info, len(answer), err
+		default:
+			return info, len(answer), fmt.Errorf("adnl: unexpected answer %08x", binary.LittleEndian.Uint32(answer))
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteapi"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const masterchainShard = 0x8000000000000000
+
+// AgeStats is the age bucket and seqno range an --age-buckets result read from.
+type AgeStats struct {
+	AgeBucket string `json:"age_bucket,omitempty"`
+	AgeMinSec int64  `json:"age_min_sec,omitempty"`
+	SeqnoFrom int32  `json:"seqno_from,omitempty"`
+	SeqnoTo   int32  `json:"seqno_to,omitempty"`
+}
+
+// ageBucket is a block age window; Max == 0 means open-ended (older than Min).
+type ageBucket struct {
+	Label string
+	Min   time.Duration
+	Max   time.Duration
+}
+
+// seqnoClock extrapolates seqnos from the tip's block interval where LookupBlock by utime is not served.
+type seqnoClock struct {
+	seqno    int32
+	utime    uint32
+	interval float64
+}
+
+func (b ageBucket) mode() string {
+	return "age:" + b.Label
+}
+
+func newSeqnoClock(api *liteapi.Client, timeout time.Duration) (seqnoClock, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	info, err := api.GetMasterchainInfo(ctx)
+	cancel()
+	if err != nil {
+		return seqnoClock{}, err
+	}
+	last := info.Last.Seqno
+	lastUtime, err := masterchainUtime(api, last, timeout)
+	if err != nil {
+		return seqnoClock{}, err
+	}
+	back := uint32(1000)
+	if last <= back {
+		back = last - 1
+	}
+	clock := seqnoClock{seqno: int32(last), utime: lastUtime, interval: 3}
+	if back == 0 {
+		return clock, nil
+	}
+	prevUtime, err := masterchainUtime(api, last-back, timeout)
+	if err == nil && lastUtime > prevUtime {
+		clock.interval = float64(lastUtime-prevUtime) / float64(back)
+	}
+	return clock, nil
+}
+
+func (c seqnoClock) estimate(t time.Time) int32 {
+	age := float64(int64(c.utime)-t.Unix()) / c.interval
+	seq := c.seqno - int32(age)
+	if seq < 1 {
+		seq = 1
+	}
+	if seq > c.seqno {
+		seq = c.seqno
+	}
+	return seq
+}
+
+func masterchainUtime(api *liteapi.Client, seqno uint32, timeout time.Duration) (uint32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_, info, err := api.LookupBlock(ctx, ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: seqno}, 1, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	return info.GenUtime, nil
+}
+
+func lookupMasterchainByUtime(api *liteapi.Client, utime uint32, timeout time.Duration) (int32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	id, _, err := api.LookupBlock(ctx, ton.BlockID{Workchain: -1, Shard: masterchainShard}, 4, nil, &utime)
+	if err != nil {
+		return 0, err
+	}
+	return int32(id.Seqno), nil
+}
+
+// resolveAgeBucket maps the bucket's age window to a seqno range; estimated is set if a bound was extrapolated.
+func resolveAgeBucket(api *liteapi.Client, clock seqnoClock, b ageBucket, timeout time.Duration) (br blockRange, estimated bool, err error) {
+	now := time.Now()
+	seqnoAt := func(age time.Duration) int32 {
+		if age <= 0 {
+			return clock.seqno
+		}
+		t := now.Add(-age)
+		seq, err := lookupMasterchainByUtime(api, uint32(t.Unix()), timeout)
+		if err != nil || seq <= 0 {
+			estimated = true
+			return clock.estimate(t)
+		}
+		return seq
+	}
+	to := seqnoAt(b.Min)
+	from := int32(1)
+	if b.Max > 0 {
+		from = seqnoAt(b.Max)
+	}
+	if from > to {
+		from, to = to, from
+	}
+	if to < 1 {
+		return blockRange{}, estimated, fmt.Errorf("bucket %s resolved to empty range", b.Label)
+	}
+	return blockRange{from: from, to: to, mode: "range"}, estimated, nil
+}
+
+func sampleBlockSeqs(br blockRange, n int, rng *lockedRand) []int32 {
+	span := int(br.to - br.from + 1)
+	if n <= 0 || n > span {
+		n = span
+	}
+	seqs := make([]int32, n)
+	for i := range seqs {
+		seqs[i] = br.from + int32(rng.Intn(span))
+	}
+	return seqs
+}
+
+func blockRangeSize(br blockRange) int {
+	if br.mode == "range" {
+		return int(br.to - br.from + 1)
+	}
+	return int(br.count)
+}
+
+func formatAgeBuckets(buckets []ageBucket) string {
+	parts := make([]string, 0, len(buckets))
+	for _, b := range buckets {
+		parts = append(parts, b.Label)
+	}
+	return strings.Join(parts, ",")
+}
+
+// runAgeBucketTests runs the block workload per bucket and level, except cells skip reports as finished.
+func runAgeBucketTests(env *runEnv, clients *clientSet, cfgName, targets string, buckets []ageBucket, perBucket int, levels []int, timeout, duration time.Duration, logger *reqLogger, randomBlocks bool, blocksRefresh time.Duration, rng *lockedRand, skip func(mode string, conc int) bool) []Result {
+	fmt.Printf("archive depth: buckets=%s\n", formatAgeBuckets(buckets))
+	api := clients.primary()
+	clock, err := newSeqnoClock(api, timeout)
+	if err != nil {
+		fmt.Printf("age buckets: masterchain head lookup failed: %v\n", err)
+		return nil
+	}
+	var out []Result
+	for _, b := range buckets {
+		br, estimated, err := resolveAgeBucket(api, clock, b, timeout)
+		if err != nil {
+			fmt.Printf("age bucket %s: %v\n", b.Label, err)
+			continue
+		}
+		note := ""
+		if estimated {
+			note = " (estimated from block rate)"
+		}
+		fmt.Printf("age bucket %s: seqno %d-%d%s\n", b.Label, br.from, br.to, note)
+		seqs := sampleBlockSeqs(br, perBucket, rng)
+		for _, conc := range levels {
+			if skip(b.mode(), conc) {
+				continue
+			}
+			res := runBlockTest(env, clients, cfgName, targets, b.mode(), seqs, conc, timeout, duration, logger, randomBlocks, blocksRefresh, rng, br)
+			res.AgeBucket = b.Label
+			res.AgeMinSec = int64(b.Min.Seconds())
+			res.SeqnoFrom = br.from
+			res.SeqnoTo = br.to
+			out = append(out, res)
+		}
+	}
+	return out
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// barrierPoll is how long the agent holds a barrier request before the run polls again.
+const barrierPoll = 30 * time.Second
+
+// barrierClient makes a run wait at the start of every cell until its agent is released by the coordinator.
+type barrierClient struct {
+	url   string
+	token string
+}
+
+// wait blocks until the cell is released; if the agent is unreachable the cell starts unsynchronised.
+func (b *barrierClient) wait(cfgName, mode string, conc int) time.Duration {
+	if b == nil {
+		return 0
+	}
+	start := time.Now()
+	cell := fmt.Sprintf("%s/%s/c%d", cfgName, mode, conc)
+	for {
+		req, err := http.NewRequest(http.MethodGet, b.url+"?cell="+url.QueryEscape(cell), nil)
+		if err != nil {
+			fmt.Printf("barrier %s: %v, starting unsynchronised\n", cell, err)
+			return time.Since(start)
+		}
+		if b.token != "" {
+			req.Header.Set("Authorization", "Bearer "+b.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			fmt.Printf("barrier %s: %v, starting unsynchronised\n", cell, err)
+			return time.Since(start)
+		}
+		var rel struct {
+			DelayMs int64 `json:"delay_ms"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&rel)
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNoContent {
+			continue
+		}
+		if resp.StatusCode != http.StatusOK || err != nil {
+			fmt.Printf("barrier %s: status %d, starting unsynchronised\n", cell, resp.StatusCode)
+			return time.Since(start)
+		}
+		time.Sleep(time.Duration(rel.DelayMs) * time.Millisecond)
+		return time.Since(start)
+	}
+}
+
+// agentFile is a file the coordinator ships with a run: a liteserver config or a flag's input file.
+type agentFile struct {
+	Name    string `json:"name"`
+	Content []byte `json:"content"`
+}
+
+// agentRunRequest is the body of POST /run: scenario flags, configs and the input files the flags name.
+type agentRunRequest struct {
+	Args    []string             `json:"args"`
+	Configs []agentFile          `json:"configs,omitempty"`
+	Files   map[string]agentFile `json:"files,omitempty"`
+}
+
+// agentStatus is the answer of GET /status; Cell is the cell a waiting run is held at.
+type agentStatus struct {
+	State string `json:"state"`
+	Cell  string `json:"cell,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// agentServer runs one scenario at a time as a child ls-load process and relays its start barrier.
+type agentServer struct {
+	listen string
+	token  string
+	mu     sync.Mutex
+	status agentStatus
+	dir    string
+	cmd    *exec.Cmd
+	// release is closed when the waiting cell is released; released keeps it for polls that missed the close
+	release  chan struct{}
+	released string
+	delayMs  int64
+}
+
+// runAgent is the `ls-load agent` subcommand.
+func runAgent(args []string) {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	listen := fs.String("listen", envOr("LS_LOAD_AGENT_LISTEN", "127.0.0.1:7070"), "Address the agent accepts coordinator requests on")
+	token := fs.String("token", envOr("LS_LOAD_AGENT_TOKEN", ""), "Bearer token the coordinator must send (--agent-token); required unless --listen is a loopback address")
+	fs.Parse(args)
+
+	if *token == "" && !isLoopbackAddr(*listen) {
+		exitf("agent: --token (or LS_LOAD_AGENT_TOKEN) is required to listen on %s", *listen)
+	}
+	a := &agentServer{listen: *listen, token: *token, status: agentStatus{State: "idle"}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /run", a.handleRun)
+	mux.HandleFunc("GET /status", a.handleStatus)
+	mux.HandleFunc("GET /barrier", a.handleBarrier)
+	mux.HandleFunc("POST /release", a.handleRelease)
+	mux.HandleFunc("POST /stop", a.handleStop)
+	mux.HandleFunc("GET /results", a.handleResults)
+	fmt.Printf("Agent listening on %s\n", *listen)
+	if err := http.ListenAndServe(*listen, requireToken(*token, mux)); err != nil {
+		exitf("agent: %v", err)
+	}
+}
+
+func (a *agentServer) handleRun(w http.ResponseWriter, r *http.Request) {
+	var req agentRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.status.State == "running" || a.status.State == "waiting" {
+		http.Error(w, "a run is in progress", http.StatusConflict)
+		return
+	}
+	if a.dir != "" {
+		os.RemoveAll(a.dir)
+		a.dir = ""
+	}
+	dir, err := os.MkdirTemp("", "ls-load-agent-")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	args, err := a.childArgs(dir, req)
+	if err != nil {
+		os.RemoveAll(dir)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		os.RemoveAll(dir)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	cmd := exec.Command(exe, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// the child sends the token to the barrier
+	cmd.Env = append(os.Environ(), "LS_LOAD_AGENT_TOKEN="+a.token)
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.dir = dir
+	a.cmd = cmd
+	a.status = agentStatus{State: "running"}
+	a.released = ""
+	fmt.Printf("agent: run started from %s\n", r.RemoteAddr)
+	go func() {
+		err := cmd.Wait()
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		switch {
+		case a.status.State == "stopped":
+		case err != nil:
+			a.status = agentStatus{State: "failed", Error: err.Error()}
+		default:
+			a.status = agentStatus{State: "done"}
+		}
+		fmt.Printf("agent: run %s\n", a.status.State)
+	}()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// childArgs checks that only scenario flags were sent, writes the shipped files and adds the agent's own flags.
+func (a *agentServer) childArgs(dir string, req agentRunRequest) ([]string, error) {
+	args := make([]string, 0, len(req.Args))
+	for _, arg := range req.Args {
+		name, _, ok := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !ok || !strings.HasPrefix(arg, "--") || !scenarioFlags[name] {
+			return nil, fmt.Errorf("flag %q can't be set by a coordinator", name)
+		}
+		args = append(args, arg)
+	}
+	var configs []string
+	for i, c := range req.Configs {
+		if c.Name == "" || strings.ContainsAny(c.Name, ",=") {
+			return nil, fmt.Errorf("invalid config name %q", c.Name)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("config-%d.json", i))
+		if err := os.WriteFile(path, c.Content, 0o644); err != nil {
+			return nil, err
+		}
+		configs = append(configs, c.Name+"="+path)
+	}
+	if len(configs) > 0 {
+		args = append(args, "--configs="+strings.Join(configs, ","))
+	}
+	for name, f := range req.Files {
+		if name != "accounts" && name != "methods" {
+			return nil, fmt.Errorf("unexpected file for --%s", name)
+		}
+		path := filepath.Join(dir, name+".txt")
+		if err := os.WriteFile(path, f.Content, 0o644); err != nil {
+			return nil, err
+		}
+		args = append(args, "--"+name+"="+path)
+	}
+	host, port, err := net.SplitHostPort(a.listen)
+	if err != nil {
+		return nil, err
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return append(args,
+		"--out="+filepath.Join(dir, "out"),
+		"--request-log=off",
+		"--histograms=true",
+		"--agents=",
+		"--resume=",
+		"--report-from=",
+		"--freshness=",
+		"--barrier=http://"+net.JoinHostPort(host, port)+"/barrier",
+	), nil
+}
+
+func (a *agentServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	st := a.status
+	a.mu.Unlock()
+	writeHTTPJSON(w, st)
+}
+
+// handleBarrier answers once the coordinator releases the cell, or with 204 after barrierPoll.
+func (a *agentServer) handleBarrier(w http.ResponseWriter, r *http.Request) {
+	cell := r.URL.Query().Get("cell")
+	a.mu.Lock()
+	if a.released == cell {
+		a.released = ""
+		delay := a.delayMs
+		a.mu.Unlock()
+		writeHTTPJSON(w, map[string]int64{"delay_ms": delay})
+		return
+	}
+	if a.status.State != "waiting" || a.status.Cell != cell {
+		a.status = agentStatus{State: "waiting", Cell: cell}
+		a.release = make(chan struct{})
+	}
+	release := a.release
+	a.mu.Unlock()
+
+	select {
+	case <-release:
+		a.mu.Lock()
+		delay := a.delayMs
+		if a.released == cell {
+			a.released = ""
+		}
+		a.mu.Unlock()
+		writeHTTPJSON(w, map[string]int64{"delay_ms": delay})
+	case <-time.After(barrierPoll):
+		w.WriteHeader(http.StatusNoContent)
+	case <-r.Context().Done():
+	}
+}
+
+// handleRelease starts the waiting cell delay_ms after the request arrives.
+func (a *agentServer) handleRelease(w http.ResponseWriter, r *http.Request) {
+	var delay int64
+	if _, err := fmt.Sscan(r.URL.Query().Get("delay_ms"), &delay); err != nil || delay < 0 {
+		http.Error(w, "invalid delay_ms", http.StatusBadRequest)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.status.State != "waiting" {
+		http.Error(w, "not waiting", http.StatusConflict)
+		return
+	}
+	a.delayMs = delay
+	a.released = a.status.Cell
+	close(a.release)
+	a.status = agentStatus{State: "running", Cell: a.status.Cell}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleStop kills the run, for a coordinator that gives up on the scenario.
+func (a *agentServer) handleStop(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.status.State != "running" && a.status.State != "waiting" {
+		http.Error(w, "no run in progress", http.StatusConflict)
+		return
+	}
+	if err := a.cmd.Process.Kill(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.status = agentStatus{State: "stopped"}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleResults serves the finished run's summary.json; it stays available until the next run.
+func (a *agentServer) handleResults(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	state, dir := a.status.State, a.dir
+	a.mu.Unlock()
+	if state != "done" {
+		http.Error(w, "no finished run", http.StatusConflict)
+		return
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "out", "*", "summary.json"))
+	if len(matches) == 0 {
+		http.Error(w, "summary.json not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	http.ServeFile(w, r, matches[0])
+}
+
+func writeHTTPJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+// The binary request log is a header and blocks of up to binLogBlock entries: the strings new in the block,
+// then every field as a column. An appended run starts with a new header, which resets the dictionary.
+//
+//	header: "LSRQLOG" 0x01
+//	block:  'B', uvarint #strings, (uvarint len, bytes)*, uint32 n,
+//	        ts int64 unix ns [n], latency_ms uint32 [n], resp_bytes uint32 [n], concurrency uint32 [n],
+//	        exit_code int32 [n], config, targets, mode, request, error uint32 ids [n] (0 = empty), ok uint8 [n]
+var binLogMagic = []byte("LSRQLOG\x01")
+
+const (
+	binLogBlock    = 4096
+	binLogBlockTag = 'B'
+	// binLogMaxString bounds a dictionary string; longer error texts are cut on a rune boundary
+	binLogMaxString = 64 << 10
+	// binLogRowSize is the column bytes of one entry.
+	binLogRowSize = 8 + 4*9 + 1
+)
+
+// binLogWriter encodes entries into column blocks.
+type binLogWriter struct {
+	w       io.Writer
+	dict    map[string]uint32
+	pending []string
+	rows    []logEntry
+	buf     bytes.Buffer
+}
+
+func newBinLogWriter(w io.Writer) (*binLogWriter, error) {
+	if _, err := w.Write(binLogMagic); err != nil {
+		return nil, err
+	}
+	return &binLogWriter{w: w, dict: map[string]uint32{}}, nil
+}
+
+func (b *binLogWriter) encode(e logEntry) error {
+	b.rows = append(b.rows, e)
+	if len(b.rows) >= binLogBlock {
+		return b.flush()
+	}
+	return nil
+}
+
+func (b *binLogWriter) id(s string) uint32 {
+	if s == "" {
+		return 0
+	}
+	if len(s) > binLogMaxString {
+		cut := binLogMaxString
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = s[:cut]
+	}
+	if id, ok := b.dict[s]; ok {
+		return id
+	}
+	id := uint32(len(b.dict) + 1)
+	b.dict[s] = id
+	b.pending = append(b.pending, s)
+	return id
+}
+
+// flush writes the entries collected so far as one block.
+func (b *binLogWriter) flush() error {
+	n := len(b.rows)
+	if n == 0 {
+		return nil
+	}
+	ids := make([][5]uint32, n)
+	for i, e := range b.rows {
+		ids[i] = [5]uint32{b.id(e.Config), b.id(e.Targets), b.id(e.Mode), b.id(e.Request), b.id(e.Error)}
+	}
+	buf := &b.buf
+	buf.Reset()
+	buf.WriteByte(binLogBlockTag)
+	buf.Write(binary.AppendUvarint(nil, uint64(len(b.pending))))
+	for _, s := range b.pending {
+		buf.Write(binary.AppendUvarint(nil, uint64(len(s))))
+		buf.WriteString(s)
+	}
+	b.pending = b.pending[:0]
+	le := binary.LittleEndian
+	var scratch [8]byte
+	put32 := func(v uint32) {
+		le.PutUint32(scratch[:4], v)
+		buf.Write(scratch[:4])
+	}
+	put32(uint32(n))
+	for _, e := range b.rows {
+		le.PutUint64(scratch[:], uint64(e.t.UnixNano()))
+		buf.Write(scratch[:])
+	}
+	for _, e := range b.rows {
+		put32(uint32(min(max(e.LatencyMs, 0), 1<<32-1)))
+	}
+	for _, e := range b.rows {
+		put32(uint32(max(e.RespBytes, 0)))
+	}
+	for _, e := range b.rows {
+		put32(uint32(e.Concurrency))
+	}
+	for _, e := range b.rows {
+		put32(uint32(int32(e.ExitCode)))
+	}
+	for col := 0; col < 5; col++ {
+		for i := range b.rows {
+			put32(ids[i][col])
+		}
+	}
+	for _, e := range b.rows {
+		if e.OK {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	}
+	b.rows = b.rows[:0]
+	_, err := b.w.Write(buf.Bytes())
+	return err
+}
+
+// readBinLog decodes a binary log from r, whose header has not been read yet.
+func readBinLog(r *bufio.Reader, fn func(e logEntry)) error {
+	var dict []string
+	le := binary.LittleEndian
+	for {
+		tag, err := r.Peek(1)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if tag[0] == binLogMagic[0] {
+			magic := make([]byte, len(binLogMagic))
+			if _, err := io.ReadFull(r, magic); err != nil {
+				return err
+			}
+			if !bytes.Equal(magic, binLogMagic) {
+				return fmt.Errorf("bad binary log header")
+			}
+			dict = dict[:0]
+			continue
+		}
+		if tag[0] != binLogBlockTag {
+			return fmt.Errorf("bad binary log block tag %q", tag[0])
+		}
+		r.ReadByte()
+		count, err := binary.ReadUvarint(r)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		// a block defines at most one string per string column of each entry
+		if count > 5*binLogBlock {
+			return fmt.Errorf("corrupt binary log: block defines %d strings", count)
+		}
+		for i := uint64(0); i < count; i++ {
+			l, err := binary.ReadUvarint(r)
+			if err != nil {
+				return unexpectedEOF(err)
+			}
+			if l > binLogMaxString {
+				return fmt.Errorf("corrupt binary log: %d byte string", l)
+			}
+			s := make([]byte, l)
+			if _, err := io.ReadFull(r, s); err != nil {
+				return unexpectedEOF(err)
+			}
+			dict = append(dict, string(s))
+		}
+		var nb [4]byte
+		if _, err := io.ReadFull(r, nb[:]); err != nil {
+			return unexpectedEOF(err)
+		}
+		n := int(le.Uint32(nb[:]))
+		if n > binLogBlock {
+			return fmt.Errorf("corrupt binary log: block of %d entries", n)
+		}
+		block := make([]byte, n*binLogRowSize)
+		if _, err := io.ReadFull(r, block); err != nil {
+			return unexpectedEOF(err)
+		}
+		str := func(id uint32) string {
+			if id == 0 || int(id) > len(dict) {
+				return ""
+			}
+			return dict[id-1]
+		}
+		col := func(k int) []byte { return block[n*8+n*4*k:] }
+		for i := 0; i < n; i++ {
+			e := logEntry{
+				t:           time.Unix(0, int64(le.Uint64(block[i*8:]))),
+				LatencyMs:   int64(le.Uint32(col(0)[i*4:])),
+				RespBytes:   int(le.Uint32(col(1)[i*4:])),
+				Concurrency: int(le.Uint32(col(2)[i*4:])),
+				ExitCode:    int(int32(le.Uint32(col(3)[i*4:]))),
+				Config:      str(le.Uint32(col(4)[i*4:])),
+				Targets:     str(le.Uint32(col(5)[i*4:])),
+				Mode:        str(le.Uint32(col(6)[i*4:])),
+				Request:     str(le.Uint32(col(7)[i*4:])),
+				Error:       str(le.Uint32(col(8)[i*4:])),
+				OK:          col(9)[i] == 1,
+			}
+			fn(e)
+		}
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func encodeBinLog(t *testing.T, entries []logEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := newBinLogWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if err := w.encode(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.flush(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decodeBinLog(data []byte) ([]logEntry, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+	if _, err := r.Discard(len(binLogMagic)); err != nil {
+		return nil, err
+	}
+	var out []logEntry
+	err := readBinLog(r, func(e logEntry) { out = append(out, e) })
+	return out, err
+}
+
+func testLogEntries(n int) []logEntry {
+	t0 := time.Unix(1700000000, 123456789)
+	out := make([]logEntry, n)
+	for i := range out {
+		e := logEntry{
+			t:           t0.Add(time.Duration(i) * time.Millisecond),
+			Config:      "ls",
+			Mode:        "blocks",
+			Concurrency: 8,
+			Request:     "GetBlock",
+			LatencyMs:   int64(i % 300),
+			RespBytes:   i * 10,
+			OK:          i%7 != 0,
+		}
+		if !e.OK {
+			e.Error = "timeout"
+			e.ExitCode = -14
+		}
+		if i%2 == 1 {
+			e.Targets = "a:1"
+		}
+		out[i] = e
+	}
+	return out
+}
+
+func TestBinLogRoundTrip(t *testing.T) {
+	// more than one block, so the second one reuses the first block's strings
+	in := testLogEntries(binLogBlock + 10)
+	data := encodeBinLog(t, in)
+	// appended run: a second header resets the dictionary
+	data = append(data, encodeBinLog(t, testLogEntries(3))...)
+
+	got, err := decodeBinLog(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := append(in, testLogEntries(3)...)
+	if len(got) != len(want) {
+		t.Fatalf("decoded %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].t.Equal(want[i].t) {
+			t.Fatalf("entry %d: ts %v, want %v", i, got[i].t, want[i].t)
+		}
+		got[i].t, want[i].t = time.Time{}, time.Time{}
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Fatalf("entry %d:\n got %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBinLogLongString(t *testing.T) {
+	// a 3-byte rune straddles the limit
+	long := strings.Repeat("a", binLogMaxString-1) + "€" + "tail"
+	got, err := decodeBinLog(encodeBinLog(t, []logEntry{{Mode: "blocks", Error: long}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := got[0].Error
+	if len(e) != binLogMaxString-1 || !utf8.ValidString(e) {
+		t.Fatalf("cut to %d bytes, valid utf8 %v", len(e), utf8.ValidString(e))
+	}
+}
+
+func TestBinLogTruncated(t *testing.T) {
+	in := testLogEntries(binLogBlock + 5)
+	data := encodeBinLog(t, in)
+	// cut inside the second block: the first one still decodes
+	got, err := decodeBinLog(data[:len(data)-3])
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("err %v, want unexpected EOF", err)
+	}
+	if len(got) != binLogBlock {
+		t.Fatalf("decoded %d entries before the cut, want %d", len(got), binLogBlock)
+	}
+	// cut inside the string table
+	if _, err := decodeBinLog(data[:len(binLogMagic)+4]); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("cut in the strings: err %v", err)
+	}
+}
+
+func TestBinLogCorrupt(t *testing.T) {
+	data := encodeBinLog(t, testLogEntries(2))
+	block := len(binLogMagic)
+
+	tests := map[string]func(b []byte) []byte{
+		"block tag": func(b []byte) []byte { b[block] = 'X'; return b },
+		"header":    func(b []byte) []byte { return append(b, []byte("LSRQLOG\x02")...) },
+		"string count": func(b []byte) []byte {
+			return append(b[:block+1:block+1], 0xff, 0xff, 0x03)
+		},
+		"string length": func(b []byte) []byte {
+			return append(b[:block+2:block+2], 0xff, 0xff, 0xff, 0x01)
+		},
+	}
+	for name, corrupt := range tests {
+		_, err := decodeBinLog(corrupt(append([]byte(nil), data...)))
+		if err == nil || errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("%s: err %v, want a corruption error", name, err)
+		}
+	}
+
+	// an entry count above the block size
+	var buf bytes.Buffer
+	buf.Write(binLogMagic)
+	buf.Write([]byte{binLogBlockTag, 0, 0xff, 0xff, 0, 0})
+	if _, err := decodeBinLog(buf.Bytes()); err == nil || !strings.Contains(err.Error(), "block of") {
+		t.Errorf("entry count: err %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/liteapi"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// defaultConfigParams are polled by services on every block: gas/fwd prices, validator sets, elector.
+var defaultConfigParams = []uint32{0, 1, 12, 15, 20, 21, 24, 25, 32, 34, 36}
+
+// validatorStatsLimit is how many validator entries a GetValidatorStats request asks for.
+const validatorStatsLimit = 1000
+
+// rawLiteClients are plain liteclient connections, one per liteserver, for requests whose answer size is counted.
+type rawLiteClients struct {
+	clients []*liteclient.Client
+	next    uint64
+}
+
+func dialRawLiteClients(servers []config.LiteServer, timeout time.Duration) (*rawLiteClients, error) {
+	r := &rawLiteClients{}
+	for _, ls := range servers {
+		key, err := base64.StdEncoding.DecodeString(ls.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key for %s", ls.Host)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		conn, err := liteclient.NewConnection(ctx, key, ls.Host)
+		cancel()
+		if err != nil {
+			fmt.Printf("raw connection to %s failed: %v\n", ls.Host, err)
+			continue
+		}
+		r.clients = append(r.clients, liteclient.NewClient(conn, liteclient.OptionTimeout(timeout)))
+	}
+	if len(r.clients) == 0 {
+		return nil, fmt.Errorf("no liteserver accepted a raw connection")
+	}
+	return r, nil
+}
+
+// query sends a marshaled liteServer request on the next connection in turn and returns the raw answer.
+func (r *rawLiteClients) query(ctx context.Context, req []byte) ([]byte, error) {
+	c := r.clients[(atomic.AddUint64(&r.next, 1)-1)%uint64(len(r.clients))]
+	inner, err := tl.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	answer, err := c.Request(ctx, append(binary.LittleEndian.AppendUint32(nil, tagLiteServerQuery), inner...))
+	if err != nil {
+		return nil, err
+	}
+	if len(answer) >= 4 && binary.LittleEndian.Uint32(answer) == tagLiteServerError {
+		var lsErr liteclient.LiteServerErrorC
+		if err := tl.Unmarshal(bytes.NewReader(answer[4:]), &lsErr); err != nil {
+			return answer, err
+		}
+		return answer, lsErr
+	}
+	return answer, nil
+}
+
+func configParamsRequest(block ton.BlockIDExt, params []uint32) ([]byte, error) {
+	return tl.Marshal(struct {
+		tl.SumType
+		Req liteclient.LiteServerGetConfigParamsRequest `tlSumType:"2a111c19"`
+	}{SumType: "Req", Req: liteclient.LiteServerGetConfigParamsRequest{Id: liteclient.BlockIDExt(block), ParamList: params}})
+}
+
+func validatorStatsRequest(block ton.BlockIDExt) ([]byte, error) {
+	return tl.Marshal(struct {
+		tl.SumType
+		Req liteclient.LiteServerGetValidatorStatsRequest `tlSumType:"091a58bc"`
+	}{SumType: "Req", Req: liteclient.LiteServerGetValidatorStatsRequest{Id: liteclient.BlockIDExt(block), Limit: validatorStatsLimit}})
+}
+
+// runConfigTest requests GetConfigAll, GetConfigParams and GetValidatorStats against random masterchain blocks.
+func runConfigTest(env *runEnv, clients *clientSet, raw *rawLiteClients, cfgName, targets string, seqs []int32, params []uint32, conc int, timeout time.Duration, duration time.Duration, logger *reqLogger, randomBlocks bool, blocksRefresh time.Duration, rng *lockedRand, br blockRange) Result {
+	mode := string(ModeConfig)
+	fmt.Printf("%s: concurrency=%d, total=%d, params=%v\n", mode, conc, len(seqs), params)
+	start := time.Now()
+	var picker *blockPicker
+	if randomBlocks {
+		picker = newBlockPicker(clients.primary(), br, blocksRefresh)
+	}
+	work := clients.wrap(func(api *liteapi.Client, i int) (err error) {
+		span := env.job(cfgName, targets, mode, conc)
+		defer span.end(&err)
+		seq := seqs[i%len(seqs)]
+		if picker != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			ps, err := picker.pick(ctx, rng.stream())
+			cancel()
+			if err != nil {
+				return err
+			}
+			seq = ps
+		}
+		span.set("ton.block.seqno", seq)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		t0 := time.Now()
+		block, err := api.WaitMasterchainBlock(ctx, uint32(seq), 15*time.Second)
+		env.logRequest(logger, span, cfgName, targets, mode, conc, "WaitMasterchainBlock", t0, 0, err)
+		if err != nil {
+			return err
+		}
+		client := api.WithBlock(block)
+
+		t1 := time.Now()
+		all, err := client.GetConfigAllRaw(ctx, 0)
+		respBytes := len(all.StateProof) + len(all.ConfigProof)
+		env.logRequest(logger, span, cfgName, targets, mode, conc, "GetConfigAll", t1, respBytes, err)
+		if err != nil {
+			return err
+		}
+
+		if len(params) > 0 {
+			req, err := configParamsRequest(block, params)
+			if err != nil {
+				return err
+			}
+			t2 := time.Now()
+			answer, err := raw.query(ctx, req)
+			env.logRequest(logger, span, cfgName, targets, mode, conc, "GetConfigParams", t2, len(answer), err)
+			if err != nil {
+				return err
+			}
+		}
+
+		req, err := validatorStatsRequest(block)
+		if err != nil {
+			return err
+		}
+		t3 := time.Now()
+		answer, err := raw.query(ctx, req)
+		env.logRequest(logger, span, cfgName, targets, mode, conc, "GetValidatorStats", t3, len(answer), err)
+		return err
+	})
+
+	jr := env.runWorkload(cfgName, mode, len(seqs), conc, duration, work)
+	res := env.finishResult(jr, mode, conc, len(seqs), duration, start)
+	clients.apply(&res)
+	return res
+}
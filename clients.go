@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tonkeeper/tongo/config"
+	"github.com/tonkeeper/tongo/liteapi"
+)
+
+// clientDialParallel bounds how many clients connect at once while a set is being built.
+const clientDialParallel = 64
+
+// ClientStats is how fairly the --clients connections of a result were served.
+type ClientStats struct {
+	Clients        int                `json:"clients,omitempty"`
+	ClientsRefused int                `json:"clients_refused,omitempty"`
+	ClientsStarved int                `json:"clients_starved,omitempty"`
+	FairnessIndex  float64            `json:"fairness_index,omitempty"`
+	ClientServers  []clientServerStat `json:"client_servers,omitempty"`
+}
+
+type clientServerStat struct {
+	Host      string  `json:"host"`
+	Clients   int     `json:"clients"`
+	Refused   int     `json:"refused"`
+	Starved   int     `json:"starved"`
+	Jobs      int     `json:"jobs"`
+	Errors    int     `json:"errors"`
+	AvgMs     float64 `json:"avg_ms"`
+	P95Ms     float64 `json:"p95_ms"`
+	MinP95Ms  float64 `json:"min_client_p95_ms"`
+	MaxP95Ms  float64 `json:"max_client_p95_ms"`
+	ErrorRate float64 `json:"error_rate"`
+}
+
+type clientCounter struct {
+	mu        sync.Mutex
+	durations []int64
+	errors    int
+}
+
+// clientSet is what workloads send jobs through: the shared client, or with --clients N independent clients
+// pinned to one liteserver each, used round-robin.
+type clientSet struct {
+	clients  []*liteapi.Client
+	hosts    []string
+	refused  map[string]int
+	next     uint64
+	counters []clientCounter
+}
+
+func sharedClientSet(api *liteapi.Client) *clientSet {
+	return &clientSet{clients: []*liteapi.Client{api}}
+}
+
+// dialClientSet connects n clients round-robin over servers; it fails only when every client was refused.
+func dialClientSet(opts []liteapi.Option, servers []config.LiteServer, n int) (*clientSet, error) {
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no liteservers in config")
+	}
+	type dialed struct {
+		api  *liteapi.Client
+		host string
+	}
+	out := make([]dialed, n)
+	refused := make([]bool, n)
+	sem := make(chan struct{}, clientDialParallel)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		ls := servers[i%len(servers)]
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			o := append(append([]liteapi.Option{}, opts...),
+				liteapi.WithLiteServers([]config.LiteServer{ls}),
+				liteapi.WithMaxConnectionsNumber(1))
+			api, err := liteapi.NewClient(o...)
+			if err != nil {
+				refused[i] = true
+				return
+			}
+			out[i] = dialed{api: api, host: ls.Host}
+		}(i)
+	}
+	wg.Wait()
+
+	cs := &clientSet{refused: map[string]int{}}
+	for i, d := range out {
+		if refused[i] {
+			cs.refused[servers[i%len(servers)].Host]++
+			continue
+		}
+		cs.clients = append(cs.clients, d.api)
+		cs.hosts = append(cs.hosts, d.host)
+	}
+	if len(cs.clients) == 0 {
+		return nil, fmt.Errorf("all %d clients were refused", n)
+	}
+	cs.counters = make([]clientCounter, len(cs.clients))
+	return cs, nil
+}
+
+// independent reports whether the set tracks per-client stats (i.e. was built by dialClientSet).
+func (cs *clientSet) independent() bool {
+	return cs != nil && cs.counters != nil
+}
+
+// primary is the client used for per-run setup such as resolving the current masterchain block.
+func (cs *clientSet) primary() *liteapi.Client {
+	return cs.clients[0]
+}
+
+// wrap adapts a job that needs a client to the runners: every call gets the next client in turn.
+func (cs *clientSet) wrap(fn func(api *liteapi.Client, i int) error) func(i int) error {
+	if !cs.independent() {
+		return func(i int) error { return fn(cs.clients[0], i) }
+	}
+	return func(i int) error {
+		k := int((atomic.AddUint64(&cs.next, 1) - 1) % uint64(len(cs.clients)))
+		t0 := time.Now()
+		err := fn(cs.clients[k], i)
+		c := &cs.counters[k]
+		c.mu.Lock()
+		if err != nil {
+			c.errors++
+		} else {
+			c.durations = append(c.durations, time.Since(t0).Milliseconds())
+		}
+		c.mu.Unlock()
+		return err
+	}
+}
+
+// apply moves the per-client stats since the last call into r; fairness is Jain's index over the clients' shares.
+func (cs *clientSet) apply(r *Result) {
+	if !cs.independent() {
+		return
+	}
+	r.Clients = len(cs.clients)
+	byHost := map[string]*clientServerStat{}
+	hostDurations := map[string][]int64{}
+	completed := make([]float64, len(cs.counters))
+	for k := range cs.counters {
+		c := &cs.counters[k]
+		c.mu.Lock()
+		durations, errs := c.durations, c.errors
+		c.durations, c.errors = nil, 0
+		c.mu.Unlock()
+
+		host := cs.hosts[k]
+		st := byHost[host]
+		if st == nil {
+			st = &clientServerStat{Host: host, MinP95Ms: math.MaxFloat64}
+			byHost[host] = st
+		}
+		st.Clients++
+		st.Jobs += len(durations) + errs
+		st.Errors += errs
+		hostDurations[host] = append(hostDurations[host], durations...)
+		completed[k] = float64(len(durations))
+
+		if len(durations) == 0 {
+			st.Starved++
+			r.ClientsStarved++
+			continue
+		}
+		_, _, _, p95, _, _ := computeMetrics(durations, len(durations))
+		st.MinP95Ms = math.Min(st.MinP95Ms, p95)
+		st.MaxP95Ms = math.Max(st.MaxP95Ms, p95)
+	}
+	r.FairnessIndex = jainIndex(completed)
+	for host, n := range cs.refused {
+		if byHost[host] == nil {
+			byHost[host] = &clientServerStat{Host: host}
+		}
+		byHost[host].Refused = n
+		r.ClientsRefused += n
+	}
+
+	r.ClientServers = r.ClientServers[:0]
+	hosts := make([]string, 0, len(byHost))
+	for h := range byHost {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	for _, h := range hosts {
+		st := byHost[h]
+		if d := hostDurations[h]; len(d) > 0 {
+			st.AvgMs, _, _, st.P95Ms, _, _ = computeMetrics(d, len(d))
+		}
+		if st.MinP95Ms == math.MaxFloat64 {
+			st.MinP95Ms = 0
+		}
+		if st.Jobs > 0 {
+			st.ErrorRate = float64(st.Errors) / float64(st.Jobs)
+		}
+		r.ClientServers = append(r.ClientServers, *st)
+	}
+}
+
+// jainIndex is (Σx)² / (n·Σx²), 0 when nothing was measured.
+func jainIndex(xs []float64) float64 {
+	var sum, sumSq float64
+	for _, x := range xs {
+		sum += x
+		sumSq += x * x
+	}
+	if sumSq == 0 {
+		return 0
+	}
+	return sum * sum / (float64(len(xs)) * sumSq)
+}
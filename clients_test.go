@@ -0,0 +1,61 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tonkeeper/tongo/liteapi"
+)
+
+func TestJainIndex(t *testing.T) {
+	tests := []struct {
+		xs   []float64
+		want float64
+	}{
+		{nil, 0},
+		{[]float64{0, 0}, 0},
+		{[]float64{5, 5, 5, 5}, 1},
+		{[]float64{10, 0, 0, 0}, 0.25},
+		{[]float64{1, 2, 3}, 36.0 / 42},
+	}
+	for _, tt := range tests {
+		if got := jainIndex(tt.xs); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("jainIndex(%v) = %f, want %f", tt.xs, got, tt.want)
+		}
+	}
+}
+
+func TestClientSetApply(t *testing.T) {
+	cs := &clientSet{
+		clients:  make([]*liteapi.Client, 3),
+		hosts:    []string{"a:1", "a:1", "b:1"},
+		refused:  map[string]int{"c:1": 2},
+		counters: make([]clientCounter, 3),
+	}
+	// slow and fast clients that completed the same number of jobs are served equally
+	cs.counters[0].durations = []int64{1, 1, 1}
+	cs.counters[1].durations = []int64{100, 200, 300}
+	cs.counters[2].errors = 3
+
+	var r Result
+	cs.apply(&r)
+	if r.Clients != 3 || r.ClientsStarved != 1 || r.ClientsRefused != 2 {
+		t.Fatalf("clients=%d starved=%d refused=%d", r.Clients, r.ClientsStarved, r.ClientsRefused)
+	}
+	if want := 36.0 / 54; math.Abs(r.FairnessIndex-want) > 1e-9 {
+		t.Fatalf("fairness %f, want %f", r.FairnessIndex, want)
+	}
+	if len(r.ClientServers) != 3 || r.ClientServers[0].Host != "a:1" || r.ClientServers[0].Jobs != 6 || r.ClientServers[2].Refused != 2 {
+		t.Fatalf("per server %+v", r.ClientServers)
+	}
+	if b := r.ClientServers[1]; b.Host != "b:1" || b.Starved != 1 || b.ErrorRate != 1 {
+		t.Fatalf("starved server %+v", b)
+	}
+
+	// the counters are moved out: the next cell starts from zero
+	var next Result
+	cs.apply(&next)
+	if next.FairnessIndex != 0 || next.ClientsStarved != 3 {
+		t.Fatalf("second apply: fairness %f starved %d", next.FairnessIndex, next.ClientsStarved)
+	}
+}
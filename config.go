@@ -36,6 +36,41 @@ func parseIntList(v string) ([]int, error) {
 	return out, nil
 }
 
+func parseUint32List(v string) ([]uint32, error) {
+	var out []uint32
+	for _, p := range strings.Split(v, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		i, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uint32: %s", p)
+		}
+		out = append(out, uint32(i))
+	}
+	return out, nil
+}
+
+func parseModes(spec string) (map[Mode]bool, error) {
+	out := map[Mode]bool{}
+	for _, p := range strings.Split(spec, ",") {
+		m := Mode(strings.ToLower(strings.TrimSpace(p)))
+		switch m {
+		case "":
+			continue
+		case ModeBoth:
+			out[ModeBlocks] = true
+			out[ModeAccounts] = true
+		case ModeBlocks, ModeAccounts, ModeRunMethod, ModeTransactions, ModeConfig, ModeProofs, ModeSend, ModeConnect, ModeVerify, ModeSession:
+			out[m] = true
+		default:
+			return nil, fmt.Errorf("unknown mode: %s", m)
+		}
+	}
+	return out, nil
+}
+
 func resolveConfigPaths(spec string) ([]configItem, error) {
 	parts := strings.Split(spec, ",")
 	seen := map[string]bool{}
@@ -96,6 +131,15 @@ func envOrInt(key string, def int) int {
 	return def
 }
 
+func envOrFloat(key string, def float64) float64 {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
 func envOrBool(key string, def bool) bool {
 	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
 		switch strings.ToLower(v) {
@@ -205,3 +249,68 @@ func formatTargets(cfg *config.GlobalConfigurationFile) string {
 	}
 	return strings.Join(parts, ", ")
 }
+
+func parseAgeBuckets(spec string) ([]ageBucket, error) {
+	parts := strings.Split(spec, ",")
+	var out []ageBucket
+	for _, p := range parts {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if strings.HasSuffix(p, "+") {
+			min, err := parseAgeDuration(strings.TrimSuffix(p, "+"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid bucket %s: %w", p, err)
+			}
+			out = append(out, ageBucket{Label: p, Min: min})
+			continue
+		}
+		bounds := strings.SplitN(p, "-", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid bucket %s: expected MIN-MAX or MIN+", p)
+		}
+		min, err1 := parseAgeDuration(bounds[0])
+		max, err2 := parseAgeDuration(bounds[1])
+		if err1 != nil || err2 != nil || max <= min {
+			return nil, fmt.Errorf("invalid bucket %s", p)
+		}
+		out = append(out, ageBucket{Label: p, Min: min, Max: max})
+	}
+	for i, a := range out {
+		for _, b := range out[:i] {
+			if a.overlaps(b) {
+				return nil, fmt.Errorf("bucket %s overlaps %s", a.Label, b.Label)
+			}
+		}
+	}
+	return out, nil
+}
+
+// overlaps reports whether two buckets share an age; Max 0 is open-ended.
+func (a ageBucket) overlaps(b ageBucket) bool {
+	return (a.Max == 0 || b.Min < a.Max) && (b.Max == 0 || a.Min < b.Max)
+}
+
+// parseAgeDuration accepts time.ParseDuration syntax plus d (days) and w (weeks) suffixes.
+func parseAgeDuration(v string) (time.Duration, error) {
+	v = strings.TrimSpace(v)
+	if v == "0" {
+		return 0, nil
+	}
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(v, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(v, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	if unit == 0 {
+		return time.ParseDuration(v)
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(v[:len(v)-1]), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid age: %s", v)
+	}
+	return time.Duration(n * float64(unit)), nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAgeDuration(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"0", 0, true},
+		{"90m", 90 * time.Minute, true},
+		{"1d", day, true},
+		{"1.5d", 36 * time.Hour, true},
+		{"2w", 14 * day, true},
+		{" 3d ", 3 * day, true},
+		{"-1d", 0, false},
+		{"xd", 0, false},
+		{"1y", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseAgeDuration(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseAgeDuration(%q) = %v, %v, want %v, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseAgeBuckets(t *testing.T) {
+	day := 24 * time.Hour
+	got, err := parseAgeBuckets("0-1h, 1h-1d,1d-1w, 1W+,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ageBucket{
+		{Label: "0-1h", Max: time.Hour},
+		{Label: "1h-1d", Min: time.Hour, Max: day},
+		{Label: "1d-1w", Min: day, Max: 7 * day},
+		{Label: "1w+", Min: 7 * day},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d buckets, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("bucket %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	for _, spec := range []string{
+		"1h",          // no bound
+		"1d-1h",       // max below min
+		"1h-1h",       // empty
+		"0-x",         // bad max
+		"x+",          // bad min
+		"0-1d,12h-2d", // overlap
+		"1d+,1w-2w",   // inside an open-ended bucket
+		"1w-2w,0-1w,1d+",
+	} {
+		if _, err := parseAgeBuckets(spec); err == nil {
+			t.Errorf("parseAgeBuckets(%q) accepted", spec)
+		}
+	}
+	if _, err := parseAgeBuckets("1w-2w,0-1w"); err != nil {
+		t.Errorf("adjacent buckets: %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/config"
+)
+
+const connectModePrefix = string(ModeConnect) + ":"
+
+// ConnectStats is what a connect-mode result measured of the ADNL handshake.
+type ConnectStats struct {
+	ConnectRate     float64 `json:"connect_rate,omitempty"`
+	HandshakeP50Ms  float64 `json:"handshake_p50_ms,omitempty"`
+	HandshakeP95Ms  float64 `json:"handshake_p95_ms,omitempty"`
+	HandshakeP99Ms  float64 `json:"handshake_p99_ms,omitempty"`
+	StormRecoveryMs float64 `json:"storm_recovery_ms,omitempty"`
+}
+
+// connectTarget is one liteserver of a config as the connect workload dials it.
+type connectTarget struct {
+	Host string
+	Key  ed25519.PublicKey
+}
+
+func connectTargets(cfg *config.GlobalConfigurationFile) ([]connectTarget, error) {
+	out := make([]connectTarget, 0, len(cfg.LiteServers))
+	for _, ls := range cfg.LiteServers {
+		key, err := base64.StdEncoding.DecodeString(ls.Key)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid key for %s", ls.Host)
+		}
+		out = append(out, connectTarget{Host: ls.Host, Key: key})
+	}
+	return out, nil
+}
+
+func isConnectMode(mode string) bool {
+	return strings.HasPrefix(mode, connectModePrefix)
+}
+
+// connectOnce dials, handshakes and sends one getMasterchainInfo; the returned time excludes the query.
+func connectOnce(env *runEnv, t connectTarget, cfgName, targets, mode string, conc int, timeout time.Duration, logger *reqLogger, span *traceSpan) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var d net.Dialer
+	t0 := time.Now()
+	conn, err := d.DialContext(ctx, "tcp", t.Host)
+	env.logRequest(logger, span, cfgName, targets, mode, conc, "TCPConnect", t0, 0, err)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	t1 := time.Now()
+	c, err := adnlHandshake(ctx, conn, t.Key)
+	env.logRequest(logger, span, cfgName, targets, mode, conc, "ADNLHandshake", t1, 0, err)
+	if err != nil {
+		return 0, err
+	}
+	handshakeMs := time.Since(t0).Milliseconds()
+	t2 := time.Now()
+	_, n, err := c.queryMasterchainInfo(ctx)
+	env.logRequest(logger, span, cfgName, targets, mode, conc, "GetMasterchainInfo", t2, n, err)
+	return handshakeMs, err
+}
+
+// runConnectTest reconnects to one liteserver, conc workers back to back or open-loop at rate per second.
+func runConnectTest(env *runEnv, cfgName, targets string, t connectTarget, rate float64, total, conc int, timeout time.Duration, duration time.Duration, logger *reqLogger) Result {
+	mode := connectModePrefix + t.Host
+	fmt.Printf("%s: concurrency=%d, rate=%.1f/s, total=%d\n", mode, conc, rate, total)
+	start := time.Now()
+	var mu sync.Mutex
+	var handshakes []int64
+	work := func(i int) (err error) {
+		span := env.job(cfgName, targets, mode, conc)
+		defer span.end(&err)
+		span.set("net.peer.name", t.Host)
+		ms, err := connectOnce(env, t, cfgName, targets, mode, conc, timeout, logger, span)
+		if err == nil {
+			mu.Lock()
+			handshakes = append(handshakes, ms)
+			mu.Unlock()
+		}
+		return err
+	}
+
+	var jr jobRun
+	if rate > 0 {
+		launches := total
+		if duration > 0 {
+			launches = 0
+		}
+		waited := env.barrier.wait(cfgName, mode, conc)
+		jr = runPacedJobs(rate, launches, conc, duration, work)
+		jr.waited = waited
+	} else {
+		jr = env.runWorkload(cfgName, mode, total, conc, duration, work)
+	}
+	res := env.finishResult(jr, mode, conc, total, duration, start)
+	if rate > 0 {
+		res.Total = res.Success + res.Errors
+	}
+	res.ConnectRate = rate
+	_, res.HandshakeP50Ms, _, res.HandshakeP95Ms, res.HandshakeP99Ms, _ = computeMetrics(handshakes, len(handshakes))
+	return res
+}
+
+// runConnectStorm releases n dials at once, as clients coming back after an outage; StormRecoveryMs is
+// the time until the last of them is done.
+func runConnectStorm(env *runEnv, cfgName, targets string, ts []connectTarget, n int, timeout time.Duration, logger *reqLogger) Result {
+	mode := connectModePrefix + "storm"
+	fmt.Printf("%s: clients=%d, servers=%d\n", mode, n, len(ts))
+	durations := make([]int64, n)
+	handshakes := make([]int64, 0, n)
+	var mu sync.Mutex
+	var successes, errors int
+	release := make(chan struct{})
+	var ready, wg sync.WaitGroup
+	ready.Add(n)
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			ready.Done()
+			<-release
+			span := env.job(cfgName, targets, mode, n)
+			span.set("net.peer.name", ts[i%len(ts)].Host)
+			t0 := time.Now()
+			ms, err := connectOnce(env, ts[i%len(ts)], cfgName, targets, mode, n, timeout, logger, span)
+			d := time.Since(t0).Milliseconds()
+			span.end(&err)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				durations[i] = -1
+				errors++
+				return
+			}
+			durations[i] = d
+			handshakes = append(handshakes, ms)
+			successes++
+		}(i)
+	}
+	ready.Wait()
+	env.barrier.wait(cfgName, mode, n)
+	start := time.Now()
+	close(release)
+	wg.Wait()
+
+	res := Result{
+		Mode:        mode,
+		Concurrency: n,
+		Total:       n,
+		Success:     successes,
+		Errors:      errors,
+		Duration:    time.Since(start),
+	}
+	res.StormRecoveryMs = float64(res.Duration.Milliseconds())
+	applyMetrics(&res, durations)
+	if env.histograms {
+		res.Hist = histogramOf(durations)
+	}
+	_, res.HandshakeP50Ms, _, res.HandshakeP95Ms, res.HandshakeP99Ms, _ = computeMetrics(handshakes, len(handshakes))
+	return res
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// agentStartDelay gives the release requests time to reach every agent before the cell starts.
+const agentStartDelay = time.Second
+
+// agentPoll is how often the coordinator checks the agents' state.
+const agentPoll = 200 * time.Millisecond
+
+// agentStatusRetries failed status polls in a row, backed off up to agentMaxBackoff, mark an agent failed.
+const (
+	agentStatusRetries = 6
+	agentMaxBackoff    = 5 * time.Second
+)
+
+// agentLocalFlags are scenario flags the coordinator does not forward: seed, histograms, freshness and SLOs.
+var agentLocalFlags = map[string]bool{
+	"seed":           true,
+	"histograms":     true,
+	"freshness":      true,
+	"slo-p99":        true,
+	"slo-error-rate": true,
+	"slo-min-rps":    true,
+}
+
+// agentArgs is the scenario as flags, resolved on the coordinator; configs and input files are shipped apart.
+func agentArgs() []string {
+	var args []string
+	flag.VisitAll(func(f *flag.Flag) {
+		if scenarioFlags[f.Name] && !agentLocalFlags[f.Name] {
+			args = append(args, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	return args
+}
+
+// newAgentRunRequest packs the scenario with the contents of the configs and input files it refers to.
+func newAgentRunRequest(configs []configItem, accountsPath, methodsPath string) (agentRunRequest, error) {
+	req := agentRunRequest{Args: agentArgs(), Files: map[string]agentFile{}}
+	for _, c := range configs {
+		b, err := os.ReadFile(c.Path)
+		if err != nil {
+			return req, err
+		}
+		req.Configs = append(req.Configs, agentFile{Name: c.Name, Content: b})
+	}
+	for name, path := range map[string]string{"accounts": accountsPath, "methods": methodsPath} {
+		if strings.TrimSpace(path) == "" {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return req, err
+		}
+		req.Files[name] = agentFile{Name: filepath.Base(path), Content: b}
+	}
+	return req, nil
+}
+
+type agentClient struct {
+	addr   string
+	token  string
+	http   *http.Client
+	status agentStatus
+
+	statusErrors int
+	retryAt      time.Time
+}
+
+// pollStatus fetches the agent's state, keeping the last known one while requests fail.
+func (c *agentClient) pollStatus(now time.Time) agentStatus {
+	if now.Before(c.retryAt) {
+		return c.status
+	}
+	var st agentStatus
+	err := c.do(http.MethodGet, "/status", nil, &st)
+	if err == nil {
+		c.statusErrors, c.retryAt = 0, time.Time{}
+		return st
+	}
+	c.statusErrors++
+	if c.statusErrors >= agentStatusRetries {
+		return agentStatus{State: "failed", Error: err.Error()}
+	}
+	backoff := min(agentPoll<<c.statusErrors, agentMaxBackoff)
+	fmt.Printf("agent %s: status: %v (retry in %s)\n", c.addr, err, backoff)
+	c.retryAt = now.Add(backoff)
+	return c.status
+}
+
+func (c *agentClient) url(path string) string {
+	return "http://" + c.addr + path
+}
+
+func (c *agentClient) do(method, path string, body any, out any) error {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.url(path), rd)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+func parseAgents(spec string) []string {
+	var out []string
+	for _, a := range strings.Split(spec, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// runAgents runs the scenario on every agent (agent i with seed+i) and releases each cell once all running
+// agents wait at it. It returns each agent's results, nil for agents that did not finish.
+func runAgents(addrs []string, token string, req agentRunRequest, seed int64) [][]Result {
+	agents := make([]*agentClient, len(addrs))
+	for i, addr := range addrs {
+		agents[i] = &agentClient{addr: addr, token: token, http: &http.Client{Timeout: 30 * time.Second}}
+		r := req
+		r.Args = append(append([]string{}, req.Args...), "--seed="+strconv.FormatInt(seed+int64(i), 10))
+		if err := agents[i].do(http.MethodPost, "/run", r, nil); err != nil {
+			stopAgents(agents[:i])
+			exitf("agent %s: %v", addr, err)
+		}
+		fmt.Printf("agent %s: started\n", addr)
+	}
+
+	for {
+		var waiting []*agentClient
+		cells := map[string]bool{}
+		active := 0
+		now := time.Now()
+		for _, a := range agents {
+			if a.status.State == "done" || a.status.State == "failed" {
+				continue
+			}
+			st := a.pollStatus(now)
+			if st != a.status {
+				printAgentStatus(a.addr, st)
+			}
+			a.status = st
+			switch st.State {
+			case "done", "failed":
+				continue
+			case "waiting":
+				waiting = append(waiting, a)
+				cells[st.Cell] = true
+			}
+			active++
+		}
+		if active == 0 {
+			break
+		}
+		if len(waiting) == active {
+			if len(cells) > 1 {
+				fmt.Printf("agents wait at different cells (%d), releasing them together\n", len(cells))
+			}
+			releaseAgents(waiting)
+		}
+		time.Sleep(agentPoll)
+	}
+
+	out := make([][]Result, len(agents))
+	for i, a := range agents {
+		if a.status.State != "done" {
+			continue
+		}
+		if err := a.do(http.MethodGet, "/results", nil, &out[i]); err != nil {
+			fmt.Printf("agent %s: results: %v\n", a.addr, err)
+		}
+	}
+	return out
+}
+
+func releaseAgents(agents []*agentClient) {
+	var wg sync.WaitGroup
+	for _, a := range agents {
+		wg.Add(1)
+		go func(a *agentClient) {
+			defer wg.Done()
+			path := fmt.Sprintf("/release?delay_ms=%d", agentStartDelay.Milliseconds())
+			if err := a.do(http.MethodPost, path, nil, nil); err != nil {
+				fmt.Printf("agent %s: release: %v\n", a.addr, err)
+			}
+		}(a)
+	}
+	wg.Wait()
+}
+
+func stopAgents(agents []*agentClient) {
+	for _, a := range agents {
+		if err := a.do(http.MethodPost, "/stop", nil, nil); err != nil {
+			fmt.Printf("agent %s: stop: %v\n", a.addr, err)
+			continue
+		}
+		fmt.Printf("agent %s: stopped\n", a.addr)
+	}
+}
+
+func printAgentStatus(addr string, st agentStatus) {
+	switch st.State {
+	case "waiting":
+		fmt.Printf("agent %s: ready for %s\n", addr, st.Cell)
+	case "failed":
+		fmt.Printf("agent %s: failed: %s\n", addr, st.Error)
+	case "done":
+		fmt.Printf("agent %s: done\n", addr)
+	}
+}
+
+// mergeAgentResults merges the agents' results cell by cell, in the order cells first appear.
+func mergeAgentResults(perAgent [][]Result, histograms bool) []Result {
+	var order []cellKey
+	cells := map[cellKey][]Result{}
+	for _, rs := range perAgent {
+		for _, r := range rs {
+			k := cellKey{r.Config, r.Mode, r.Concurrency}
+			if _, ok := cells[k]; !ok {
+				order = append(order, k)
+			}
+			cells[k] = append(cells[k], r)
+		}
+	}
+	out := make([]Result, 0, len(order))
+	for _, k := range order {
+		out = append(out, mergeResults(cells[k], histograms))
+	}
+	return out
+}
+
+// mergeResults sums one cell run by several agents; percentiles come from the merged histograms and
+// Concurrency stays per agent.
+func mergeResults(rs []Result, histograms bool) Result {
+	first := rs[0]
+	m := Result{
+		Config:      first.Config,
+		Targets:     first.Targets,
+		Mode:        first.Mode,
+		Concurrency: first.Concurrency,
+		AgeStats:    first.AgeStats,
+		Agents:      len(rs),
+	}
+	m.Profile, m.ProfileUnit = first.Profile, first.ProfileUnit
+	m.ConnectRate = first.ConnectRate
+	hist := map[int]int64{}
+	var latencySum float64
+	for _, r := range rs {
+		m.Total += r.Total
+		m.Success += r.Success
+		m.Errors += r.Errors
+		m.NotFound += r.NotFound
+		m.GasFailures += r.GasFailures
+		m.VMFailures += r.VMFailures
+		m.Bytes += r.Bytes
+		m.ProofCalls += r.ProofCalls
+		m.ProofLinks += r.ProofLinks
+		m.ProofFailures += r.ProofFailures
+		m.Accepted += r.Accepted
+		m.Rejected += r.Rejected
+		m.BackPressure += r.BackPressure
+		m.Clients += r.Clients
+		m.ClientsRefused += r.ClientsRefused
+		m.ClientsStarved += r.ClientsStarved
+		for code, n := range r.ExitCodes {
+			if m.ExitCodes == nil {
+				m.ExitCodes = map[string]int{}
+			}
+			m.ExitCodes[code] += n
+		}
+		m.Duration = max(m.Duration, r.Duration)
+		m.MaxMs = math.Max(m.MaxMs, r.MaxMs)
+		m.HandshakeP50Ms = math.Max(m.HandshakeP50Ms, r.HandshakeP50Ms)
+		m.HandshakeP95Ms = math.Max(m.HandshakeP95Ms, r.HandshakeP95Ms)
+		m.HandshakeP99Ms = math.Max(m.HandshakeP99Ms, r.HandshakeP99Ms)
+		m.StormRecoveryMs = math.Max(m.StormRecoveryMs, r.StormRecoveryMs)
+		latencySum += r.AvgMs * float64(r.Success)
+		addHist(hist, r.Hist)
+		// without histograms (results of an older agent) the slowest agent's percentiles are reported
+		m.P50Ms = math.Max(m.P50Ms, r.P50Ms)
+		m.P90Ms = math.Max(m.P90Ms, r.P90Ms)
+		m.P95Ms = math.Max(m.P95Ms, r.P95Ms)
+		m.P99Ms = math.Max(m.P99Ms, r.P99Ms)
+	}
+	if m.Success > 0 {
+		m.AvgMs = latencySum / float64(m.Success)
+	}
+	if bins := histBins(hist); bins != nil {
+		m.P50Ms = histPercentile(bins, 50)
+		m.P90Ms = histPercentile(bins, 90)
+		m.P95Ms = histPercentile(bins, 95)
+		m.P99Ms = histPercentile(bins, 99)
+		if histograms {
+			m.Hist = bins
+		}
+	}
+	if m.Duration > 0 {
+		m.RPS = float64(m.Success) / m.Duration.Seconds()
+	}
+	applyThroughput(&m)
+	mergeSeries(&m, rs, histograms)
+	return m
+}
+
+// mergeSeries adds the agents' per-second series up on one timeline, from the earliest agent's first second.
+func mergeSeries(m *Result, rs []Result, histograms bool) {
+	start := int64(0)
+	for _, r := range rs {
+		if len(r.SeriesSec) > 0 && (start == 0 || r.SeriesStart < start) {
+			start = r.SeriesStart
+		}
+	}
+	if start == 0 {
+		return
+	}
+	offsets := make([]int, len(rs))
+	n := 0
+	for i, r := range rs {
+		if len(r.SeriesSec) == 0 {
+			continue
+		}
+		offsets[i] = int(math.Round(float64(r.SeriesStart-start) / 1000))
+		n = max(n, offsets[i]+len(r.SeriesSec))
+	}
+	add := func(dst []float64, off int, src []float64) []float64 {
+		if len(src) == 0 {
+			return dst
+		}
+		if dst == nil {
+			dst = make([]float64, n)
+		}
+		for k, v := range src {
+			dst[off+k] += v
+		}
+		return dst
+	}
+	hists := make([]map[int]int64, n)
+	maxOf := func(dst []float64, off int, src []float64) []float64 {
+		if len(src) == 0 {
+			return dst
+		}
+		if dst == nil {
+			dst = make([]float64, n)
+		}
+		for k, v := range src {
+			dst[off+k] = math.Max(dst[off+k], v)
+		}
+		return dst
+	}
+	haveHist := true
+	for i, r := range rs {
+		if len(r.SeriesSec) == 0 {
+			continue
+		}
+		off := offsets[i]
+		m.SeriesRPS = add(m.SeriesRPS, off, r.SeriesRPS)
+		m.SeriesErr = add(m.SeriesErr, off, r.SeriesErr)
+		m.SeriesMBps = add(m.SeriesMBps, off, r.SeriesMBps)
+		m.SeriesTarget = add(m.SeriesTarget, off, r.SeriesTarget)
+		m.SeriesP50 = maxOf(m.SeriesP50, off, r.SeriesP50)
+		m.SeriesP90 = maxOf(m.SeriesP90, off, r.SeriesP90)
+		m.SeriesP95 = maxOf(m.SeriesP95, off, r.SeriesP95)
+		m.SeriesP99 = maxOf(m.SeriesP99, off, r.SeriesP99)
+		if len(r.SeriesHist) == 0 {
+			haveHist = false
+			continue
+		}
+		for k, bins := range r.SeriesHist {
+			if hists[off+k] == nil {
+				hists[off+k] = map[int]int64{}
+			}
+			addHist(hists[off+k], bins)
+		}
+	}
+	m.SeriesStart = start
+	m.SeriesSec = make([]int, n)
+	for i := range m.SeriesSec {
+		m.SeriesSec[i] = i + 1
+	}
+	if !haveHist {
+		return
+	}
+	m.SeriesP50 = make([]float64, n)
+	m.SeriesP90 = make([]float64, n)
+	m.SeriesP95 = make([]float64, n)
+	m.SeriesP99 = make([]float64, n)
+	if histograms {
+		m.SeriesHist = make([][]histBin, n)
+	}
+	for k, h := range hists {
+		bins := histBins(h)
+		m.SeriesP50[k] = histPercentile(bins, 50)
+		m.SeriesP90[k] = histPercentile(bins, 90)
+		m.SeriesP95[k] = histPercentile(bins, 95)
+		m.SeriesP99[k] = histPercentile(bins, 99)
+		if histograms {
+			m.SeriesHist[k] = bins
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func agentResult(total, success int, dur time.Duration) Result {
+	return Result{Config: "a", Mode: "blocks", Concurrency: 5, Total: total, Success: success, Errors: total - success, Duration: dur}
+}
+
+func TestMergeResults(t *testing.T) {
+	a := agentResult(100, 90, 10*time.Second)
+	a.AvgMs, a.P50Ms, a.P99Ms = 1, 1, 1
+	a.Hist = []histBin{{B: 1, N: 90}}
+	a.ExitCodes = map[string]int{"0": 80, "-13": 10}
+	b := agentResult(10, 10, 20*time.Second)
+	b.AvgMs, b.P50Ms, b.P99Ms = 10, 10, 10
+	b.Hist = []histBin{{B: 10, N: 10}}
+	b.ExitCodes = map[string]int{"0": 10}
+
+	m := mergeResults([]Result{a, b}, true)
+	if m.Total != 110 || m.Success != 100 || m.Errors != 10 || m.Agents != 2 {
+		t.Fatalf("counters total=%d success=%d errors=%d agents=%d", m.Total, m.Success, m.Errors, m.Agents)
+	}
+	if !reflect.DeepEqual(m.ExitCodes, map[string]int{"0": 90, "-13": 10}) {
+		t.Fatalf("exit codes %v", m.ExitCodes)
+	}
+	if m.Duration != 20*time.Second || m.RPS != 5 {
+		t.Fatalf("duration %s rps %.2f", m.Duration, m.RPS)
+	}
+	if m.AvgMs != 1.9 {
+		t.Fatalf("avg %.2f, want the success-weighted 1.9", m.AvgMs)
+	}
+	// percentiles come from the merged histogram, not the slowest agent
+	if m.P50Ms != 1 || m.P90Ms != 1 || m.P95Ms != 10 || m.P99Ms != 10 {
+		t.Fatalf("percentiles p50=%.0f p90=%.0f p95=%.0f p99=%.0f", m.P50Ms, m.P90Ms, m.P95Ms, m.P99Ms)
+	}
+	if !reflect.DeepEqual(m.Hist, []histBin{{B: 1, N: 90}, {B: 10, N: 10}}) {
+		t.Fatalf("hist %v", m.Hist)
+	}
+	if m := mergeResults([]Result{a, b}, false); m.Hist != nil || m.P95Ms != 10 {
+		t.Fatalf("without --histograms: hist %v p95 %.0f", m.Hist, m.P95Ms)
+	}
+
+	// results of agents without histograms report the slowest agent
+	a.Hist, b.Hist = nil, nil
+	m = mergeResults([]Result{a, b}, true)
+	if m.P50Ms != 10 || m.P99Ms != 10 || m.Hist != nil {
+		t.Fatalf("max fallback p50=%.0f p99=%.0f hist=%v", m.P50Ms, m.P99Ms, m.Hist)
+	}
+}
+
+func TestMergeSeries(t *testing.T) {
+	a := agentResult(6, 6, 3*time.Second)
+	a.SeriesStart = 1_000_000
+	a.SeriesSec = []int{1, 2, 3}
+	a.SeriesRPS = []float64{1, 2, 3}
+	a.SeriesP99 = []float64{1, 1, 1}
+	a.SeriesHist = [][]histBin{{{B: 1, N: 1}}, {{B: 1, N: 2}}, {{B: 1, N: 3}}}
+	// the second agent started a second later
+	b := agentResult(30, 30, 2*time.Second)
+	b.SeriesStart = 1_001_000
+	b.SeriesSec = []int{1, 2}
+	b.SeriesRPS = []float64{10, 20}
+	b.SeriesP99 = []float64{5, 5}
+	b.SeriesHist = [][]histBin{{{B: 5, N: 10}}, {{B: 5, N: 20}}}
+
+	var m Result
+	mergeSeries(&m, []Result{a, b}, true)
+	if m.SeriesStart != a.SeriesStart || !reflect.DeepEqual(m.SeriesSec, []int{1, 2, 3}) {
+		t.Fatalf("timeline start %d secs %v", m.SeriesStart, m.SeriesSec)
+	}
+	if !reflect.DeepEqual(m.SeriesRPS, []float64{1, 12, 23}) {
+		t.Fatalf("rps %v", m.SeriesRPS)
+	}
+	if !reflect.DeepEqual(m.SeriesP99, []float64{1, 5, 5}) {
+		t.Fatalf("p99 %v", m.SeriesP99)
+	}
+	if !reflect.DeepEqual(m.SeriesP50, []float64{1, 5, 5}) {
+		t.Fatalf("p50 from merged histograms %v", m.SeriesP50)
+	}
+	if !reflect.DeepEqual(m.SeriesHist[1], []histBin{{B: 1, N: 2}, {B: 5, N: 10}}) {
+		t.Fatalf("second 2 hist %v", m.SeriesHist[1])
+	}
+
+	// one agent without per-second histograms: percentiles are the max across agents
+	b.SeriesHist = nil
+	m = Result{}
+	mergeSeries(&m, []Result{a, b}, true)
+	if !reflect.DeepEqual(m.SeriesP99, []float64{1, 5, 5}) || m.SeriesP50 != nil || m.SeriesHist != nil {
+		t.Fatalf("max fallback p99 %v p50 %v hist %v", m.SeriesP99, m.SeriesP50, m.SeriesHist)
+	}
+
+	m = Result{}
+	mergeSeries(&m, []Result{agentResult(1, 1, time.Second)}, true)
+	if m.SeriesSec != nil {
+		t.Fatalf("series without seconds: %v", m.SeriesSec)
+	}
+}
+
+func TestPollStatusRetries(t *testing.T) {
+	// nothing listens on port 1 of the loopback
+	c := &agentClient{addr: "127.0.0.1:1", http: &http.Client{Timeout: time.Second}, status: agentStatus{State: "running"}}
+	now := time.Now()
+	for i := 1; i < agentStatusRetries; i++ {
+		if st := c.pollStatus(now); st.State != "running" {
+			t.Fatalf("failure %d: state %q, want the last known state", i, st.State)
+		}
+		if !c.retryAt.After(now) {
+			t.Fatalf("failure %d: no backoff", i)
+		}
+		if st := c.pollStatus(now); st.State != "running" || c.statusErrors != i {
+			t.Fatalf("polled again during the backoff: %d errors", c.statusErrors)
+		}
+		now = c.retryAt
+	}
+	if st := c.pollStatus(now); st.State != "failed" {
+		t.Fatalf("state %q after %d failures, want failed", st.State, agentStatusRetries)
+	}
+}
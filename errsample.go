@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errorSample is one failed request in full, with the job's params.
+type errorSample struct {
+	Ts          string            `json:"ts"`
+	Config      string            `json:"config"`
+	Targets     string            `json:"targets,omitempty"`
+	Mode        string            `json:"mode"`
+	Concurrency int               `json:"concurrency"`
+	Request     string            `json:"request"`
+	Class       string            `json:"class"`
+	LSCode      *int32            `json:"ls_code,omitempty"`
+	Params      map[string]string `json:"params,omitempty"`
+	LatencyMs   int64             `json:"latency_ms"`
+	Error       string            `json:"error"`
+}
+
+type errorSampleKey struct {
+	Config string
+	Class  string
+}
+
+// errorSampler keeps the first n errors of every config and error class.
+type errorSampler struct {
+	mu      sync.Mutex
+	n       int
+	kept    map[errorSampleKey]int
+	samples []errorSample
+}
+
+func newErrorSampler(n int) *errorSampler {
+	return &errorSampler{n: n, kept: map[errorSampleKey]int{}}
+}
+
+// add records a failed request if its class still has room. Classifying is the only cost once it is full.
+func (s *errorSampler) add(span *traceSpan, cfg, targets, mode string, conc int, req string, start time.Time, err error) {
+	if s == nil || err == nil {
+		return
+	}
+	text := err.Error()
+	k := errorSampleKey{cfg, classifyError(text)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.kept[k] >= s.n {
+		return
+	}
+	s.kept[k]++
+	e := errorSample{
+		Ts:          start.UTC().Format(time.RFC3339Nano),
+		Config:      cfg,
+		Targets:     targets,
+		Mode:        mode,
+		Concurrency: conc,
+		Request:     req,
+		Class:       k.Class,
+		Params:      span.paramMap(),
+		LatencyMs:   time.Since(start).Milliseconds(),
+		Error:       text,
+	}
+	if code, _, ok := liteServerError(text); ok {
+		e.LSCode = &code
+	}
+	s.samples = append(s.samples, e)
+}
+
+// load keeps the samples of cells an earlier run finished, for --resume.
+func (s *errorSampler) load(path string, store *resultStore) error {
+	if s == nil {
+		return nil
+	}
+	old, err := readErrorSamples(path)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range old {
+		if !store.has(e.Config, e.Mode, e.Concurrency) {
+			continue
+		}
+		s.kept[errorSampleKey{e.Config, e.Class}]++
+		s.samples = append(s.samples, e)
+	}
+	return nil
+}
+
+// list returns the samples ordered by config, class and time.
+func (s *errorSampler) list() []errorSample {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	out := append([]errorSample(nil), s.samples...)
+	s.mu.Unlock()
+	sortErrorSamples(out)
+	return out
+}
+
+func sortErrorSamples(list []errorSample) {
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Config != list[j].Config {
+			return list[i].Config < list[j].Config
+		}
+		if list[i].Class != list[j].Class {
+			return list[i].Class < list[j].Class
+		}
+		return list[i].Ts < list[j].Ts
+	})
+}
+
+// readErrorSamples reads error_samples.json; a missing file is no samples.
+func readErrorSamples(path string) ([]errorSample, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []errorSample
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func formatSampleParams(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+params[k])
+	}
+	return strings.Join(parts, " ")
+}
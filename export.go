@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// exportNames are the --export kinds, by the file they write in the results dir when no path is given.
+var exportNames = map[string]string{
+	"influx":      "series.lp",
+	"openmetrics": "series.om",
+}
+
+// exportTarget is one --export entry.
+type exportTarget struct {
+	Kind string
+	Path string
+}
+
+// parseExports reads --export: comma-separated kind=path entries, or a bare kind for the results dir.
+func parseExports(spec string) ([]exportTarget, error) {
+	var out []exportTarget
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kind, path, _ := strings.Cut(part, "=")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if _, ok := exportNames[kind]; !ok {
+			return nil, fmt.Errorf("unknown export %q (want influx or openmetrics)", kind)
+		}
+		out = append(out, exportTarget{Kind: kind, Path: strings.TrimSpace(path)})
+	}
+	return out, nil
+}
+
+// seriesPoint is one second of a series; measurement_field is its OpenMetrics name.
+type seriesPoint struct {
+	Measurement string
+	Tags        [][2]string
+	Ms          int64
+	Fields      []seriesField
+}
+
+type seriesField struct {
+	Name  string
+	Value float64
+}
+
+// seriesPoints flattens the per-second series of a run into timestamped points; fixed runs have none.
+func seriesPoints(run string, results []Result, methods map[methodKey]methodSeries, errorSeries map[errorSeriesKey]errorSeries) []seriesPoint {
+	var points []seriesPoint
+	at := func(s []float64, i int) (float64, bool) {
+		if i >= len(s) || math.IsNaN(s[i]) || math.IsInf(s[i], 0) {
+			return 0, false
+		}
+		return s[i], true
+	}
+	field := func(fields []seriesField, name string, s []float64, i int) []seriesField {
+		if v, ok := at(s, i); ok {
+			fields = append(fields, seriesField{name, v})
+		}
+		return fields
+	}
+	for _, r := range results {
+		if r.SeriesStart == 0 {
+			continue
+		}
+		tags := [][2]string{{"run", run}, {"config", r.Config}, {"mode", r.Mode}, {"concurrency", strconv.Itoa(r.Concurrency)}}
+		if r.Targets != "" {
+			tags = append(tags, [2]string{"targets", r.Targets})
+		}
+		if r.AgeBucket != "" {
+			tags = append(tags, [2]string{"age_bucket", r.AgeBucket})
+		}
+		for i, sec := range r.SeriesSec {
+			var f []seriesField
+			f = field(f, "rps", r.SeriesRPS, i)
+			f = field(f, "errors", r.SeriesErr, i)
+			f = field(f, "p50_ms", r.SeriesP50, i)
+			f = field(f, "p90_ms", r.SeriesP90, i)
+			f = field(f, "p95_ms", r.SeriesP95, i)
+			f = field(f, "p99_ms", r.SeriesP99, i)
+			f = field(f, "mbps", r.SeriesMBps, i)
+			f = field(f, "target", r.SeriesTarget, i)
+			if len(f) > 0 {
+				points = append(points, seriesPoint{"ls_load", tags, r.SeriesStart + int64(sec-1)*1000, f})
+			}
+		}
+	}
+
+	mkeys := make([]methodKey, 0, len(methods))
+	for k := range methods {
+		mkeys = append(mkeys, k)
+	}
+	sort.Slice(mkeys, func(i, j int) bool {
+		a, b := mkeys[i], mkeys[j]
+		if a.Config != b.Config {
+			return a.Config < b.Config
+		}
+		if a.Mode != b.Mode {
+			return a.Mode < b.Mode
+		}
+		if a.Concurrency != b.Concurrency {
+			return a.Concurrency < b.Concurrency
+		}
+		return a.Method < b.Method
+	})
+	for _, k := range mkeys {
+		m := methods[k]
+		tags := [][2]string{{"run", run}, {"config", k.Config}, {"mode", k.Mode}, {"concurrency", strconv.Itoa(k.Concurrency)}, {"method", k.Method}}
+		for i, sec := range m.Sec {
+			var f []seriesField
+			f = field(f, "ok", m.OK, i)
+			f = field(f, "errors", m.Err, i)
+			f = field(f, "p50_ms", m.P50, i)
+			f = field(f, "p90_ms", m.P90, i)
+			f = field(f, "p95_ms", m.P95, i)
+			f = field(f, "p99_ms", m.P99, i)
+			if len(f) > 0 {
+				points = append(points, seriesPoint{"ls_load_method", tags, m.Start + int64(sec-1)*1000, f})
+			}
+		}
+	}
+
+	ekeys := make([]errorSeriesKey, 0, len(errorSeries))
+	for k := range errorSeries {
+		ekeys = append(ekeys, k)
+	}
+	sort.Slice(ekeys, func(i, j int) bool {
+		a, b := ekeys[i], ekeys[j]
+		if a.Config != b.Config {
+			return a.Config < b.Config
+		}
+		if a.Mode != b.Mode {
+			return a.Mode < b.Mode
+		}
+		if a.Concurrency != b.Concurrency {
+			return a.Concurrency < b.Concurrency
+		}
+		return a.Code < b.Code
+	})
+	for _, k := range ekeys {
+		s := errorSeries[k]
+		tags := [][2]string{{"run", run}, {"config", k.Config}, {"mode", k.Mode}, {"concurrency", strconv.Itoa(k.Concurrency)}, {"code", k.Code}}
+		for i, sec := range s.Sec {
+			if v, ok := at(s.Cnt, i); ok {
+				points = append(points, seriesPoint{"ls_load_error_class", tags, s.Start + int64(sec-1)*1000, []seriesField{{"errors", v}}})
+			}
+		}
+	}
+	return points
+}
+
+// writeExports writes every --export target of a run whose results are in dir.
+func writeExports(targets []exportTarget, dir string, results []Result, methods map[methodKey]methodSeries, errorSeries map[errorSeriesKey]errorSeries) {
+	if len(targets) == 0 {
+		return
+	}
+	points := seriesPoints(filepath.Base(dir), results, methods, errorSeries)
+	for _, t := range targets {
+		path := t.Path
+		if path == "" {
+			path = filepath.Join(dir, exportNames[t.Kind])
+		}
+		var err error
+		switch t.Kind {
+		case "influx":
+			err = writeInflux(path, points)
+		case "openmetrics":
+			err = writeOpenMetrics(path, points)
+		}
+		if err != nil {
+			fmt.Printf("failed to write %s export: %v\n", t.Kind, err)
+			continue
+		}
+		fmt.Printf("Exported %s series to: %s\n", t.Kind, path)
+	}
+}
+
+// writeInflux writes the points as InfluxDB line protocol with nanosecond timestamps.
+func writeInflux(path string, points []seriesPoint) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for _, p := range points {
+		w.WriteString(p.Measurement)
+		for _, t := range p.Tags {
+			if t[1] == "" {
+				continue
+			}
+			w.WriteString("," + influxEscape(t[0]) + "=" + influxEscape(t[1]))
+		}
+		for i, fl := range p.Fields {
+			sep := ","
+			if i == 0 {
+				sep = " "
+			}
+			w.WriteString(sep + influxEscape(fl.Name) + "=" + strconv.FormatFloat(fl.Value, 'f', -1, 64))
+		}
+		w.WriteString(" " + strconv.FormatInt(p.Ms*1e6, 10) + "\n")
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+var influxReplacer = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+
+func influxEscape(s string) string {
+	return influxReplacer.Replace(s)
+}
+
+// writeOpenMetrics writes one gauge family per measurement field, timestamps in seconds.
+func writeOpenMetrics(path string, points []seriesPoint) error {
+	var order []string
+	families := map[string]*strings.Builder{}
+	for _, p := range points {
+		var labels strings.Builder
+		for _, t := range p.Tags {
+			if t[1] == "" {
+				continue
+			}
+			if labels.Len() > 0 {
+				labels.WriteByte(',')
+			}
+			labels.WriteString(t[0] + `="` + openMetricsEscape(t[1]) + `"`)
+		}
+		ts := strconv.FormatFloat(float64(p.Ms)/1000, 'f', 3, 64)
+		for _, fl := range p.Fields {
+			name := p.Measurement + "_" + fl.Name
+			b := families[name]
+			if b == nil {
+				b = &strings.Builder{}
+				families[name] = b
+				order = append(order, name)
+			}
+			b.WriteString(name + "{" + labels.String() + "} " + strconv.FormatFloat(fl.Value, 'f', -1, 64) + " " + ts + "\n")
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	for _, name := range order {
+		w.WriteString("# TYPE " + name + " gauge\n")
+		w.WriteString(families[name].String())
+	}
+	w.WriteString("# EOF\n")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+var openMetricsReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func openMetricsEscape(s string) string {
+	return openMetricsReplacer.Replace(s)
+}
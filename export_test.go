@@ -0,0 +1,117 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseExports(t *testing.T) {
+	got, err := parseExports(" influx , OpenMetrics=/tmp/x.om,,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != (exportTarget{"influx", ""}) || got[1] != (exportTarget{"openmetrics", "/tmp/x.om"}) {
+		t.Fatalf("parseExports = %+v", got)
+	}
+	if got, err := parseExports(""); err != nil || len(got) != 0 {
+		t.Fatalf("empty spec: %v %v", got, err)
+	}
+	if _, err := parseExports("influx,prometheus=x"); err == nil {
+		t.Fatal("unknown kind accepted")
+	}
+}
+
+func testSeriesPoints() []seriesPoint {
+	var r Result
+	r.Config, r.Mode, r.Concurrency, r.Targets = `a "b"`, "blocks", 4, "h:1"
+	r.SeriesStart = 1700000000000
+	r.SeriesSec = []int{1, 2}
+	r.SeriesRPS = []float64{10, 12.5}
+	r.SeriesP95 = []float64{math.NaN(), 30}
+	// fixed dataset runs have no timeline
+	var fixed Result
+	fixed.SeriesSec, fixed.SeriesRPS = []int{1}, []float64{1}
+
+	errs := map[errorSeriesKey]errorSeries{
+		{Config: "a", Mode: "blocks", Concurrency: 4, Code: "timeout"}: {Start: 1700000001000, Sec: []int{1}, Cnt: []float64{3}},
+	}
+	return seriesPoints("run 1", []Result{r, fixed}, nil, errs)
+}
+
+func TestSeriesPoints(t *testing.T) {
+	points := testSeriesPoints()
+	if len(points) != 3 {
+		t.Fatalf("%d points, want 3", len(points))
+	}
+	if p := points[0]; p.Ms != 1700000000000 || len(p.Fields) != 1 || p.Fields[0].Name != "rps" {
+		t.Fatalf("first second without its NaN p95: %+v", p)
+	}
+	if p := points[1]; p.Ms != 1700000001000 || len(p.Fields) != 2 {
+		t.Fatalf("second second: %+v", p)
+	}
+	if p := points[2]; p.Measurement != "ls_load_error_class" || p.Tags[4] != [2]string{"code", "timeout"} {
+		t.Fatalf("error class point: %+v", p)
+	}
+}
+
+func TestWriteInflux(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "series.lp")
+	if err := writeInflux(path, testSeriesPoints()); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	want := `ls_load,run=run\ 1,config=a\ "b",mode=blocks,concurrency=4,targets=h:1 rps=10 1700000000000000000
+ls_load,run=run\ 1,config=a\ "b",mode=blocks,concurrency=4,targets=h:1 rps=12.5,p95_ms=30 1700000001000000000
+ls_load_error_class,run=run\ 1,config=a,mode=blocks,concurrency=4,code=timeout errors=3 1700000001000000000
+`
+	if string(data) != want {
+		t.Fatalf("influx:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "series.om")
+	if err := writeOpenMetrics(path, testSeriesPoints()); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	want := `# TYPE ls_load_rps gauge
+ls_load_rps{run="run 1",config="a \"b\"",mode="blocks",concurrency="4",targets="h:1"} 10 1700000000.000
+ls_load_rps{run="run 1",config="a \"b\"",mode="blocks",concurrency="4",targets="h:1"} 12.5 1700000001.000
+# TYPE ls_load_p95_ms gauge
+ls_load_p95_ms{run="run 1",config="a \"b\"",mode="blocks",concurrency="4",targets="h:1"} 30 1700000001.000
+# TYPE ls_load_error_class_errors gauge
+ls_load_error_class_errors{run="run 1",config="a",mode="blocks",concurrency="4",code="timeout"} 3 1700000001.000
+# EOF
+`
+	if string(data) != want {
+		t.Fatalf("openmetrics:\n%s\nwant:\n%s", data, want)
+	}
+
+	// every family is declared once and its samples follow it without interleaving
+	seen := map[string]bool{}
+	family := ""
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "# EOF\n"), "\n") {
+		if name, ok := strings.CutPrefix(line, "# TYPE "); ok {
+			family = strings.Fields(name)[0]
+			if seen[family] {
+				t.Fatalf("family %s declared twice", family)
+			}
+			seen[family] = true
+			continue
+		}
+		if line != "" && !strings.HasPrefix(line, family+"{") {
+			t.Fatalf("sample %q outside its family %s", line, family)
+		}
+	}
+
+	if err := writeOpenMetrics(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "# EOF\n" {
+		t.Fatalf("empty export %q", data)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// freshnessSeries is one liteserver's view of the masterchain head: Lag is blocks behind the best server
+// (-1 without a successful poll), availability the delay behind the first server to report a block.
+type freshnessSeries struct {
+	Config     string    `json:"config"`
+	Host       string    `json:"host"`
+	StartMs    int64     `json:"start_ms"`
+	Sec        []int     `json:"sec"`
+	Lag        []float64 `json:"lag"`
+	Polls      int       `json:"polls"`
+	Errors     int       `json:"errors"`
+	LastSeqno  uint32    `json:"last_seqno"`
+	AvgLag     float64   `json:"avg_lag"`
+	MaxLag     int       `json:"max_lag"`
+	AvailP50Ms float64   `json:"avail_p50_ms"`
+	AvailP95Ms float64   `json:"avail_p95_ms"`
+	AvailMaxMs float64   `json:"avail_max_ms"`
+}
+
+type freshnessServer struct {
+	config, host string
+	target       connectTarget
+	lastSeqno    uint32
+	maxLagPerSec map[int]int
+	lagSum       int
+	polls        int
+	errors       int
+	maxLag       int
+	avail        []int64
+}
+
+// freshnessTracker polls every liteserver over its own ADNL connection for the whole run.
+type freshnessTracker struct {
+	interval  time.Duration
+	timeout   time.Duration
+	start     time.Time
+	mu        sync.Mutex
+	maxSeqno  uint32
+	firstSeen map[uint32]time.Time
+	servers   []*freshnessServer
+	stop      chan struct{}
+	wg        sync.WaitGroup
+}
+
+func newFreshnessTracker(interval, timeout time.Duration) *freshnessTracker {
+	return &freshnessTracker{
+		interval:  interval,
+		timeout:   timeout,
+		firstSeen: map[uint32]time.Time{},
+		stop:      make(chan struct{}),
+	}
+}
+
+func (t *freshnessTracker) add(cfgName string, targets []connectTarget) {
+	for _, ct := range targets {
+		t.servers = append(t.servers, &freshnessServer{config: cfgName, host: ct.Host, target: ct, maxLagPerSec: map[int]int{}})
+	}
+}
+
+func (t *freshnessTracker) Start() {
+	t.start = time.Now()
+	for _, s := range t.servers {
+		t.wg.Add(1)
+		go t.poll(s)
+	}
+}
+
+func (t *freshnessTracker) poll(s *freshnessServer) {
+	defer t.wg.Done()
+	var c *adnlConn
+	defer func() {
+		if c != nil {
+			c.Close()
+		}
+	}()
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		seqno, err := t.query(&c, s.target)
+		t.observe(s, time.Now(), seqno, err)
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// query asks for the head over *c, reconnecting first when the previous poll broke the connection.
+func (t *freshnessTracker) query(c **adnlConn, target connectTarget) (uint32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+	if *c == nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", target.Host)
+		if err != nil {
+			return 0, err
+		}
+		ac, err := adnlHandshake(ctx, conn, target.Key)
+		if err != nil {
+			conn.Close()
+			return 0, err
+		}
+		*c = ac
+	}
+	info, _, err := (*c).queryMasterchainInfo(ctx)
+	if err != nil {
+		(*c).Close()
+		*c = nil
+		return 0, err
+	}
+	return info.Last.Seqno, nil
+}
+
+func (t *freshnessTracker) observe(s *freshnessServer, now time.Time, seqno uint32, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.polls++
+	if err != nil {
+		s.errors++
+		return
+	}
+	if seqno > t.maxSeqno {
+		from := t.maxSeqno + 1
+		if t.maxSeqno == 0 {
+			from = seqno
+		}
+		for k := from; k <= seqno; k++ {
+			t.firstSeen[k] = now
+		}
+		t.maxSeqno = seqno
+	}
+	if s.lastSeqno > 0 {
+		for k := s.lastSeqno + 1; k <= seqno; k++ {
+			if seen, ok := t.firstSeen[k]; ok {
+				s.avail = append(s.avail, now.Sub(seen).Milliseconds())
+			}
+		}
+	}
+	if seqno > s.lastSeqno {
+		s.lastSeqno = seqno
+	}
+	lag := int(t.maxSeqno - seqno)
+	sec := int(now.Sub(t.start).Seconds())
+	if prev, ok := s.maxLagPerSec[sec]; !ok || lag > prev {
+		s.maxLagPerSec[sec] = lag
+	}
+	s.lagSum += lag
+	if lag > s.maxLag {
+		s.maxLag = lag
+	}
+}
+
+// Stop ends polling and returns one series per server, with per-second max lag.
+func (t *freshnessTracker) Stop() []freshnessSeries {
+	close(t.stop)
+	t.wg.Wait()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	total := int(time.Since(t.start).Seconds()) + 1
+	out := make([]freshnessSeries, 0, len(t.servers))
+	for _, s := range t.servers {
+		fs := freshnessSeries{
+			Config:    s.config,
+			Host:      s.host,
+			StartMs:   t.start.UTC().UnixMilli(),
+			Sec:       make([]int, total),
+			Lag:       make([]float64, total),
+			Polls:     s.polls,
+			Errors:    s.errors,
+			LastSeqno: s.lastSeqno,
+			MaxLag:    s.maxLag,
+		}
+		for i := 0; i < total; i++ {
+			fs.Sec[i] = i + 1
+			fs.Lag[i] = -1
+			if lag, ok := s.maxLagPerSec[i]; ok {
+				fs.Lag[i] = float64(lag)
+			}
+		}
+		if ok := s.polls - s.errors; ok > 0 {
+			fs.AvgLag = float64(s.lagSum) / float64(ok)
+		}
+		if len(s.avail) > 0 {
+			_, fs.AvailP50Ms, _, fs.AvailP95Ms, _, fs.AvailMaxMs = computeMetrics(s.avail, len(s.avail))
+		}
+		out = append(out, fs)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Config != out[j].Config {
+			return out[i].Config < out[j].Config
+		}
+		return out[i].Host < out[j].Host
+	})
+	return out
+}
+
+func printFreshness(series []freshnessSeries) {
+	for _, s := range series {
+		fmt.Printf("  freshness %s %s: polls=%d err=%d avg_lag=%.2f max_lag=%d avail_p95=%.0fms\n",
+			s.Config, s.Host, s.Polls, s.Errors, s.AvgLag, s.MaxLag, s.AvailP95Ms)
+	}
+}
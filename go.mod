@@ -2,7 +2,10 @@ module ls-load
 
 go 1.24.0
 
-require github.com/tonkeeper/tongo v1.16.64
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/tonkeeper/tongo v1.16.64
+)
 
 require (
 	github.com/oasisprotocol/curve25519-voi v0.0.0-20220328075252-7dd334e3daae // indirect
@@ -0,0 +1,170 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed index_template.html
+var indexTemplate string
+
+// indexRun is one results dir as listed on the index page.
+type indexRun struct {
+	Name    string    `json:"name"`
+	Time    time.Time `json:"time"`
+	Configs []string  `json:"configs"`
+	Modes   []string  `json:"modes"`
+	Cells   int       `json:"cells"`
+	Success int       `json:"success"`
+	Errors  int       `json:"errors"`
+	PeakRPS float64   `json:"peak_rps"`
+	MaxP99  float64   `json:"max_p99_ms"`
+	Report  bool      `json:"report"`
+	// Partial is set when the run has no summary.json and was read from results.jsonl.
+	Partial bool `json:"partial,omitempty"`
+}
+
+// indexCell is one (config, mode, concurrency) result of a run, a point of the trend charts.
+type indexCell struct {
+	Run         string  `json:"run"`
+	Config      string  `json:"config"`
+	Mode        string  `json:"mode"`
+	Concurrency int     `json:"concurrency"`
+	RPS         float64 `json:"rps"`
+	P99Ms       float64 `json:"p99_ms"`
+	ErrorRate   float64 `json:"error_rate"`
+}
+
+// runIndex is the `ls-load index [DIR]` subcommand: it writes DIR/index.html over every run in DIR.
+func runIndex(args []string) {
+	dir := envOr("LS_LOAD_OUT", "results")
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	runs, cells, err := scanResultsDirs(dir)
+	if err != nil {
+		exitf("failed to read results: %v", err)
+	}
+	if len(runs) == 0 {
+		exitf("no runs found in %s", dir)
+	}
+	path := filepath.Join(dir, "index.html")
+	if err := writeIndexHTML(path, runs, cells); err != nil {
+		exitf("failed to write index: %v", err)
+	}
+	fmt.Printf("Index of %d runs written to: %s\n", len(runs), path)
+}
+
+// scanResultsDirs reads every run under dir, oldest first. Dirs without results are skipped.
+func scanResultsDirs(dir string) ([]indexRun, []indexCell, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	var runs []indexRun
+	var cells []indexCell
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		runDir := filepath.Join(dir, e.Name())
+		run := indexRun{Name: e.Name()}
+		results, err := readResultsJSON(filepath.Join(runDir, "summary.json"))
+		if err != nil {
+			results, err = readResultsLog(filepath.Join(runDir, resultsLogName))
+			run.Partial = true
+		}
+		if err != nil || len(results) == 0 {
+			continue
+		}
+		if t, err := time.ParseInLocation("20060102-150405", e.Name(), time.Local); err == nil {
+			run.Time = t
+		} else if info, err := e.Info(); err == nil {
+			run.Time = info.ModTime()
+		}
+		if _, err := os.Stat(filepath.Join(runDir, "report.html")); err == nil {
+			run.Report = true
+		}
+		run.Configs = uniqueConfigs(results)
+		modes := map[string]bool{}
+		for _, r := range results {
+			if !modes[r.Mode] {
+				modes[r.Mode] = true
+				run.Modes = append(run.Modes, r.Mode)
+			}
+			run.Cells++
+			run.Success += r.Success
+			run.Errors += r.Errors
+			run.PeakRPS = max(run.PeakRPS, r.RPS)
+			run.MaxP99 = max(run.MaxP99, r.P99Ms)
+			cell := indexCell{Run: run.Name, Config: r.Config, Mode: r.Mode, Concurrency: r.Concurrency, RPS: r.RPS, P99Ms: r.P99Ms}
+			if n := r.Success + r.Errors; n > 0 {
+				cell.ErrorRate = float64(r.Errors) / float64(n)
+			}
+			cells = append(cells, cell)
+		}
+		runs = append(runs, run)
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].Time.Before(runs[j].Time) })
+	return runs, cells, nil
+}
+
+func writeIndexHTML(path string, runs []indexRun, cells []indexCell) error {
+	indexJSON, _ := json.Marshal(struct {
+		Runs  []indexRun  `json:"runs"`
+		Cells []indexCell `json:"cells"`
+	}{runs, cells})
+	tmpl := strings.TrimSpace(indexTemplate)
+	if tmpl == "" {
+		return fmt.Errorf("index template is empty")
+	}
+	body := strings.ReplaceAll(tmpl, "{{TIME}}", time.Now().Format(time.RFC3339))
+	body = strings.ReplaceAll(body, "{{RUNS_SECTION}}", buildIndexRunsSection(runs))
+	body = strings.ReplaceAll(body, "{{INDEX_JSON}}", string(indexJSON))
+	return os.WriteFile(path, []byte(body), 0o644)
+}
+
+// buildIndexRunsSection lists the runs newest first.
+func buildIndexRunsSection(runs []indexRun) string {
+	var b strings.Builder
+	b.WriteString("<section class=\"section\">")
+	b.WriteString("<h2>Runs</h2>")
+	b.WriteString("<table class=\"table\"><thead><tr>")
+	for _, h := range []string{"Run", "Date", "Configs", "Modes", "Cells", "Requests", "Errors", "Peak RPS", "Max P99"} {
+		b.WriteString("<th>" + h + "</th>")
+	}
+	b.WriteString("</tr></thead><tbody>")
+	for i := len(runs) - 1; i >= 0; i-- {
+		r := runs[i]
+		name := htmlEsc(r.Name)
+		if r.Report {
+			name = "<a href=\"" + htmlEsc(r.Name) + "/report.html\">" + name + "</a>"
+		}
+		if r.Partial {
+			name += " <span class=\"badge\">partial</span>"
+		}
+		errRate := 0.0
+		if n := r.Success + r.Errors; n > 0 {
+			errRate = float64(r.Errors) / float64(n) * 100
+		}
+		b.WriteString("<tr class=\"item\">")
+		b.WriteString("<td>" + name + "</td>")
+		b.WriteString("<td>" + r.Time.Format("2006-01-02 15:04") + "</td>")
+		b.WriteString("<td>" + htmlEsc(strings.Join(r.Configs, ", ")) + "</td>")
+		b.WriteString("<td>" + htmlEsc(strings.Join(r.Modes, ", ")) + "</td>")
+		b.WriteString("<td>" + strconv.Itoa(r.Cells) + "</td>")
+		b.WriteString("<td>" + strconv.Itoa(r.Success+r.Errors) + "</td>")
+		b.WriteString(fmt.Sprintf("<td>%d (%.2f%%)</td>", r.Errors, errRate))
+		b.WriteString(fmt.Sprintf("<td>%.1f</td><td>%.1f ms</td>", r.PeakRPS, r.MaxP99))
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</tbody></table></section>")
+	return b.String()
+}
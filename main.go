@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,9 +19,17 @@ import (
 type Mode string
 
 const (
-	ModeBlocks   Mode = "blocks"
-	ModeAccounts Mode = "accounts"
-	ModeBoth     Mode = "both"
+	ModeBlocks       Mode = "blocks"
+	ModeAccounts     Mode = "accounts"
+	ModeBoth         Mode = "both"
+	ModeRunMethod    Mode = "runmethod"
+	ModeTransactions Mode = "transactions"
+	ModeConfig       Mode = "config"
+	ModeProofs       Mode = "proofs"
+	ModeSend         Mode = "send"
+	ModeConnect      Mode = "connect"
+	ModeVerify       Mode = "verify"
+	ModeSession      Mode = "session"
 )
 
 type Result struct {
@@ -29,6 +38,7 @@ type Result struct {
 	Mode        string        `json:"mode"`
 	Concurrency int           `json:"concurrency"`
 	Total       int           `json:"total"`
+	Seed        int64         `json:"seed"`
 	Success     int           `json:"success"`
 	Errors      int           `json:"errors"`
 	Duration    time.Duration `json:"duration"`
@@ -47,12 +57,48 @@ type Result struct {
 	SeriesP95   []float64     `json:"series_p95,omitempty"`
 	SeriesP99   []float64     `json:"series_p99,omitempty"`
 	SeriesStart int64         `json:"series_start_ms,omitempty"`
+	ProfileStats
+	SessionStats
+	SoakStats
+	NotFound int `json:"not_found,omitempty"`
+	AgeStats
+	MethodStats
+	Pages []pageStat `json:"pages,omitempty"`
+	Bytes int64      `json:"bytes,omitempty"`
+	MBps  float64    `json:"mb_per_sec,omitempty"`
+	ProofStats
+	SendStats
+	PayloadStats
+	ConnectStats
+	ClientStats
+	VerifyStats
+	HistStats
+	Agents     int    `json:"agents,omitempty"`
+	LogDropped int    `json:"log_dropped,omitempty"`
+	RequestLog string `json:"request_log,omitempty"`
 }
 
 func main() {
 	loadDotEnv(envOr("LS_LOAD_ENV", ".env"))
+	if len(os.Args) > 1 && os.Args[1] == "agent" {
+		runAgent(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "index" {
+		runIndex(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "convert-log" {
+		runConvertLog(os.Args[2:])
+		return
+	}
+	os.Exit(run())
+}
 
+// run is the load test itself; it returns the exit code so deferred cleanup runs before main exits.
+func run() int {
 	var (
+		modeStr            = flag.String("mode", envOr("LS_LOAD_MODE", "both"), "Comma-separated workloads: blocks|accounts|both|runmethod|transactions|config|proofs|send|connect|verify|session")
 		configsStr         = flag.String("configs", envOr("LS_LOAD_CONFIGS", "config.json"), "Comma-separated config paths or globs (optional alias: name=path)")
 		concurrency        = flag.String("concurrency", envOr("LS_LOAD_CONCURRENCY", "5,10,20,50"), "Comma-separated concurrency levels")
 		stepsStr           = flag.String("steps", envOr("LS_LOAD_STEPS", ""), "Comma-separated step concurrency levels (overrides --concurrency)")
@@ -60,30 +106,114 @@ func main() {
 		blocksSpec         = flag.String("blocks", envOr("LS_LOAD_BLOCKS", "last:200"), "Block range: last:N or range:FROM-TO (masterchain seqno)")
 		blocksRand         = flag.Bool("blocks-random", envOrBool("LS_LOAD_BLOCKS_RANDOM", false), "Randomize block selection per request (reduces caching)")
 		blocksRefStr       = flag.String("blocks-refresh", envOr("LS_LOAD_BLOCKS_REFRESH", "5s"), "Refresh interval for last:N when blocks-random is on")
+		ageBucketsStr      = flag.String("age-buckets", envOr("LS_LOAD_AGE_BUCKETS", ""), "Block age buckets for archive depth test (e.g. 0-1h,1h-1d,1d-7d,30d+)")
 		accountsFile       = flag.String("accounts", envOr("LS_LOAD_ACCOUNTS", ""), "Path to file with account addresses (one per line)")
 		accountsN          = flag.Int("accounts-count", envOrInt("LS_LOAD_ACCOUNTS_COUNT", 10000), "Number of random accounts when --accounts not set")
 		accountsWarm       = flag.Bool("accounts-warmup", true, "Warm up accounts by scanning recent shard blocks")
 		accountsWarmBlocks = flag.Int("accounts-warmup-blocks", envOrInt("LS_LOAD_ACCOUNTS_WARMUP_BLOCKS", 8), "Masterchain blocks to scan during warmup")
 		accountsShuf       = flag.Bool("accounts-shuffle", envOrBool("LS_LOAD_ACCOUNTS_SHUFFLE", false), "Shuffle account list on load")
+		methodsFile        = flag.String("methods", envOr("LS_LOAD_METHODS", ""), "Path to get-method list for runmethod mode (<account> <method> [args] per line)")
+		txPages            = flag.Int("tx-pages", envOrInt("LS_LOAD_TX_PAGES", 4), "GetTransactions pages to walk back per account in transactions mode")
+		txPageSize         = flag.Int("tx-page-size", envOrInt("LS_LOAD_TX_PAGE_SIZE", 16), "Transactions per GetTransactions page (max 16)")
+		configParamsStr    = flag.String("config-params", envOr("LS_LOAD_CONFIG_PARAMS", ""), "Comma-separated GetConfigParams ids for config mode (default: 0,1,12,15,20,21,24,25,32,34,36)")
+		proofClients       = flag.Int("proof-clients", envOrInt("LS_LOAD_PROOF_CLIENTS", 50), "Light clients to sync per step in proofs mode (with --duration clients resync until it ends)")
+		sendCount          = flag.Int("send-count", envOrInt("LS_LOAD_SEND_COUNT", 1000), "External messages per step in send mode when --duration is not set")
+		clientsN           = flag.Int("clients", envOrInt("LS_LOAD_CLIENTS", 0), "Independent single-connection clients to spread workers over, round-robin across liteservers (0 = one shared client)")
+		sessionStr         = flag.String("session", envOr("LS_LOAD_SESSION", defaultSessionScript), "Virtual-user script for session mode: masterchain, account, method:NAME, transactions, shards; STEP~DIST overrides the think time before a step")
+		thinkStr           = flag.String("think", envOr("LS_LOAD_THINK", "uniform:500ms-2s"), "Think time between session steps: none, const:D, uniform:MIN-MAX or exp:MEAN")
+		profileStr         = flag.String("profile", envOr("LS_LOAD_PROFILE", ""), "Load shape within each timed run: ramp[:DUR], spike:Mx@AT+LEN, sawtooth:PERIOD or stepdown:K (empty = flat)")
+		profileRate        = flag.Float64("profile-rate", envOrFloat("LS_LOAD_PROFILE_RATE", 0), "Shape an open-loop request rate (req/s) instead of the worker count; concurrency caps requests in flight")
+		seedFlag           = flag.Int64("seed", int64(envOrInt("LS_LOAD_SEED", 0)), "Seed for every random choice (accounts, block picks, shuffles); 0 = random, recorded in summary.json")
+		resumeDir          = flag.String("resume", envOr("LS_LOAD_RESUME", ""), "Resume an interrupted run in this results dir (e.g. results/20240101-120000): finished cells are skipped")
+		soakIntervalStr    = flag.String("soak-interval", envOr("LS_LOAD_SOAK_INTERVAL", ""), "Soak mode: checkpoint every timed run at this interval into soak.jsonl and flag drift (e.g. 10m; empty = off)")
+		soakDrift          = flag.Float64("soak-drift", envOrFloat("LS_LOAD_SOAK_DRIFT", 0.2), "Relative change of p95 or RPS against the first soak interval that counts as drift")
+		sloP99             = flag.Float64("slo-p99", envOrFloat("LS_LOAD_SLO_P99", 0), "SLO: max p99 latency (ms) of every config/mode/concurrency; a miss fails the run (0 = off)")
+		sloErrorRate       = flag.Float64("slo-error-rate", envOrFloat("LS_LOAD_SLO_ERROR_RATE", 0), "SLO: max error rate (0..1) of every config/mode/concurrency; a miss fails the run (0 = off)")
+		sloMinRPS          = flag.Float64("slo-min-rps", envOrFloat("LS_LOAD_SLO_MIN_RPS", 0), "SLO: min RPS of every config/mode/concurrency; a miss fails the run (0 = off)")
+		freshnessStr       = flag.String("freshness", envOr("LS_LOAD_FRESHNESS", ""), "Poll every liteserver's masterchain head at this interval for the whole run (e.g. 250ms; empty = off)")
+		verifyCount        = flag.Int("verify-count", envOrInt("LS_LOAD_VERIFY_COUNT", 50), "Requests per kind (blocks, accounts, get-methods) compared across configs in verify mode")
+		connectRate        = flag.Float64("connect-rate", envOrFloat("LS_LOAD_CONNECT_RATE", 0), "New connections per second per liteserver in connect mode (0 = closed loop at each concurrency level)")
+		connectCount       = flag.Int("connect-count", envOrInt("LS_LOAD_CONNECT_COUNT", 100), "Connections per liteserver and step in connect mode when --duration is not set")
+		connectStorm       = flag.Int("connect-storm", envOrInt("LS_LOAD_CONNECT_STORM", 0), "Clients reconnecting at once in a simulated reconnect storm per config (0 = off)")
+		mockServer         = flag.Bool("mock", envOrBool("LS_LOAD_MOCK", false), "Run against a built-in mock liteserver instead of --configs (send and connect modes only)")
+		methodsDiscover    = flag.Int("methods-discover", envOrInt("LS_LOAD_METHODS_DISCOVER", 200), "Warmed-up accounts to probe for wallet/jetton get-methods when --methods is not set")
+		agentsStr          = flag.String("agents", envOr("LS_LOAD_AGENTS", ""), "Coordinator mode: comma-separated agent addresses (host:port) that run the scenario in sync; their results are merged")
+		agentToken         = flag.String("agent-token", envOr("LS_LOAD_AGENT_TOKEN", ""), "Bearer token sent to the agents (their --token)")
+		barrierURL         = flag.String("barrier", "", "Start barrier URL to wait at before every cell (set by the agent for the runs it starts)")
+		histograms         = flag.Bool("histograms", envOrBool("LS_LOAD_HISTOGRAMS", false), "Store latency histograms (overall and per second) in results so runs can be merged")
 		outDir             = flag.String("out", envOr("LS_LOAD_OUT", "results"), "Output directory")
 		timeoutStr         = flag.String("timeout", envOr("LS_LOAD_TIMEOUT", "10s"), "Per-request timeout")
 		durationStr        = flag.String("duration", envOr("LS_LOAD_DURATION", ""), "Test duration per scenario (e.g. 10s). Empty = fixed dataset run")
 		reportFrom         = flag.String("report-from", envOr("LS_LOAD_REPORT_FROM", ""), "Regenerate report.html from existing results dir (reads summary.json and requests.jsonl)")
 		reportMaxPts       = flag.Int("report-max-points", envOrInt("LS_LOAD_REPORT_MAX_POINTS", 240), "Max points per series in HTML report (downsample; 0 = no downsample)")
-		reqLogStr          = flag.String("request-log", envOr("LS_LOAD_REQUEST_LOG", "auto"), "Per-request JSONL log path (use 'auto' to write in results dir, 'off' to disable)")
+		reqLogStr          = flag.String("request-log", envOr("LS_LOAD_REQUEST_LOG", "auto"), "Per-request log path (use 'auto' to write in results dir, 'off' to disable; .bin paths are binary, .gz/.zst paths are compressed)")
+		traceTarget        = flag.String("trace", envOr("LS_LOAD_TRACE", ""), "Export a trace per job as OTLP/JSON: an OTLP/HTTP collector URL (e.g. http://localhost:4318) or a file path")
+		traceSlowest       = flag.Float64("trace-slowest", envOrFloat("LS_LOAD_TRACE_SLOWEST", 100), "Tail sampling: keep the traces of the slowest N% of each cell's jobs (100 = all)")
+		traceErrors        = flag.Bool("trace-errors", envOrBool("LS_LOAD_TRACE_ERRORS", true), "Keep the trace of every failing job, whatever --trace-slowest says")
+		errorSamplesN      = flag.Int("error-samples", envOrInt("LS_LOAD_ERROR_SAMPLES", 5), "Keep the first N failed requests of each config and error class in full, with their params (0 = off)")
+		exportStr          = flag.String("export", envOr("LS_LOAD_EXPORT", ""), "Comma-separated exports of the per-second series: influx[=PATH], openmetrics[=PATH] (no path = results dir)")
+		reqLogFormat       = flag.String("request-log-format", envOr("LS_LOAD_REQUEST_LOG_FORMAT", "jsonl"), "Format of the auto request log: jsonl|binary (compact columns for very high RPS)")
+		reqLogCompress     = flag.String("request-log-compress", envOr("LS_LOAD_REQUEST_LOG_COMPRESS", "none"), "Compression of the auto request log: none|gzip|zstd")
 		retries            = flag.Int("retries", envOrInt("LS_LOAD_RETRIES", 0), "LiteServer retry attempts (0 = auto) ")
 		maxConns           = flag.Int("max-connections", envOrInt("LS_LOAD_MAX_CONNECTIONS", 0), "Max connections to liteservers (0 = auto)")
 		workers            = flag.Int("workers-per-conn", envOrInt("LS_LOAD_WORKERS_PER_CONN", 0), "Workers per connection (0 = default)")
 		poolStr            = flag.String("pool-strategy", envOr("LS_LOAD_POOL_STRATEGY", ""), "Pool strategy: best-ping|first-working")
 		proofStr           = flag.String("proof", envOr("LS_LOAD_PROOF", "fast"), "proof check policy: unsafe|fast|secure")
 	)
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		runServe(os.Args[2:])
+		return 0
+	}
 	flag.Parse()
 
+	exports, err := parseExports(*exportStr)
+	if err != nil {
+		exitf("invalid export: %v", err)
+	}
+
+	if *sloP99 < 0 || *sloErrorRate < 0 || *sloErrorRate > 1 || *sloMinRPS < 0 {
+		exitf("invalid SLO: --slo-p99 and --slo-min-rps must be >= 0, --slo-error-rate within 0..1")
+	}
+	slo := sloLimits{P99Ms: *sloP99, ErrorRate: *sloErrorRate, MinRPS: *sloMinRPS}
+
 	if strings.TrimSpace(*reportFrom) != "" {
-		if err := regenerateReport(*reportFrom, *reqLogStr, *reportMaxPts); err != nil {
+		checks, err := regenerateReport(*reportFrom, *reqLogStr, *reportMaxPts, exports, slo)
+		if err != nil {
 			exitf("failed to regenerate report: %v", err)
 		}
-		return
+		printChecks(os.Stdout, checks)
+		if checksFailed(checks) {
+			return 1
+		}
+		return 0
+	}
+
+	modes, err := parseModes(*modeStr)
+	if err != nil || len(modes) == 0 {
+		exitf("invalid mode: %s", *modeStr)
+	}
+
+	if _, ok := requestLogCompressions[strings.ToLower(*reqLogCompress)]; !ok {
+		exitf("invalid request-log-compress: %s", *reqLogCompress)
+	}
+	if _, ok := requestLogFormats[strings.ToLower(*reqLogFormat)]; !ok {
+		exitf("invalid request-log-format: %s", *reqLogFormat)
+	}
+
+	env := &runEnv{histograms: *histograms}
+	if strings.TrimSpace(*barrierURL) != "" {
+		env.barrier = &barrierClient{url: strings.TrimSpace(*barrierURL), token: os.Getenv("LS_LOAD_AGENT_TOKEN")}
+	}
+	agentAddrs := parseAgents(*agentsStr)
+	if len(agentAddrs) > 0 {
+		switch {
+		case modes[ModeVerify]:
+			exitf("--agents cannot be combined with verify mode")
+		case strings.TrimSpace(*soakIntervalStr) != "":
+			exitf("--agents cannot be combined with --soak-interval")
+		case strings.TrimSpace(*resumeDir) != "":
+			exitf("--agents cannot be combined with --resume")
+		}
 	}
 
 	concurrencyLevels, err := parseIntList(*concurrency)
@@ -91,9 +221,18 @@ func main() {
 		exitf("invalid concurrency list: %s", *concurrency)
 	}
 
-	configs, err := resolveConfigPaths(*configsStr)
-	if err != nil || len(configs) == 0 {
-		exitf("no configs found: %s", *configsStr)
+	var configs []configItem
+	if *mockServer {
+		for m := range modes {
+			if m != ModeSend && m != ModeConnect {
+				exitf("--mock supports only --mode send,connect")
+			}
+		}
+	} else {
+		configs, err = resolveConfigPaths(*configsStr)
+		if err != nil || len(configs) == 0 {
+			exitf("no configs found: %s", *configsStr)
+		}
 	}
 
 	proofPolicy, err := parseProofPolicy(*proofStr)
@@ -115,6 +254,14 @@ func main() {
 	if err != nil {
 		exitf("invalid step-duration: %s", *stepDurStr)
 	}
+	soakInterval, err := parseDurationOptional(*soakIntervalStr)
+	if err != nil {
+		exitf("invalid soak-interval: %s", *soakIntervalStr)
+	}
+	freshnessInterval, err := parseDurationOptional(*freshnessStr)
+	if err != nil {
+		exitf("invalid freshness interval: %s", *freshnessStr)
+	}
 
 	var blocksRefresh time.Duration
 	if *blocksRand {
@@ -143,14 +290,61 @@ func main() {
 		}
 	}
 
-	rng := newLockedRand()
+	if strings.TrimSpace(*profileStr) != "" {
+		env.shape, err = parseProfile(*profileStr)
+		if err != nil {
+			exitf("invalid profile: %v", err)
+		}
+		if duration == 0 {
+			exitf("--profile requires --duration or --step-duration")
+		}
+		env.rateShape = *profileRate
+	} else if *profileRate > 0 {
+		exitf("--profile-rate requires --profile")
+	}
+	if soakInterval > 0 {
+		if duration == 0 {
+			exitf("--soak-interval requires --duration or --step-duration")
+		}
+		if env.shape != nil {
+			exitf("--soak-interval cannot be combined with --profile")
+		}
+		if *soakDrift <= 0 {
+			exitf("invalid soak-drift: %v", *soakDrift)
+		}
+	}
+
+	var sessionSteps []sessionStep
+	if modes[ModeSession] {
+		think, err := parseThinkTime(*thinkStr)
+		if err != nil {
+			exitf("invalid think: %v", err)
+		}
+		sessionSteps, err = parseSessionScript(*sessionStr, think)
+		if err != nil {
+			exitf("invalid session: %v", err)
+		}
+	}
+
+	seed := *seedFlag
+	if seed == 0 && strings.TrimSpace(*resumeDir) != "" {
+		// a resumed run keeps drawing from the seed it started with
+		if prev, err := readResultsLog(filepath.Join(strings.TrimSpace(*resumeDir), resultsLogName)); err == nil && len(prev) > 0 {
+			seed = prev[0].Seed
+		}
+	}
+	if seed == 0 {
+		seed = randomSeed()
+	}
+	fmt.Printf("Seed: %d\n", seed)
+	rng := newLockedRand(seed)
 
 	var accounts []ton.AccountID
 	var accountsBase []ton.AccountID
 	var accountsFromFile bool
 	if *accountsFile == "" {
 		if !*accountsWarm {
-			accountsBase, err = generateRandomAccounts(*accountsN)
+			accountsBase, err = generateRandomAccounts(*accountsN, rng)
 			if err != nil {
 				exitf("failed to generate accounts: %v", err)
 			}
@@ -175,10 +369,102 @@ func main() {
 		exitf("invalid block range: %v", err)
 	}
 
-	stamp := time.Now().Format("20060102-150405")
-	outRoot := filepath.Join(*outDir, stamp)
-	if err := os.MkdirAll(outRoot, 0o755); err != nil {
-		exitf("failed to create output dir: %v", err)
+	var methodCalls []methodCall
+	if modes[ModeRunMethod] && strings.TrimSpace(*methodsFile) != "" {
+		methodCalls, err = loadMethodCalls(*methodsFile)
+		if err != nil {
+			exitf("failed to load methods: %v", err)
+		}
+		if len(methodCalls) == 0 {
+			exitf("no get-methods loaded from %s", *methodsFile)
+		}
+	}
+
+	configParams := defaultConfigParams
+	if strings.TrimSpace(*configParamsStr) != "" {
+		configParams, err = parseUint32List(*configParamsStr)
+		if err != nil {
+			exitf("invalid config-params: %s", *configParamsStr)
+		}
+	}
+
+	var ageBuckets []ageBucket
+	if strings.TrimSpace(*ageBucketsStr) != "" {
+		ageBuckets, err = parseAgeBuckets(*ageBucketsStr)
+		if err != nil || len(ageBuckets) == 0 {
+			exitf("invalid age-buckets: %s", *ageBucketsStr)
+		}
+	}
+
+	resuming := strings.TrimSpace(*resumeDir) != ""
+	var outRoot string
+	if resuming {
+		outRoot = strings.TrimSpace(*resumeDir)
+		if info, err := os.Stat(outRoot); err != nil || !info.IsDir() {
+			exitf("resume dir not found: %s", outRoot)
+		}
+	} else {
+		stamp := time.Now().Format("20060102-150405")
+		outRoot = filepath.Join(*outDir, stamp)
+		if err := os.MkdirAll(outRoot, 0o755); err != nil {
+			exitf("failed to create output dir: %v", err)
+		}
+	}
+	fmt.Printf("Results: %s\n", outRoot)
+
+	// verify compares answers held in memory across configs, so on resume it always runs again
+	store, allResults, err := openResultStore(filepath.Join(outRoot, resultsLogName), resuming, func(r Result) bool {
+		return !modes[ModeVerify] || r.Mode != string(ModeVerify)
+	})
+	if err != nil {
+		exitf("failed to open results log: %v", err)
+	}
+	defer store.Close()
+	if resuming {
+		fmt.Printf("Resuming %s: %d finished results\n", outRoot, len(allResults))
+	}
+
+	if soakInterval > 0 {
+		soakPath := filepath.Join(outRoot, "soak.jsonl")
+		env.soak, err = newSoakCheckpoints(soakPath, soakInterval, *soakDrift, rng)
+		if err != nil {
+			exitf("failed to open soak checkpoints: %v", err)
+		}
+		defer env.soak.Close()
+		fmt.Printf("Soak checkpoints every %s: %s\n", soakInterval, soakPath)
+	}
+	// soak runs are too long to keep every response size
+	env.payloads = newPayloadRecorder(env.soak != nil, rng.stream())
+
+	// with --agents every agent starts its own mock server
+	if *mockServer && len(agentAddrs) == 0 {
+		srv, err := startMockServer()
+		if err != nil {
+			exitf("failed to start mock server: %v", err)
+		}
+		defer srv.Close()
+		mockPath := filepath.Join(outRoot, "mock-config.json")
+		if err := srv.writeConfig(mockPath); err != nil {
+			exitf("failed to write mock config: %v", err)
+		}
+		fmt.Printf("Mock liteserver: %s\n", srv.Addr())
+		configs = []configItem{{Path: mockPath, Name: "mock"}}
+	}
+
+	var tracker *freshnessTracker
+	if freshnessInterval > 0 {
+		tracker = newFreshnessTracker(freshnessInterval, timeout)
+		for _, cfgItem := range configs {
+			cfg, err := config.ParseConfigFile(cfgItem.Path)
+			if err != nil {
+				continue
+			}
+			if ts, err := connectTargets(cfg); err == nil {
+				tracker.add(cfgItem.Name, ts)
+			}
+		}
+		tracker.Start()
+		fmt.Printf("Freshness monitor: %d liteservers every %s\n", len(tracker.servers), freshnessInterval)
 	}
 
 	var logger *reqLogger
@@ -188,15 +474,39 @@ func main() {
 		switch strings.ToLower(logPath) {
 		case "off", "none", "false", "0":
 			logPath = ""
+		case "auto":
+			// the report aggregates the whole log in memory, too much for a soak run
+			if env.soak != nil {
+				fmt.Printf("Per-request log: off in soak mode (set --request-log to a path to keep it)\n")
+				logPath = ""
+			}
+			// requests go to the liteservers from the agents, which keep no request log
+			if len(agentAddrs) > 0 {
+				logPath = ""
+			}
 		}
 		if logPath == "" {
 			// logging disabled
 		} else {
 			if strings.EqualFold(logPath, "auto") {
-				logPath = filepath.Join(outRoot, "requests.jsonl")
+				logPath = filepath.Join(outRoot, requestLogName(strings.ToLower(*reqLogFormat), strings.ToLower(*reqLogCompress)))
 			}
 			var err error
-			logger, err = newReqLogger(logPath)
+			if resuming {
+				// the entries of the finished cells are in the log the run started with
+				if rec := resolveRequestLog(outRoot, allResults); rec != "" && rec != logPath {
+					fmt.Printf("Request log: continuing %s of the resumed run\n", rec)
+					logPath = rec
+				}
+				dropped, err := pruneRequestLog(logPath, store)
+				if err != nil {
+					exitf("failed to prune request log: %v", err)
+				}
+				if dropped > 0 {
+					fmt.Printf("Request log: dropped %d entries of unfinished cells\n", dropped)
+				}
+			}
+			logger, err = newReqLogger(logPath, resuming)
 			if err != nil {
 				exitf("failed to init request log: %v", err)
 			}
@@ -205,10 +515,74 @@ func main() {
 		}
 	}
 
-	var allResults []Result
+	// with --agents the requests go from the agents, which do not trace
+	if target := strings.TrimSpace(*traceTarget); target != "" && len(agentAddrs) == 0 {
+		if *traceSlowest <= 0 || *traceSlowest > 100 {
+			exitf("invalid trace-slowest: %v (want 0 < N <= 100)", *traceSlowest)
+		}
+		resource := map[string]string{"ls_load.run": filepath.Base(outRoot), "ls_load.seed": strconv.FormatInt(seed, 10)}
+		env.tracer, err = newTraceExporter(target, resuming, *traceSlowest, *traceErrors, resource)
+		if err != nil {
+			exitf("failed to init tracing: %v", err)
+		}
+		fmt.Printf("Traces: %s", target)
+		if *traceSlowest < 100 {
+			fmt.Printf(" (slowest %g%% of jobs per cell", *traceSlowest)
+			if *traceErrors {
+				fmt.Printf(" and every failing job")
+			}
+			fmt.Printf(")")
+		}
+		fmt.Println()
+	}
+
+	// as with traces, the agents keep their own samples
+	if *errorSamplesN > 0 && len(agentAddrs) == 0 {
+		env.samples = newErrorSampler(*errorSamplesN)
+		if resuming {
+			if err := env.samples.load(filepath.Join(outRoot, "error_samples.json"), store); err != nil {
+				exitf("failed to read error samples: %v", err)
+			}
+		}
+	}
+
 	var methodData map[methodKey]methodSeries
 	var errorSummary []errorSummaryEntry
 	var errorSeriesData map[errorSeriesKey]errorSeries
+	var verify *verifyPlan
+	verifyAnswersByConfig := map[string]*verifyAnswers{}
+	var configOrder []string
+
+	if len(agentAddrs) > 0 {
+		req, err := newAgentRunRequest(configs, *accountsFile, *methodsFile)
+		if err != nil {
+			exitf("failed to prepare agent run: %v", err)
+		}
+		fmt.Printf("\n== Agents: %s ==\n", strings.Join(agentAddrs, ", "))
+		perAgent := runAgents(agentAddrs, *agentToken, req, seed)
+		for i, rs := range perAgent {
+			if rs == nil {
+				continue
+			}
+			dir := filepath.Join(outRoot, "agents", strconv.Itoa(i+1))
+			if err := os.MkdirAll(dir, 0o755); err == nil {
+				err = writeJSON(filepath.Join(dir, "summary.json"), rs)
+			}
+			if err != nil {
+				fmt.Printf("failed to write agent results: %v\n", err)
+			}
+		}
+		for _, res := range mergeAgentResults(perAgent, env.histograms) {
+			res.Seed = seed
+			allResults = append(allResults, res)
+			printResult(res)
+			if err := store.add(res); err != nil {
+				fmt.Printf("failed to persist result: %v\n", err)
+			}
+		}
+		// the scenario ran on the agents
+		configs = nil
+	}
 
 	for _, cfgItem := range configs {
 		cfgName := cfgItem.Name
@@ -263,81 +637,266 @@ func main() {
 
 		_ = *retries
 
+		collect := func(res Result) {
+			res.Config = cfgName
+			res.Seed = seed
+			res.Targets = targets
+			res.LogDropped = logger.droppedIn(cfgName, res.Mode, res.Concurrency)
+			res.RequestLog = recordRequestLog(outRoot, reqLogPath)
+			applyPayloads(&res, env.payloads.take(cfgName, res.Mode, res.Concurrency))
+			allResults = append(allResults, res)
+			printResult(res)
+			if err := store.add(res); err != nil {
+				fmt.Printf("failed to persist result: %v\n", err)
+			}
+		}
+		// finished reports cells that completed before --resume, so they are not run again
+		finished := func(mode string, conc int) bool {
+			if store.has(cfgName, mode, conc) {
+				fmt.Printf("  mode=%s conc=%d finished earlier, skipping\n", mode, conc)
+				return true
+			}
+			return false
+		}
+
+		// connect dials every liteserver itself, so it runs before the shared client exists
+		if modes[ModeConnect] {
+			if ts, err2 := connectTargets(cfg); err2 != nil {
+				fmt.Printf("connect targets failed: %v\n", err2)
+			} else if len(ts) > 0 {
+				for _, t := range ts {
+					for _, conc := range concurrencyLevels {
+						if finished(connectModePrefix+t.Host, conc) {
+							continue
+						}
+						collect(runConnectTest(env, cfgName, targets, t, *connectRate, *connectCount, conc, timeout, duration, logger))
+					}
+				}
+				if *connectStorm > 0 && !finished(connectModePrefix+"storm", *connectStorm) {
+					collect(runConnectStorm(env, cfgName, targets, ts, *connectStorm, timeout, logger))
+				}
+			}
+			if len(modes) == 1 {
+				continue
+			}
+		}
+
+		clientStart := time.Now()
 		api, err := liteapi.NewClient(opts...)
 		if err != nil {
 			fmt.Printf("connection failed: %v\n", err)
 			continue
 		}
+		fmt.Printf("Client ready in %s\n", time.Since(clientStart).Round(time.Millisecond))
 
-		blockSeqs, err2 := buildBlockSeqs(api, br)
-		if err2 != nil {
-			fmt.Printf("block range build failed: %v\n", err2)
-		} else {
+		clients := sharedClientSet(api)
+		if *clientsN > 0 {
+			dialStart := time.Now()
+			cs, err := dialClientSet(opts, cfg.LiteServers, *clientsN)
+			if err != nil {
+				fmt.Printf("independent clients failed: %v\n", err)
+				continue
+			}
+			clients = cs
+			fmt.Printf("Independent clients: %d connected, %d refused in %s\n", len(cs.clients), *clientsN-len(cs.clients), time.Since(dialStart).Round(time.Millisecond))
+		}
+
+		if modes[ModeBlocks] {
+			if len(ageBuckets) > 0 {
+				for _, res := range runAgeBucketTests(env, clients, cfgName, targets, ageBuckets, blockRangeSize(br), concurrencyLevels, timeout, duration, logger, *blocksRand, blocksRefresh, rng, finished) {
+					collect(res)
+				}
+			} else if blockSeqs, err2 := buildBlockSeqs(api, br); err2 != nil {
+				fmt.Printf("block range build failed: %v\n", err2)
+			} else {
+				for _, conc := range concurrencyLevels {
+					if finished(string(ModeBlocks), conc) {
+						continue
+					}
+					res := runBlockTest(env, clients, cfgName, targets, string(ModeBlocks), blockSeqs, conc, timeout, duration, logger, *blocksRand, blocksRefresh, rng, br)
+					collect(res)
+				}
+			}
+		}
+
+		accounts = nil
+		if modes[ModeAccounts] || modes[ModeTransactions] || (modes[ModeRunMethod] && len(methodCalls) == 0) || (modes[ModeVerify] && verify == nil) || modes[ModeSession] {
+			accounts = accountsBase
+			if !accountsFromFile && *accountsWarm {
+				fmt.Printf("warming up accounts from recent blocks (target=%d, mc_blocks=%d)\n", *accountsN, *accountsWarmBlocks)
+				accounts, err = warmupAccounts(api, timeout, *accountsN, *accountsWarmBlocks, rng)
+				if err != nil {
+					fmt.Printf("warmup failed: %v\n", err)
+				}
+				if *accountsShuf && len(accounts) > 1 {
+					shuffleAccounts(accounts, rng)
+				}
+			}
+			if len(accounts) == 0 {
+				fmt.Printf("no accounts available for test\n")
+			}
+		}
+
+		if modes[ModeAccounts] && len(accounts) > 0 {
+			for _, conc := range concurrencyLevels {
+				if finished(string(ModeAccounts), conc) {
+					continue
+				}
+				res := runAccountTest(env, clients, cfgName, targets, accounts, conc, timeout, duration, logger, true, rng)
+				collect(res)
+			}
+		}
+
+		if modes[ModeSession] && len(accounts) > 0 {
 			for _, conc := range concurrencyLevels {
-				res := runBlockTest(api, cfgName, targets, blockSeqs, conc, timeout, duration, logger, *blocksRand, blocksRefresh, rng, br)
-				res.Config = cfgName
-				res.Targets = targets
-				allResults = append(allResults, res)
-				printResult(res)
+				if finished(string(ModeSession), conc) {
+					continue
+				}
+				res := runSessionTest(env, clients, cfgName, targets, accounts, sessionSteps, conc, timeout, duration, logger, rng)
+				collect(res)
+			}
+		}
+
+		if modes[ModeRunMethod] {
+			calls := methodCalls
+			if len(calls) == 0 && len(accounts) > 0 {
+				calls = discoverMethodCalls(api, accounts, timeout, *methodsDiscover)
+			}
+			if len(calls) == 0 {
+				fmt.Printf("no get-method targets available for test\n")
+			} else {
+				for _, conc := range concurrencyLevels {
+					if finished(string(ModeRunMethod), conc) {
+						continue
+					}
+					res := runMethodTest(env, clients, cfgName, targets, calls, conc, timeout, duration, logger, rng)
+					collect(res)
+				}
 			}
 		}
 
-		accounts = accountsBase
-		if !accountsFromFile && *accountsWarm {
-			fmt.Printf("warming up accounts from recent blocks (target=%d, mc_blocks=%d)\n", *accountsN, *accountsWarmBlocks)
-			accounts, err = warmupAccounts(api, timeout, *accountsN, *accountsWarmBlocks, rng)
+		if modes[ModeTransactions] {
+			if len(accounts) > 0 {
+				for _, conc := range concurrencyLevels {
+					if finished(string(ModeTransactions), conc) {
+						continue
+					}
+					res := runTransactionsTest(env, clients, cfgName, targets, accounts, *txPages, *txPageSize, conc, timeout, duration, logger, rng)
+					collect(res)
+				}
+			}
+			if blockSeqs, err2 := buildBlockSeqs(api, br); err2 != nil {
+				fmt.Printf("block range build failed: %v\n", err2)
+			} else {
+				for _, conc := range concurrencyLevels {
+					if finished(string(ModeTransactions)+":blocks", conc) {
+						continue
+					}
+					res := runBlockTransactionsTest(env, clients, cfgName, targets, blockSeqs, conc, timeout, duration, logger, *blocksRand, blocksRefresh, rng, br)
+					collect(res)
+				}
+			}
+		}
+
+		if modes[ModeConfig] {
+			raw, err := dialRawLiteClients(cfg.LiteServers, timeout)
 			if err != nil {
-				fmt.Printf("warmup failed: %v\n", err)
-				continue
+				fmt.Printf("config: %v\n", err)
+			} else if blockSeqs, err2 := buildBlockSeqs(api, br); err2 != nil {
+				fmt.Printf("block range build failed: %v\n", err2)
+			} else {
+				for _, conc := range concurrencyLevels {
+					if finished(string(ModeConfig), conc) {
+						continue
+					}
+					res := runConfigTest(env, clients, raw, cfgName, targets, blockSeqs, configParams, conc, timeout, duration, logger, *blocksRand, blocksRefresh, rng, br)
+					collect(res)
+				}
 			}
-			if *accountsShuf && len(accounts) > 1 {
-				shuffleAccounts(accounts, rng)
+		}
+
+		if modes[ModeProofs] {
+			if blockSeqs, err2 := buildBlockSeqs(api, br); err2 != nil {
+				fmt.Printf("block range build failed: %v\n", err2)
+			} else {
+				for _, conc := range concurrencyLevels {
+					if finished(string(ModeProofs), conc) {
+						continue
+					}
+					res := runProofsTest(env, clients, cfgName, targets, blockSeqs, *proofClients, conc, timeout, duration, logger, rng)
+					collect(res)
+				}
 			}
 		}
-		if len(accounts) == 0 {
-			fmt.Printf("no accounts available for test\n")
-			continue
+
+		if modes[ModeSend] {
+			dests, err2 := pickSendDestinations(api, sendDestinations, timeout, !*mockServer, rng)
+			if err2 != nil {
+				fmt.Printf("send destinations check failed, skipping send: %v\n", err2)
+			} else {
+				for _, conc := range concurrencyLevels {
+					if finished(string(ModeSend), conc) {
+						continue
+					}
+					res := runSendTest(env, clients, cfgName, targets, dests, *sendCount, conc, timeout, duration, logger, rng)
+					collect(res)
+				}
+			}
 		}
-		for _, conc := range concurrencyLevels {
-			res := runAccountTest(api, cfgName, targets, accounts, conc, timeout, duration, logger, true, rng)
-			res.Config = cfgName
-			res.Targets = targets
-			allResults = append(allResults, res)
-			printResult(res)
+
+		if modes[ModeVerify] {
+			if verify == nil {
+				blockSeqs, err2 := buildBlockSeqs(api, br)
+				if err2 != nil {
+					fmt.Printf("block range build failed: %v\n", err2)
+				}
+				calls := methodCalls
+				if len(calls) == 0 && len(accounts) > 0 {
+					calls = discoverMethodCalls(api, accounts, timeout, *methodsDiscover)
+				}
+				verify, err2 = buildVerifyPlan(api, blockSeqs, accounts, calls, *verifyCount, timeout)
+				if err2 != nil {
+					fmt.Printf("verify plan failed: %v\n", err2)
+				}
+			}
+			if verify != nil {
+				res, ans := runVerifyTest(env, clients, cfgName, targets, verify, concurrencyLevels[0], timeout, logger)
+				verifyAnswersByConfig[cfgName] = ans
+				configOrder = append(configOrder, cfgName)
+				collect(res)
+			}
 		}
 
 		// liteapi client has no explicit Close; connections will close on process exit
 	}
 
+	var freshness []freshnessSeries
+	if tracker != nil {
+		freshness = tracker.Stop()
+		printFreshness(freshness)
+	}
+
 	if len(allResults) == 0 {
 		exitf("no results collected")
 	}
+	applyVerify(allResults, verify, configOrder, verifyAnswersByConfig)
 
 	if logger != nil {
 		logger.Close()
 		logger = nil
 	}
+	env.tracer.Close()
 
 	if reqLogPath != "" {
 		if _, err := os.Stat(reqLogPath); err == nil {
-			md, err := buildMethodSeriesFromLog(reqLogPath)
+			agg, err := aggregateRequestLog(reqLogPath)
 			if err == nil {
-				methodData = md
+				methodData = agg.methodSeries()
+				errorSummary = agg.errorSummary()
+				errorSeriesData = agg.errorSeriesMap()
 			} else {
 				fmt.Printf("failed to parse request log: %v\n", err)
 			}
-			ed, err := buildErrorSummaryFromLog(reqLogPath)
-			if err == nil {
-				errorSummary = ed
-			} else {
-				fmt.Printf("failed to build error summary: %v\n", err)
-			}
-			es, err := buildErrorSeriesFromLog(reqLogPath)
-			if err == nil {
-				errorSeriesData = es
-			} else {
-				fmt.Printf("failed to build error series: %v\n", err)
-			}
 		}
 	}
 
@@ -349,6 +908,16 @@ func main() {
 		fmt.Printf("failed to write JSON: %v\n", err)
 	}
 
+	if err := writePayloadsCSV(filepath.Join(outRoot, "payloads.csv"), allResults); err != nil {
+		fmt.Printf("failed to write payloads CSV: %v\n", err)
+	}
+
+	if len(freshness) > 0 {
+		if err := writeJSON(filepath.Join(outRoot, "freshness.json"), freshness); err != nil {
+			fmt.Printf("failed to write freshness JSON: %v\n", err)
+		}
+	}
+
 	if len(errorSummary) > 0 {
 		if err := writeJSON(filepath.Join(outRoot, "errors.json"), errorSummary); err != nil {
 			fmt.Printf("failed to write errors JSON: %v\n", err)
@@ -358,11 +927,30 @@ func main() {
 		}
 	}
 
-	if err := writeHTMLReport(filepath.Join(outRoot, "report.html"), allResults, methodData, errorSummary, errorSeriesData, *reportMaxPts); err != nil {
+	samples := env.samples.list()
+	if len(samples) > 0 {
+		if err := writeJSON(filepath.Join(outRoot, "error_samples.json"), samples); err != nil {
+			fmt.Printf("failed to write error samples: %v\n", err)
+		}
+	}
+
+	writeExports(exports, outRoot, allResults, methodData, errorSeriesData)
+
+	checks := summaryChecks(allResults, slo)
+	if err := writeMarkdownSummary(filepath.Join(outRoot, "summary.md"), allResults, errorSummary, checks); err != nil {
+		fmt.Printf("failed to write Markdown summary: %v\n", err)
+	}
+
+	if err := writeHTMLReport(filepath.Join(outRoot, "report.html"), allResults, methodData, errorSummary, errorSeriesData, samples, freshness, *reportMaxPts); err != nil {
 		fmt.Printf("failed to write HTML report: %v\n", err)
 	}
 
+	printRunSummary(os.Stdout, allResults, errorSummary, checks)
 	fmt.Printf("\nReport written to: %s\n", filepath.Join(outRoot, "report.html"))
+	if checksFailed(checks) {
+		return 1
+	}
+	return 0
 }
 
 func exitf(format string, args ...interface{}) {
@@ -374,8 +962,14 @@ func printResult(r Result) {
 	if r.Targets != "" {
 		fmt.Printf("  mode=%s conc=%d ok=%d err=%d rps=%.2f p95=%.1fms targets=%s\n",
 			r.Mode, r.Concurrency, r.Success, r.Errors, r.RPS, r.P95Ms, r.Targets)
-		return
+	} else {
+		fmt.Printf("  mode=%s conc=%d ok=%d err=%d rps=%.2f p95=%.1fms\n",
+			r.Mode, r.Concurrency, r.Success, r.Errors, r.RPS, r.P95Ms)
+	}
+	for _, d := range r.SoakDrift {
+		fmt.Printf("    drift: %s\n", d)
+	}
+	if r.LogDropped > 0 {
+		fmt.Printf("    request log: %d entries dropped\n", r.LogDropped)
 	}
-	fmt.Printf("  mode=%s conc=%d ok=%d err=%d rps=%.2f p95=%.1fms\n",
-		r.Mode, r.Concurrency, r.Success, r.Errors, r.RPS, r.P95Ms)
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// cellHasher computes cell hashes at every level: merkle proofs commit to level-0 hashes, boc.Cell only
+// exposes the representation hash. It mirrors tongo's immutableCell.
+type cellHasher struct {
+	cache map[*boc.Cell]*cellLevels
+}
+
+type cellLevels struct {
+	mask   uint32
+	hashes [][]byte
+	depths []int
+}
+
+func newCellHasher() *cellHasher {
+	return &cellHasher{cache: map[*boc.Cell]*cellLevels{}}
+}
+
+func cellData(c *boc.Cell) ([]byte, error) {
+	bs := c.RawBitString()
+	return bs.GetTopUppedArray()
+}
+
+func maskApply(mask uint32, level int) uint32 {
+	return mask & ((1 << uint32(level)) - 1)
+}
+
+func (l *cellLevels) index(level int, pruned bool) (int, bool) {
+	idx := bits.OnesCount32(maskApply(l.mask, level))
+	if pruned && idx != bits.OnesCount32(l.mask) {
+		return idx, true
+	}
+	if pruned {
+		return 0, false
+	}
+	return idx, false
+}
+
+func (h *cellHasher) levels(c *boc.Cell) (*cellLevels, error) {
+	if l, ok := h.cache[c]; ok {
+		return l, nil
+	}
+	data, err := cellData(c)
+	if err != nil {
+		return nil, err
+	}
+	refs := c.Refs()
+	merkle := c.CellType() == boc.MerkleProofCell || c.CellType() == boc.MerkleUpdateCell
+	childLevels := make([]*cellLevels, len(refs))
+	l := &cellLevels{}
+	for i, ref := range refs {
+		cl, err := h.levels(ref)
+		if err != nil {
+			return nil, err
+		}
+		childLevels[i] = cl
+		if merkle {
+			l.mask |= cl.mask >> 1
+		} else {
+			l.mask |= cl.mask
+		}
+	}
+	switch c.CellType() {
+	case boc.PrunedBranchCell:
+		if len(data) < 2 {
+			return nil, fmt.Errorf("pruned branch too short")
+		}
+		l.mask = uint32(data[1])
+		// type, mask, then a hash and a depth per level below the branch: hashAt and depthAt read them
+		if n := bits.OnesCount32(l.mask); n == 0 || len(data) < 2+n*(32+2) {
+			return nil, fmt.Errorf("pruned branch of %d bytes for level mask %#x", len(data), l.mask)
+		}
+	case boc.LibraryCell:
+		l.mask = 0
+	}
+
+	exotic := 0
+	if c.IsExotic() {
+		exotic = 8
+	}
+	bitSize := c.BitSize()
+	d2 := byte((bitSize+7)/8 + bitSize/8)
+	offset := 0
+	if c.CellType() == boc.PrunedBranchCell {
+		offset = bits.OnesCount32(l.mask)
+	}
+	level := 32 - bits.LeadingZeros32(l.mask)
+	hashIndex := -1
+	for i := 0; i <= level; i++ {
+		if i > 0 && (l.mask>>(i-1))%2 == 0 {
+			continue
+		}
+		hashIndex++
+		if hashIndex < offset {
+			continue
+		}
+		d1 := byte(len(refs) + exotic + 32*int(maskApply(l.mask, i)))
+		x := sha256.New()
+		x.Write([]byte{d1, d2})
+		if hashIndex == offset {
+			x.Write(data)
+		} else {
+			x.Write(l.hashes[hashIndex-offset-1])
+		}
+		childLevel := i
+		if merkle {
+			childLevel = i + 1
+		}
+		depth := 0
+		for j, cl := range childLevels {
+			cd := cl.depthAt(refs[j], childLevel)
+			var repr [2]byte
+			binary.BigEndian.PutUint16(repr[:], uint16(cd))
+			x.Write(repr[:])
+			if cd > depth {
+				depth = cd
+			}
+		}
+		if len(refs) > 0 {
+			depth++
+		}
+		for j, cl := range childLevels {
+			x.Write(cl.hashAt(refs[j], childLevel))
+		}
+		l.hashes = append(l.hashes, x.Sum(nil))
+		l.depths = append(l.depths, depth)
+	}
+	h.cache[c] = l
+	return l, nil
+}
+
+// hashAt returns the hash for the given level; pruned branches answer lower levels from their stored hashes.
+func (l *cellLevels) hashAt(c *boc.Cell, level int) []byte {
+	pruned := c.CellType() == boc.PrunedBranchCell
+	idx, stored := l.index(level, pruned)
+	if stored {
+		data, _ := cellData(c)
+		return data[2+idx*32 : 2+(idx+1)*32]
+	}
+	return l.hashes[idx]
+}
+
+func (l *cellLevels) depthAt(c *boc.Cell, level int) int {
+	pruned := c.CellType() == boc.PrunedBranchCell
+	idx, stored := l.index(level, pruned)
+	if stored {
+		data, _ := cellData(c)
+		n := bits.OnesCount32(l.mask)
+		return int(binary.BigEndian.Uint16(data[2+n*32+idx*2:]))
+	}
+	return l.depths[idx]
+}
+
+// merkleProofRoot checks a serialized merkle proof and returns the hash of the tree it proves.
+func merkleProofRoot(raw []byte) (ton.Bits256, error) {
+	cells, err := boc.DeserializeBoc(raw)
+	if err != nil {
+		return ton.Bits256{}, err
+	}
+	if len(cells) != 1 {
+		return ton.Bits256{}, fmt.Errorf("merkle proof: expected 1 root, got %d", len(cells))
+	}
+	root := cells[0]
+	virtual, err := root.GetMerkleRoot()
+	if err != nil {
+		return ton.Bits256{}, err
+	}
+	root.ResetCounters()
+	refs := root.Refs()
+	if len(refs) != 1 {
+		return ton.Bits256{}, fmt.Errorf("merkle proof: expected 1 ref, got %d", len(refs))
+	}
+	l, err := newCellHasher().levels(refs[0])
+	if err != nil {
+		return ton.Bits256{}, err
+	}
+	var got ton.Bits256
+	copy(got[:], l.hashAt(refs[0], 0))
+	if got != ton.Bits256(virtual) {
+		return ton.Bits256{}, fmt.Errorf("merkle proof: virtual hash %x does not match tree hash %x", virtual, got)
+	}
+	return got, nil
+}
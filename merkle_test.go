@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/tonkeeper/tongo/boc"
+)
+
+// randomTree builds a tree of ordinary cells with random data, depth levels deep.
+func randomTree(t *testing.T, r *rand.Rand, depth int) *boc.Cell {
+	t.Helper()
+	c := boc.NewCell()
+	if err := c.WriteUint(uint64(r.Uint32()), 1+r.Intn(32)); err != nil {
+		t.Fatal(err)
+	}
+	if depth == 0 {
+		return c
+	}
+	for i := 0; i < 1+r.Intn(4); i++ {
+		if err := c.AddRef(randomTree(t, r, depth-1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+// proveRandomPath makes a tongo merkle proof of root that keeps one random path and prunes every other branch.
+func proveRandomPath(t *testing.T, r *rand.Rand, root *boc.Cell) []byte {
+	t.Helper()
+	prover, err := boc.NewMerkleProver(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	top := prover.Cursor()
+	cur, c := top, root
+	for c.RefsSize() > 0 {
+		keep := r.Intn(c.RefsSize())
+		for i := 0; i < c.RefsSize(); i++ {
+			if i != keep {
+				cur.Ref(i).Prune()
+			}
+		}
+		cur, c = cur.Ref(keep), c.Refs()[keep]
+	}
+	proof, err := prover.CreateProof(top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proof
+}
+
+func TestMerkleProofRootMatchesTongo(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		root := randomTree(t, r, 1+r.Intn(5))
+		want, err := root.Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		proof := proveRandomPath(t, r, root)
+		got, err := merkleProofRoot(proof)
+		if err != nil {
+			t.Fatalf("tree %d: %v", i, err)
+		}
+		if !bytes.Equal(got[:], want) {
+			t.Fatalf("tree %d: proof root %x, tongo hash %x", i, got, want)
+		}
+
+		// the representation hash of the proof cell itself, which has pruned branches below it
+		cells, err := boc.DeserializeBoc(proof)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l, err := newCellHasher().levels(cells[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		repr, err := cells[0].Hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(l.hashes[len(l.hashes)-1], repr) {
+			t.Fatalf("tree %d: proof cell hash %x, tongo hash %x", i, l.hashes[len(l.hashes)-1], repr)
+		}
+	}
+}
+
+func TestMerkleShortPrunedBranch(t *testing.T) {
+	pruned := boc.NewCellExotic(boc.PrunedBranchCell)
+	// type 1, level mask 1, and only half of the hash that level needs
+	if err := pruned.WriteBytes(append([]byte{1, 1}, make([]byte, 16)...)); err != nil {
+		t.Fatal(err)
+	}
+	root := boc.NewCell()
+	if err := root.AddRef(pruned); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newCellHasher().levels(root); err == nil {
+		t.Fatal("short pruned branch was accepted")
+	}
+}
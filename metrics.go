@@ -2,6 +2,7 @@ package main
 
 import (
 	"math"
+	"math/bits"
 	"sort"
 )
 
@@ -18,6 +19,13 @@ func applyMetrics(r *Result, durations []int64) {
 	}
 }
 
+// applyThroughput derives MB/s from the response bytes accumulated over the run.
+func applyThroughput(r *Result) {
+	if r.Duration > 0 && r.Bytes > 0 {
+		r.MBps = float64(r.Bytes) / 1e6 / r.Duration.Seconds()
+	}
+}
+
 func computeMetrics(durations []int64, successCount int) (avg, p50, p90, p95, p99, max float64) {
 	if successCount == 0 {
 		return 0, 0, 0, 0, 0, 0
@@ -89,3 +97,81 @@ func countsToFloat64(counts []int64) []float64 {
 	}
 	return out
 }
+
+// HistStats is the latency histograms of a result, kept with --histograms so results of several agents can be merged.
+type HistStats struct {
+	Hist       []histBin   `json:"hist,omitempty"`
+	SeriesHist [][]histBin `json:"series_hist,omitempty"`
+}
+
+// histBin is one non-empty bucket of a latency histogram: B is the bucket index, N the count.
+type histBin struct {
+	B int   `json:"b"`
+	N int64 `json:"n"`
+}
+
+// histBucket maps ms to a log-linear bucket: exact below 16ms, then 8 per power of two (at most 12.5% wide).
+func histBucket(ms int64) int {
+	if ms < 16 {
+		return int(max(ms, 0))
+	}
+	e := bits.Len64(uint64(ms)) - 1
+	return 16 + (e-4)*8 + int(ms>>(e-3))&7
+}
+
+// histValue is the lower bound of bucket b in ms.
+func histValue(b int) float64 {
+	if b < 16 {
+		return float64(b)
+	}
+	e, sub := (b-16)/8+4, (b-16)%8
+	return float64(int64(8+sub) << (e - 3))
+}
+
+func histogramOf(durations []int64) []histBin {
+	m := map[int]int64{}
+	for _, d := range durations {
+		if d >= 0 {
+			m[histBucket(d)]++
+		}
+	}
+	return histBins(m)
+}
+
+func addHist(dst map[int]int64, bins []histBin) {
+	for _, b := range bins {
+		dst[b.B] += b.N
+	}
+}
+
+func histBins(m map[int]int64) []histBin {
+	if len(m) == 0 {
+		return nil
+	}
+	out := make([]histBin, 0, len(m))
+	for b, n := range m {
+		out = append(out, histBin{B: b, N: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].B < out[j].B })
+	return out
+}
+
+// histPercentile is the nearest-rank percentile of sorted bins, like percentile on raw values.
+func histPercentile(bins []histBin, p float64) float64 {
+	var total int64
+	for _, b := range bins {
+		total += b.N
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(p / 100 * float64(total)))
+	var seen int64
+	for _, b := range bins {
+		seen += b.N
+		if seen >= rank {
+			return histValue(b.B)
+		}
+	}
+	return histValue(bins[len(bins)-1].B)
+}
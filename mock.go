@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteapi"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tl"
+)
+
+const (
+	// mockSendDelay is the simulated SendMessage processing time.
+	mockSendDelay = 2 * time.Millisecond
+	// mockMaxPending is how many SendMessage queries the mock processes at once before rejecting with back-pressure.
+	mockMaxPending = 64
+	mockMaxWait    = 5 * time.Second
+)
+
+// mockServer is a minimal liteserver answering getMasterchainInfo and sendMessage, for safe send runs.
+type mockServer struct {
+	key     ed25519.PrivateKey
+	ln      net.Listener
+	tip     liteclient.LiteServerMasterchainInfoC
+	pending int64
+}
+
+func startMockServer() (*mockServer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &mockServer{key: key, ln: ln}
+	s.tip.Last.Workchain = ^uint32(0)
+	s.tip.Last.Shard = masterchainShard
+	s.tip.Last.Seqno = 1
+	rand.Read(s.tip.Last.RootHash[:])
+	rand.Read(s.tip.Last.FileHash[:])
+	rand.Read(s.tip.StateRootHash[:])
+	go s.serve()
+	return s, nil
+}
+
+func (s *mockServer) Addr() *net.TCPAddr {
+	return s.ln.Addr().(*net.TCPAddr)
+}
+
+func (s *mockServer) Close() error {
+	return s.ln.Close()
+}
+
+// writeConfig writes a global config pointing at the mock so it can be used like any other --configs entry.
+func (s *mockServer) writeConfig(path string) error {
+	addr := s.Addr()
+	ip := binary.BigEndian.Uint32(addr.IP.To4())
+	cfg := map[string]any{
+		"liteservers": []map[string]any{{
+			"ip":   int64(ip),
+			"port": addr.Port,
+			"id": map[string]string{
+				"@type": "pub.ed25519",
+				"key":   base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey)),
+			},
+		}},
+	}
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
+
+func (s *mockServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *mockServer) handle(conn net.Conn) {
+	defer conn.Close()
+	c, err := s.handshake(conn)
+	if err != nil {
+		return
+	}
+	closed := make(chan struct{})
+	defer close(closed)
+	r := bufio.NewReader(conn)
+	for {
+		p, err := liteclient.ParsePacket(r, c.decrypt)
+		if err != nil {
+			return
+		}
+		switch p.MagicType() {
+		case tagTCPPing:
+			if len(p.Payload) == 12 {
+				pong := binary.LittleEndian.AppendUint32(nil, tagTCPPong)
+				c.send(append(pong, p.Payload[4:]...))
+			}
+		case tagADNLQuery:
+			go s.answer(c, p.Payload, closed)
+		}
+	}
+}
+
+// handshake is the server side of adnlHandshake.
+func (s *mockServer) handshake(conn net.Conn) (*adnlConn, error) {
+	req := make([]byte, 256)
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Time{})
+	id := adnlKeyID(s.key.Public().(ed25519.PublicKey))
+	if !bytes.Equal(req[:32], id[:]) {
+		return nil, errors.New("mock: handshake for unknown key")
+	}
+	shared, err := x25519Shared(s.key, req[32:64])
+	if err != nil {
+		return nil, err
+	}
+	hash := req[64:96]
+	hc, err := handshakeCipher(shared, hash)
+	if err != nil {
+		return nil, err
+	}
+	params := append([]byte{}, req[96:]...)
+	hc.XORKeyStream(params, params)
+	if h := sha256.Sum256(params); !bytes.Equal(h[:], hash) {
+		return nil, errors.New("mock: handshake checksum mismatch")
+	}
+	c := &adnlConn{conn: conn}
+	if c.encrypt, c.decrypt, err = sessionCiphers(params, false); err != nil {
+		return nil, err
+	}
+	return c, c.send(nil)
+}
+
+func (s *mockServer) answer(c *adnlConn, payload []byte, closed <-chan struct{}) {
+	if len(payload) < 36 {
+		return
+	}
+	queryID := payload[4:36]
+	var query []byte
+	if err := tl.Unmarshal(bytes.NewReader(payload[36:]), &query); err != nil {
+		return
+	}
+	resp := s.liteQuery(query, closed)
+	body, err := tl.Marshal(resp)
+	if err != nil {
+		return
+	}
+	out := binary.LittleEndian.AppendUint32(nil, tagADNLAnswer)
+	out = append(out, queryID...)
+	out = append(out, body...)
+	c.send(out)
+}
+
+func (s *mockServer) liteQuery(query []byte, closed <-chan struct{}) []byte {
+	if len(query) < 4 || binary.LittleEndian.Uint32(query) != tagLiteServerQuery {
+		return mockError(601, "mock: unknown query")
+	}
+	var data []byte
+	if err := tl.Unmarshal(bytes.NewReader(query[4:]), &data); err != nil || len(data) < 4 {
+		return mockError(601, "mock: unknown query")
+	}
+	if binary.LittleEndian.Uint32(data) == tagWaitMasterchainSeqno && len(data) >= 16 {
+		seqno := binary.LittleEndian.Uint32(data[4:])
+		if seqno > s.tip.Last.Seqno {
+			// the mock chain never advances: hold the waiter like a real node would, then time out
+			wait := time.Duration(binary.LittleEndian.Uint32(data[8:])) * time.Millisecond
+			if wait > mockMaxWait {
+				wait = mockMaxWait
+			}
+			select {
+			case <-time.After(wait):
+			case <-closed:
+			}
+			return mockError(652, "mock: timeout waiting for masterchain block")
+		}
+		data = data[12:]
+	}
+	switch binary.LittleEndian.Uint32(data) {
+	case tagGetMasterchainInfo:
+		b, _ := tl.Marshal(s.tip)
+		return append(binary.LittleEndian.AppendUint32(nil, tagLiteServerMasterchainInfo), b...)
+	case tagSendMessage:
+		return s.sendMessage(data[4:])
+	default:
+		return mockError(601, "mock: unknown query")
+	}
+}
+
+func (s *mockServer) sendMessage(req []byte) []byte {
+	if n := atomic.AddInt64(&s.pending, 1); n > mockMaxPending {
+		atomic.AddInt64(&s.pending, -1)
+		return mockError(503, fmt.Sprintf("mock: too many pending messages (%d)", mockMaxPending))
+	}
+	defer atomic.AddInt64(&s.pending, -1)
+	time.Sleep(mockSendDelay)
+	var body []byte
+	if err := tl.Unmarshal(bytes.NewReader(req), &body); err != nil {
+		return mockError(400, "mock: bad sendMessage request")
+	}
+	if err := liteapi.VerifySendMessagePayload(body); err != nil {
+		return mockError(400, "mock: invalid external message: "+err.Error())
+	}
+	b, _ := tl.Marshal(liteclient.LiteServerSendMsgStatusC{Status: 1})
+	return append(binary.LittleEndian.AppendUint32(nil, tagLiteServerSendMsgStatus), b...)
+}
+
+func mockError(code uint32, msg string) []byte {
+	b, _ := tl.Marshal(liteclient.LiteServerErrorC{Code: code, Message: msg})
+	return append(binary.LittleEndian.AppendUint32(nil, tagLiteServerError), b...)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// payloadSlot is the time resolution of recorded response bytes; slots are re-bucketed to result seconds.
+const payloadSlot = 100 * time.Millisecond
+
+// PayloadStats is the response size distribution of a result, overall and per method, and its bandwidth series.
+type PayloadStats struct {
+	PayloadAvgBytes float64       `json:"payload_avg_bytes,omitempty"`
+	PayloadP50Bytes float64       `json:"payload_p50_bytes,omitempty"`
+	PayloadP95Bytes float64       `json:"payload_p95_bytes,omitempty"`
+	PayloadP99Bytes float64       `json:"payload_p99_bytes,omitempty"`
+	Payloads        []payloadStat `json:"payloads,omitempty"`
+	SeriesMBps      []float64     `json:"series_mbps,omitempty"`
+}
+
+type payloadKey struct {
+	Config      string
+	Mode        string
+	Concurrency int
+}
+
+type payloadStat struct {
+	Method   string  `json:"method"`
+	Count    int     `json:"count"`
+	Bytes    int64   `json:"bytes"`
+	AvgBytes float64 `json:"avg_bytes"`
+	P50Bytes float64 `json:"p50_bytes"`
+	P95Bytes float64 `json:"p95_bytes"`
+	P99Bytes float64 `json:"p99_bytes"`
+	MBps     float64 `json:"mb_per_sec"`
+}
+
+type payloadSeries struct {
+	sizes  map[string][]int64
+	counts map[string]int
+	bytes  map[string]int64
+	slots  map[int64]int64
+}
+
+// payloadRecorder aggregates response sizes per cell from logRequestExit, so the request log can be off.
+type payloadRecorder struct {
+	mu        sync.Mutex
+	data      map[payloadKey]*payloadSeries
+	reservoir bool
+	rnd       *jobRand
+}
+
+func newPayloadRecorder(reservoir bool, rnd *jobRand) *payloadRecorder {
+	return &payloadRecorder{data: map[payloadKey]*payloadSeries{}, reservoir: reservoir, rnd: rnd}
+}
+
+func (p *payloadRecorder) add(cfg, mode string, conc int, method string, start time.Time, size int) {
+	key := payloadKey{Config: cfg, Mode: mode, Concurrency: conc}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.data[key]
+	if s == nil {
+		s = &payloadSeries{sizes: map[string][]int64{}, counts: map[string]int{}, bytes: map[string]int64{}, slots: map[int64]int64{}}
+		p.data[key] = s
+	}
+	if p.reservoir {
+		s.sizes[method] = reservoirAdd(s.sizes[method], int64(s.counts[method]), int64(size), p.rnd)
+	} else {
+		s.sizes[method] = append(s.sizes[method], int64(size))
+	}
+	s.counts[method]++
+	s.bytes[method] += int64(size)
+	s.slots[start.UnixMilli()/payloadSlot.Milliseconds()] += int64(size)
+}
+
+// take removes and returns what was recorded for one result.
+func (p *payloadRecorder) take(cfg, mode string, conc int) *payloadSeries {
+	key := payloadKey{Config: cfg, Mode: mode, Concurrency: conc}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s := p.data[key]
+	delete(p.data, key)
+	return s
+}
+
+func sizeStats(sizes []int64) (avg, p50, p95, p99 float64) {
+	vals := append([]int64(nil), sizes...)
+	sort.Slice(vals, func(i, j int) bool { return vals[i] < vals[j] })
+	var sum int64
+	for _, v := range vals {
+		sum += v
+	}
+	return float64(sum) / float64(len(vals)), percentile(vals, 50), percentile(vals, 95), percentile(vals, 99)
+}
+
+// applyPayloads fills the payload fields of r; results that count their own bytes keep them if none were recorded.
+func applyPayloads(r *Result, s *payloadSeries) {
+	if s == nil || len(s.sizes) == 0 {
+		applyThroughput(r)
+		return
+	}
+	var all []int64
+	methods := make([]string, 0, len(s.sizes))
+	for m, sizes := range s.sizes {
+		methods = append(methods, m)
+		all = append(all, sizes...)
+	}
+	sort.Strings(methods)
+	r.Bytes = 0
+	r.Payloads = r.Payloads[:0]
+	for _, m := range methods {
+		sizes := s.sizes[m]
+		st := payloadStat{Method: m, Count: s.counts[m], Bytes: s.bytes[m]}
+		st.AvgBytes, st.P50Bytes, st.P95Bytes, st.P99Bytes = sizeStats(sizes)
+		if r.Duration > 0 {
+			st.MBps = float64(st.Bytes) / 1e6 / r.Duration.Seconds()
+		}
+		r.Bytes += st.Bytes
+		r.Payloads = append(r.Payloads, st)
+	}
+	r.PayloadAvgBytes, r.PayloadP50Bytes, r.PayloadP95Bytes, r.PayloadP99Bytes = sizeStats(all)
+	applyThroughput(r)
+
+	if len(r.SeriesSec) == 0 || r.SeriesStart == 0 {
+		return
+	}
+	perSec := make([]int64, len(r.SeriesSec))
+	for slot, b := range s.slots {
+		sec := (slot*payloadSlot.Milliseconds() - r.SeriesStart) / 1000
+		if sec >= 0 && sec < int64(len(perSec)) {
+			perSec[sec] += b
+		}
+	}
+	r.SeriesMBps = make([]float64, len(perSec))
+	for i, b := range perSec {
+		r.SeriesMBps[i] = float64(b) / 1e6
+	}
+}
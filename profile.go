@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// profileIdle is how long a worker parked by the profile sleeps before checking the level again.
+const profileIdle = 20 * time.Millisecond
+
+// ProfileStats is the load profile a timed result followed and its per-second target level.
+type ProfileStats struct {
+	SeriesTarget []float64 `json:"series_target,omitempty"`
+	Profile      string    `json:"profile,omitempty"`
+	ProfileUnit  string    `json:"profile_unit,omitempty"`
+}
+
+// loadProfile scales the step's level (workers, or rate with --profile-rate) over one timed run.
+type loadProfile struct {
+	Spec string
+	Peak float64
+	mult func(elapsed, duration time.Duration) float64
+}
+
+// parseProfile reads ramp[:DUR], spike:Mx@AT+LEN, sawtooth:PERIOD or stepdown:K.
+func parseProfile(spec string) (*loadProfile, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	kind, arg, _ := strings.Cut(spec, ":")
+	p := &loadProfile{Spec: spec, Peak: 1}
+	switch kind {
+	case "ramp":
+		var over time.Duration
+		if arg != "" {
+			d, err := time.ParseDuration(arg)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid ramp duration %q", arg)
+			}
+			over = d
+		}
+		p.mult = func(e, d time.Duration) float64 {
+			if over > 0 {
+				d = over
+			}
+			return math.Min(1, e.Seconds()/d.Seconds())
+		}
+	case "spike":
+		factor, window, ok := strings.Cut(arg, "x@")
+		atStr, lenStr, ok2 := strings.Cut(window, "+")
+		m, err := strconv.ParseFloat(factor, 64)
+		at, err2 := time.ParseDuration(atStr)
+		length, err3 := time.ParseDuration(lenStr)
+		if !ok || !ok2 || err != nil || err2 != nil || err3 != nil || m <= 0 || length <= 0 {
+			return nil, fmt.Errorf("invalid spike %q, expected spike:Mx@AT+LEN (e.g. spike:4x@30s+10s)", arg)
+		}
+		p.Peak = math.Max(1, m)
+		p.mult = func(e, _ time.Duration) float64 {
+			if e >= at && e < at+length {
+				return m
+			}
+			return 1
+		}
+	case "sawtooth":
+		period, err := time.ParseDuration(arg)
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("invalid sawtooth period %q", arg)
+		}
+		p.mult = func(e, _ time.Duration) float64 {
+			return float64(e%period) / float64(period)
+		}
+	case "stepdown":
+		k, err := strconv.Atoi(arg)
+		if err != nil || k < 2 {
+			return nil, fmt.Errorf("invalid stepdown steps %q, expected at least 2", arg)
+		}
+		p.mult = func(e, d time.Duration) float64 {
+			step := int(float64(e) / (float64(d) / float64(k)))
+			if step >= k {
+				step = k - 1
+			}
+			return float64(k-step) / float64(k)
+		}
+	default:
+		return nil, fmt.Errorf("unknown profile %q", kind)
+	}
+	return p, nil
+}
+
+// level is the profile's target at elapsed for a step with the given base level; at least 1.
+func (p *loadProfile) level(base float64, elapsed, duration time.Duration) float64 {
+	return math.Max(1, base*p.mult(elapsed, duration))
+}
+
+// targetSeries samples the target level in the middle of every second, for plotting against RPS.
+func (p *loadProfile) targetSeries(base float64, duration time.Duration) []float64 {
+	n := int(math.Ceil(duration.Seconds()))
+	if n < 1 {
+		n = 1
+	}
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = p.level(base, time.Duration(i)*time.Second+time.Second/2, duration)
+	}
+	return out
+}
+
+// runShapedJobs starts workers for the peak and parks those above the profile's current level.
+func runShapedJobs(itemCount, conc int, duration time.Duration, p *loadProfile, fn func(i int) error) jobRun {
+	if itemCount <= 0 {
+		return jobRun{result: Result{Errors: 1}}
+	}
+	if conc <= 0 {
+		conc = 1
+	}
+	var idx uint64
+	start := time.Now()
+	deadline := start.Add(duration)
+	rec := newJobRecorder(start, duration)
+	workers := int(math.Ceil(float64(conc) * p.Peak))
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for {
+				now := time.Now()
+				if now.After(deadline) {
+					return
+				}
+				if float64(w) >= math.Round(p.level(float64(conc), now.Sub(start), duration)) {
+					time.Sleep(profileIdle)
+					continue
+				}
+				i := int(atomic.AddUint64(&idx, 1)-1) % itemCount
+				t0 := time.Now()
+				err := fn(i)
+				rec.record(time.Since(t0).Milliseconds(), err)
+			}
+		}(w)
+	}
+	wg.Wait()
+	jr := rec.jobRun()
+	jr.seriesTarget = p.targetSeries(float64(conc), duration)
+	return jr
+}
+
+// runShapedRate is runPacedJobs with the rate following the profile.
+func runShapedRate(itemCount int, rate float64, maxInFlight int, duration time.Duration, p *loadProfile, fn func(i int) error) jobRun {
+	if itemCount <= 0 {
+		return jobRun{result: Result{Errors: 1}}
+	}
+	jr := runRateJobs(func(e time.Duration) float64 { return p.level(rate, e, duration) }, 0, maxInFlight, duration, func(i int) error {
+		return fn(i % itemCount)
+	})
+	jr.seriesTarget = p.targetSeries(rate, duration)
+	return jr
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseProfile(t *testing.T) {
+	for _, spec := range []string{
+		"", "flat", "ramp:x", "ramp:0s", "ramp:-5s",
+		"spike", "spike:4x", "spike:4x@30s", "spike:0x@30s+10s", "spike:-2x@30s+10s", "spike:4x@30s+0s", "spike:4x@30s+-1s",
+		"sawtooth", "sawtooth:0s", "sawtooth:-1m",
+		"stepdown", "stepdown:1", "stepdown:0", "stepdown:-3", "stepdown:x",
+	} {
+		if _, err := parseProfile(spec); err == nil {
+			t.Errorf("parseProfile(%q) accepted", spec)
+		}
+	}
+
+	const d = 100 * time.Second
+	tests := []struct {
+		spec    string
+		peak    float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{"ramp", 1, 0, 0},
+		{"ramp", 1, 25 * time.Second, 0.25},
+		{"RAMP:10s", 1, 5 * time.Second, 0.5},
+		{"ramp:10s", 1, 50 * time.Second, 1},
+		{"spike:4x@30s+10s", 4, 29 * time.Second, 1},
+		{"spike:4x@30s+10s", 4, 30 * time.Second, 4},
+		{"spike:4x@30s+10s", 4, 40 * time.Second, 1},
+		// a dip below the base is a spike too, but the peak stays at the base
+		{"spike:0.5x@0s+10s", 1, time.Second, 0.5},
+		{"sawtooth:20s", 1, 0, 0},
+		{"sawtooth:20s", 1, 25 * time.Second, 0.25},
+		{"stepdown:4", 1, 0, 1},
+		{"stepdown:4", 1, 50 * time.Second, 0.5},
+		{"stepdown:4", 1, d, 0.25},
+	}
+	for _, tt := range tests {
+		p, err := parseProfile(tt.spec)
+		if err != nil {
+			t.Fatalf("parseProfile(%q): %v", tt.spec, err)
+		}
+		if p.Peak != tt.peak {
+			t.Errorf("%s: peak %v, want %v", tt.spec, p.Peak, tt.peak)
+		}
+		if got := p.mult(tt.elapsed, d); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s at %s: %v, want %v", tt.spec, tt.elapsed, got, tt.want)
+		}
+	}
+}
+
+func TestProfileLevel(t *testing.T) {
+	p, err := parseProfile("sawtooth:10s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const d = 30 * time.Second
+	// the bottom of the tooth and a zero or negative base still run one worker
+	for _, tt := range []struct {
+		base    float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{100, 0, 1},
+		{100, 5 * time.Second, 50},
+		{0, 5 * time.Second, 1},
+		{-8, 5 * time.Second, 1},
+	} {
+		if got := p.level(tt.base, tt.elapsed, d); got != tt.want {
+			t.Errorf("level(%v, %s) = %v, want %v", tt.base, tt.elapsed, got, tt.want)
+		}
+	}
+
+	series := p.targetSeries(100, 2500*time.Millisecond)
+	if len(series) != 3 || series[0] != 5 || series[2] != 25 {
+		t.Errorf("targetSeries = %v", series)
+	}
+	if got := p.targetSeries(100, 0); len(got) != 1 {
+		t.Errorf("targetSeries of an empty step = %v", got)
+	}
+}
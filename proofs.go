@@ -0,0 +1,589 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteapi"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	// maxProofCalls bounds one sync walk in case a server keeps returning incomplete chains.
+	maxProofCalls = 64
+	signatureSize = 64
+
+	tagPubEd25519 = 0x4813b4c6 // pub.ed25519, hashed with the key into a validator's short node id
+	tagBlockID    = 0xc50b6e70 // ton.blockId root_hash file_hash, the message validators sign
+)
+
+// ProofStats counts the proofs a proofs-mode result checked and the latency of single proof hops.
+type ProofStats struct {
+	ProofCalls    int     `json:"proof_calls,omitempty"`
+	ProofLinks    int     `json:"proof_links,omitempty"`
+	ProofFailures int     `json:"proof_failures,omitempty"`
+	HopP50Ms      float64 `json:"hop_p50_ms,omitempty"`
+	HopP95Ms      float64 `json:"hop_p95_ms,omitempty"`
+	HopP99Ms      float64 `json:"hop_p99_ms,omitempty"`
+}
+
+func proofErrorf(format string, args ...any) error {
+	return fmt.Errorf("proof verification failed: "+format, args...)
+}
+
+// keyBlockFor is the key block a light client would trust for seqno: the block itself or its previous key block.
+func keyBlockFor(ctx context.Context, api *liteapi.Client, seqno uint32) (ton.BlockIDExt, error) {
+	id, info, err := api.LookupBlock(ctx, ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: seqno}, 1, nil, nil)
+	if err != nil {
+		return ton.BlockIDExt{}, err
+	}
+	if info.KeyBlock {
+		return id, nil
+	}
+	id, _, err = api.LookupBlock(ctx, ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: info.PrevKeyBlockSeqno}, 1, nil, nil)
+	return id, err
+}
+
+// checkMerkleRoot verifies a merkle proof and that it proves the given block.
+func checkMerkleRoot(name string, raw []byte, block ton.BlockIDExt) error {
+	root, err := merkleProofRoot(raw)
+	if err != nil {
+		return proofErrorf("%s: %v", name, err)
+	}
+	if root != block.RootHash {
+		return proofErrorf("%s: proves %x, expected block %d root %x", name, root, block.Seqno, block.RootHash)
+	}
+	return nil
+}
+
+// verifyBlockLink checks one GetBlockProof step from from: back links through the prev_blocks of from's state,
+// forward links by signatures of over 2/3 of the validator weight in from's config.
+func verifyBlockLink(link liteclient.LiteServerBlockLink, from ton.BlockIDExt) (ton.BlockIDExt, error) {
+	switch link.SumType {
+	case "LiteServerBlockLinkBack":
+		l := link.LiteServerBlockLinkBack
+		linkFrom, to := l.From.ToBlockIdExt(), l.To.ToBlockIdExt()
+		if linkFrom != from {
+			return to, proofErrorf("back link starts at %d, expected %d", linkFrom.Seqno, from.Seqno)
+		}
+		if to.Seqno >= linkFrom.Seqno {
+			return to, proofErrorf("back link goes forward %d->%d", linkFrom.Seqno, to.Seqno)
+		}
+		if len(l.Proof) == 0 || len(l.StateProof) == 0 {
+			return to, proofErrorf("back link %d->%d has no proof", linkFrom.Seqno, to.Seqno)
+		}
+		if err := checkMerkleRoot("back proof", l.Proof, linkFrom); err != nil {
+			return to, err
+		}
+		if len(l.DestProof) > 0 {
+			if err := checkMerkleRoot("back dest proof", l.DestProof, to); err != nil {
+				return to, err
+			}
+		}
+		if err := checkPrevBlock(l.Proof, l.StateProof, to); err != nil {
+			return to, err
+		}
+		return to, nil
+	case "LiteServerBlockLinkForward":
+		l := link.LiteServerBlockLinkForward
+		linkFrom, to := l.From.ToBlockIdExt(), l.To.ToBlockIdExt()
+		if linkFrom != from {
+			return to, proofErrorf("forward link starts at %d, expected %d", linkFrom.Seqno, from.Seqno)
+		}
+		if to.Seqno <= linkFrom.Seqno {
+			return to, proofErrorf("forward link goes back %d->%d", linkFrom.Seqno, to.Seqno)
+		}
+		if len(l.ConfigProof) == 0 {
+			return to, proofErrorf("forward link %d->%d has no config proof", linkFrom.Seqno, to.Seqno)
+		}
+		if err := checkMerkleRoot("config proof", l.ConfigProof, linkFrom); err != nil {
+			return to, err
+		}
+		if err := checkMerkleRoot("forward dest proof", l.DestProof, to); err != nil {
+			return to, err
+		}
+		nodes, err := masterValidators(l.ConfigProof)
+		if err != nil {
+			return to, proofErrorf("config proof of %d: %v", linkFrom.Seqno, err)
+		}
+		header, err := proofBlockHeader(l.DestProof)
+		if err != nil {
+			return to, proofErrorf("forward dest proof: %v", err)
+		}
+		if l.Signatures.CatchainSeqno != header.GenCatchainSeqno || l.Signatures.ValidatorSetHash != header.GenValidatorListHashShort {
+			return to, proofErrorf("signatures of %d are for catchain %d set %08x, block has %d set %08x", to.Seqno,
+				l.Signatures.CatchainSeqno, l.Signatures.ValidatorSetHash, header.GenCatchainSeqno, header.GenValidatorListHashShort)
+		}
+		if err := checkSignatures(l.Signatures.Signatures, nodes, to); err != nil {
+			return to, err
+		}
+		return to, nil
+	default:
+		return from, proofErrorf("unknown link type %q", link.SumType)
+	}
+}
+
+// validatorNode is a signer of masterchain blocks, by its short node id.
+type validatorNode struct {
+	pubkey ed25519.PublicKey
+	weight uint64
+}
+
+// validatorNodeID is the short node id of a validator key: sha256 of its TL pub.ed25519 form.
+func validatorNodeID(pubkey []byte) [32]byte {
+	b := binary.LittleEndian.AppendUint32(nil, tagPubEd25519)
+	return sha256.Sum256(append(b, pubkey...))
+}
+
+// checkSignatures checks that distinct known validators holding more than 2/3 of the weight signed block.
+func checkSignatures(sigs []liteclient.LiteServerSignatureC, nodes map[[32]byte]validatorNode, block ton.BlockIDExt) error {
+	if len(sigs) == 0 {
+		return proofErrorf("block %d is not signed", block.Seqno)
+	}
+	var total, signed uint64
+	for _, n := range nodes {
+		total += n.weight
+	}
+	msg := binary.LittleEndian.AppendUint32(nil, tagBlockID)
+	msg = append(append(msg, block.RootHash[:]...), block.FileHash[:]...)
+	seen := make(map[[32]byte]struct{}, len(sigs))
+	for _, s := range sigs {
+		if len(s.Signature) != signatureSize {
+			return proofErrorf("signature of %x has %d bytes", s.NodeIdShort, len(s.Signature))
+		}
+		if _, dup := seen[s.NodeIdShort]; dup {
+			return proofErrorf("duplicate signature of %x", s.NodeIdShort)
+		}
+		seen[s.NodeIdShort] = struct{}{}
+		n, ok := nodes[s.NodeIdShort]
+		if !ok {
+			return proofErrorf("signature of %x, not a validator of block %d", s.NodeIdShort, block.Seqno)
+		}
+		if !ed25519.Verify(n.pubkey, msg, s.Signature) {
+			return proofErrorf("bad signature of %x on block %d", s.NodeIdShort, block.Seqno)
+		}
+		signed += n.weight
+	}
+	if signed*3 <= total*2 {
+		return proofErrorf("block %d signed by weight %d of %d", block.Seqno, signed, total)
+	}
+	return nil
+}
+
+// proofRoot returns the cell a merkle proof proves.
+func proofRoot(raw []byte) (*boc.Cell, error) {
+	cells, err := boc.DeserializeBoc(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(cells) != 1 {
+		return nil, boc.ErrNotSingleRoot
+	}
+	if cells[0].CellType() != boc.MerkleProofCell || cells[0].RefsSize() != 1 {
+		return nil, fmt.Errorf("not a merkle proof")
+	}
+	return cells[0].Refs()[0], nil
+}
+
+// proofBlockRef returns ref i of the block a merkle proof proves: 0 info, 1 value flow, 2 state update, 3 extra.
+func proofBlockRef(raw []byte, i int) (*boc.Cell, error) {
+	block, err := proofRoot(raw)
+	if err != nil {
+		return nil, err
+	}
+	if block.RefsSize() != 4 {
+		return nil, fmt.Errorf("block cell has %d refs", block.RefsSize())
+	}
+	ref := block.Refs()[i]
+	if ref.CellType() == boc.PrunedBranchCell {
+		return nil, fmt.Errorf("block ref %d is pruned", i)
+	}
+	ref.ResetCounters()
+	return ref, nil
+}
+
+func proofBlockInfo(raw []byte) (tlb.BlockInfo, error) {
+	var info tlb.BlockInfo
+	c, err := proofBlockRef(raw, 0)
+	if err != nil {
+		return info, err
+	}
+	err = tlb.Unmarshal(c, &info)
+	return info, err
+}
+
+func proofBlockHeader(raw []byte) (tlb.BlockInfoPart, error) {
+	info, err := proofBlockInfo(raw)
+	return info.BlockInfoPart, err
+}
+
+// masterValidators is the masterchain signers in a key block's config proof: the main entries of param 34.
+func masterValidators(configProof []byte) (map[[32]byte]validatorNode, error) {
+	c, err := proofBlockRef(configProof, 3)
+	if err != nil {
+		return nil, err
+	}
+	var extra tlb.BlockExtra
+	if err := tlb.Unmarshal(c, &extra); err != nil {
+		return nil, err
+	}
+	if !extra.Custom.Exists {
+		return nil, fmt.Errorf("not a masterchain block")
+	}
+	param, ok := extra.Custom.Value.Value.Config.Config.Get(34)
+	if !ok {
+		return nil, fmt.Errorf("no config param 34")
+	}
+	var p34 tlb.ConfigParam34
+	if err := tlb.Unmarshal(&param.Value, &p34); err != nil {
+		return nil, fmt.Errorf("config param 34: %v", err)
+	}
+	var main int
+	var items []tlb.HashmapItem[tlb.Uint16, tlb.ValidatorDescr]
+	switch vs := p34.CurValidators; vs.SumType {
+	case "Validators":
+		main, items = int(min(vs.Validators.Main, vs.Validators.Total)), vs.Validators.List.Items()
+	case "ValidatorsExt":
+		main, items = int(min(vs.ValidatorsExt.Main, vs.ValidatorsExt.Total)), vs.ValidatorsExt.List.Items()
+	default:
+		return nil, fmt.Errorf("unknown validator set %q", vs.SumType)
+	}
+	nodes := make(map[[32]byte]validatorNode, main)
+	for _, it := range items {
+		if int(it.Key) >= main {
+			continue
+		}
+		key := it.Value.PubKey()
+		v := validatorNode{pubkey: ed25519.PublicKey(key[:])}
+		if it.Value.SumType == "Validator" {
+			v.weight = it.Value.Validator.Weight
+		} else {
+			v.weight = it.Value.ValidatorAddr.Weight
+		}
+		nodes[validatorNodeID(v.pubkey)] = v
+	}
+	if len(nodes) != main {
+		return nil, fmt.Errorf("%d of %d masterchain validators in the proof", len(nodes), main)
+	}
+	return nodes, nil
+}
+
+// checkPrevBlock checks that block is in the prev_blocks of the state after a block proof, as a back link claims.
+func checkPrevBlock(blockProof, stateProof []byte, block ton.BlockIDExt) error {
+	update, err := proofBlockRef(blockProof, 2)
+	if err != nil {
+		return proofErrorf("back proof: %v", err)
+	}
+	if update.CellType() != boc.MerkleUpdateCell {
+		return proofErrorf("back proof: state update is not a merkle update")
+	}
+	// merkle_update#04 from_hash:bits256 to_hash:bits256
+	if err := update.Skip(8 + 256); err != nil {
+		return proofErrorf("back proof: %v", err)
+	}
+	toHash, err := update.ReadBytes(32)
+	if err != nil {
+		return proofErrorf("back proof: %v", err)
+	}
+	stateHash, err := merkleProofRoot(stateProof)
+	if err != nil {
+		return proofErrorf("back state proof: %v", err)
+	}
+	if !bytes.Equal(stateHash[:], toHash) {
+		return proofErrorf("back state proof proves %x, block state is %x", stateHash, toHash)
+	}
+	c, err := proofRoot(stateProof)
+	if err != nil {
+		return proofErrorf("back state proof: %v", err)
+	}
+	var state tlb.ShardStateUnsplit
+	if err := tlb.Unmarshal(c, &state); err != nil {
+		return proofErrorf("back state proof: %v", err)
+	}
+	if !state.ShardStateUnsplit.Custom.Exists {
+		return proofErrorf("back state proof: not a masterchain state")
+	}
+	prev := state.ShardStateUnsplit.Custom.Value.Value.Other.PrevBlocks
+	for i, k := range prev.Keys() {
+		if uint32(k) != block.Seqno {
+			continue
+		}
+		ref := prev.Values()[i].BlkRef
+		if ref.SeqNo != block.Seqno || ton.Bits256(ref.RootHash) != block.RootHash || ton.Bits256(ref.FileHash) != block.FileHash {
+			return proofErrorf("prev_blocks has %d:%x, link claims %x", ref.SeqNo, ref.RootHash, block.RootHash)
+		}
+		return nil
+	}
+	return proofErrorf("block %d is not in the proven prev_blocks", block.Seqno)
+}
+
+// verifyShardProof walks a GetShardBlockProof chain from the shard hashes of its masterchain block down to block.
+func verifyShardProof(proof liteclient.LiteServerShardBlockProofC, block ton.BlockIDExt) error {
+	cur := proof.MasterchainId.ToBlockIdExt()
+	if cur.Workchain != -1 {
+		return proofErrorf("shard proof starts at workchain %d", cur.Workchain)
+	}
+	for i, link := range proof.Links {
+		next := link.Id.ToBlockIdExt()
+		if err := checkMerkleRoot(fmt.Sprintf("shard link %d", i), link.Proof, cur); err != nil {
+			return err
+		}
+		var err error
+		if i == 0 {
+			err = checkShardTop(link.Proof, next)
+		} else {
+			err = checkShardPrev(link.Proof, next)
+		}
+		if err != nil {
+			return proofErrorf("shard link %d: %v", i, err)
+		}
+		cur = next
+	}
+	if cur != block {
+		return proofErrorf("shard proof ends at %d:%x, expected %d:%x", cur.Seqno, cur.Shard, block.Seqno, block.Shard)
+	}
+	return nil
+}
+
+// checkShardTop checks that a masterchain block proof has block as the top of its shard in the shard hashes.
+func checkShardTop(raw []byte, block ton.BlockIDExt) error {
+	extra, err := proofBlockRef(raw, 3)
+	if err != nil {
+		return err
+	}
+	// block_extra#4a33f6fd in_msg_descr:^ out_msg_descr:^ account_blocks:^ rand_seed:bits256 created_by:bits256
+	// custom:(Maybe ^McBlockExtra)
+	if err := extra.Skip(32 + 256 + 256); err != nil {
+		return err
+	}
+	if ok, err := extra.ReadBit(); err != nil || !ok || extra.RefsSize() < 4 {
+		return fmt.Errorf("not a masterchain block")
+	}
+	mc := extra.Refs()[3]
+	mc.ResetCounters()
+	// masterchain_block_extra#cca5 key_block:Bool shard_hashes:ShardHashes
+	if err := mc.Skip(16 + 1); err != nil {
+		return err
+	}
+	var hashes tlb.HashmapE[tlb.Uint32, tlb.Ref[boc.Cell]]
+	if err := tlb.Unmarshal(mc, &hashes); err != nil {
+		return fmt.Errorf("shard hashes: %v", err)
+	}
+	tree, ok := hashes.Get(tlb.Uint32(block.Workchain))
+	if !ok || tree.Value.BitsAvailableForRead() == 0 {
+		return fmt.Errorf("workchain %d is not in the shard hashes", block.Workchain)
+	}
+	// bt_leaf$0 leaf:ShardDescr | bt_fork$1 left:^BinTree right:^BinTree, descending by the shard prefix
+	c := &tree.Value
+	c.ResetCounters()
+	for depth := 0; ; depth++ {
+		fork, err := c.ReadBit()
+		if err != nil {
+			return err
+		}
+		if !fork {
+			if block.Shard&-block.Shard != 1<<(63-depth) {
+				return fmt.Errorf("shard %x is not a leaf of the shard hashes", block.Shard)
+			}
+			break
+		}
+		if depth == 63 || c.RefsSize() != 2 {
+			return fmt.Errorf("bad shard tree fork")
+		}
+		c = c.Refs()[block.Shard>>(63-depth)&1]
+		if c.CellType() == boc.PrunedBranchCell {
+			return fmt.Errorf("shard %x is pruned from the shard hashes", block.Shard)
+		}
+		c.ResetCounters()
+	}
+	var desc tlb.ShardDesc
+	if err := tlb.Unmarshal(c, &desc); err != nil {
+		return fmt.Errorf("shard descr: %v", err)
+	}
+	top := ton.ToBlockId(desc, block.Workchain)
+	top.Shard = block.Shard
+	if top != block {
+		return fmt.Errorf("shard top is %d:%x, link claims %d:%x", top.Seqno, top.RootHash, block.Seqno, block.RootHash)
+	}
+	return nil
+}
+
+// checkShardPrev checks that block is a prev block of the shard block a proof proves.
+func checkShardPrev(raw []byte, block ton.BlockIDExt) error {
+	info, err := proofBlockInfo(raw)
+	if err != nil {
+		return err
+	}
+	parents, err := ton.GetParents(info)
+	if err != nil {
+		return err
+	}
+	for _, p := range parents {
+		if p == block {
+			return nil
+		}
+	}
+	return fmt.Errorf("%d:%x is not a prev block", block.Seqno, block.RootHash)
+}
+
+// runProofsTest simulates light clients syncing from the key block before a random seqno to the tip, then
+// proving a shard block of the tip. Job latency is the whole sync; hop stats are per GetBlockProof call.
+func runProofsTest(env *runEnv, clients *clientSet, cfgName, targets string, seqs []int32, lightClients int, conc int, timeout time.Duration, duration time.Duration, logger *reqLogger, rng *lockedRand) Result {
+	mode := string(ModeProofs)
+	fmt.Printf("%s: concurrency=%d, clients=%d\n", mode, conc, lightClients)
+	start := time.Now()
+	var mu sync.Mutex
+	var hops []int64
+	var links, failures int64
+	work := clients.wrap(func(api *liteapi.Client, i int) (err error) {
+		span := env.job(cfgName, targets, mode, conc)
+		defer span.end(&err)
+		r := rng.stream()
+		seq := seqs[r.Intn(len(seqs))]
+		span.set("ton.block.seqno", seq)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		t0 := time.Now()
+		known, err := keyBlockFor(ctx, api, uint32(seq))
+		cancel()
+		env.logRequest(logger, span, cfgName, targets, mode, conc, "LookupBlock", t0, 0, err)
+		if err != nil {
+			return err
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+		t1 := time.Now()
+		info, err := api.GetMasterchainInfo(ctx)
+		cancel()
+		env.logRequest(logger, span, cfgName, targets, mode, conc, "GetMasterchainInfo", t1, 0, err)
+		if err != nil {
+			return err
+		}
+		target := info.Last.ToBlockIdExt()
+
+		cur := known
+		for call := 0; cur != target; call++ {
+			if call == maxProofCalls {
+				atomic.AddInt64(&failures, 1)
+				return proofErrorf("no tip after %d GetBlockProof calls", maxProofCalls)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			t2 := time.Now()
+			proof, err := api.GetBlockProofRaw(ctx, cur, &target)
+			cancel()
+			hopMs := time.Since(t2).Milliseconds()
+			respBytes := 0
+			for _, s := range proof.Steps {
+				respBytes += len(s.LiteServerBlockLinkBack.Proof) + len(s.LiteServerBlockLinkBack.DestProof) + len(s.LiteServerBlockLinkBack.StateProof)
+				respBytes += len(s.LiteServerBlockLinkForward.ConfigProof) + len(s.LiteServerBlockLinkForward.DestProof)
+			}
+			env.logRequest(logger, span, cfgName, targets, mode, conc, "GetBlockProof", t2, respBytes, err)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			hops = append(hops, hopMs)
+			mu.Unlock()
+			if from := proof.From.ToBlockIdExt(); from != cur {
+				atomic.AddInt64(&failures, 1)
+				return proofErrorf("chain starts at %d, expected %d", from.Seqno, cur.Seqno)
+			}
+			if len(proof.Steps) == 0 && !proof.Complete {
+				atomic.AddInt64(&failures, 1)
+				return proofErrorf("empty incomplete chain from %d", cur.Seqno)
+			}
+			for _, step := range proof.Steps {
+				next, err := verifyBlockLink(step, cur)
+				if err != nil {
+					atomic.AddInt64(&failures, 1)
+					return err
+				}
+				cur = next
+				atomic.AddInt64(&links, 1)
+			}
+			if to := proof.To.ToBlockIdExt(); to != cur {
+				atomic.AddInt64(&failures, 1)
+				return proofErrorf("chain ends at %d, links reach %d", to.Seqno, cur.Seqno)
+			}
+			if proof.Complete && cur != target {
+				atomic.AddInt64(&failures, 1)
+				return proofErrorf("complete chain stops at %d, tip is %d", cur.Seqno, target.Seqno)
+			}
+		}
+
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+		t3 := time.Now()
+		shards, err := api.GetAllShardsInfo(ctx, target)
+		cancel()
+		env.logRequest(logger, span, cfgName, targets, mode, conc, "GetAllShardsInfo", t3, 0, err)
+		if err != nil {
+			return err
+		}
+		if len(shards) == 0 {
+			return nil
+		}
+		shard := shards[r.Intn(len(shards))]
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+		t4 := time.Now()
+		sp, err := api.WithBlock(shard).GetShardBlockProof(ctx)
+		cancel()
+		respBytes := 0
+		for _, l := range sp.Links {
+			respBytes += len(l.Proof)
+		}
+		env.logRequest(logger, span, cfgName, targets, mode, conc, "GetShardBlockProof", t4, respBytes, err)
+		if err != nil {
+			return err
+		}
+		if err := verifyShardProof(sp, shard); err != nil {
+			atomic.AddInt64(&failures, 1)
+			return err
+		}
+		// the server proves from the masterchain block that took the shard block in, which can precede the tip
+		mc := sp.MasterchainId.ToBlockIdExt()
+		if mc == target {
+			return nil
+		}
+		if mc.Seqno > target.Seqno {
+			atomic.AddInt64(&failures, 1)
+			return proofErrorf("shard proof from %d, past the tip %d", mc.Seqno, target.Seqno)
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+		t5 := time.Now()
+		back, err := api.GetBlockProofRaw(ctx, target, &mc)
+		cancel()
+		env.logRequest(logger, span, cfgName, targets, mode, conc, "GetBlockProof", t5, 0, err)
+		if err != nil {
+			return err
+		}
+		cur = target
+		for _, step := range back.Steps {
+			if cur, err = verifyBlockLink(step, cur); err != nil {
+				atomic.AddInt64(&failures, 1)
+				return err
+			}
+		}
+		if cur != mc {
+			atomic.AddInt64(&failures, 1)
+			return proofErrorf("back chain from the tip reaches %d, shard proof starts at %d", cur.Seqno, mc.Seqno)
+		}
+		return nil
+	})
+
+	jr := env.runWorkload(cfgName, mode, lightClients, conc, duration, work)
+	res := env.finishResult(jr, mode, conc, lightClients, duration, start)
+	res.ProofLinks = int(links)
+	res.ProofFailures = int(failures)
+	res.ProofCalls = len(hops)
+	_, res.HopP50Ms, _, res.HopP95Ms, res.HopP99Ms, _ = computeMetrics(hops, len(hops))
+	clients.apply(&res)
+	return res
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"math/rand"
+	"testing"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+func TestCheckSignatures(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	var block ton.BlockIDExt
+	block.Seqno = 100
+	r.Read(block.RootHash[:])
+	r.Read(block.FileHash[:])
+	msg := binary.LittleEndian.AppendUint32(nil, tagBlockID)
+	msg = append(append(msg, block.RootHash[:]...), block.FileHash[:]...)
+
+	// four validators of weight 10: three of them are more than 2/3, two are not
+	nodes := map[[32]byte]validatorNode{}
+	var sigs []liteclient.LiteServerSignatureC
+	for i := 0; i < 4; i++ {
+		pub, priv, err := ed25519.GenerateKey(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := validatorNodeID(pub)
+		nodes[id] = validatorNode{pubkey: pub, weight: 10}
+		sigs = append(sigs, liteclient.LiteServerSignatureC{NodeIdShort: id, Signature: ed25519.Sign(priv, msg)})
+	}
+	if err := checkSignatures(sigs[:3], nodes, block); err != nil {
+		t.Fatalf("3 of 4 signatures: %v", err)
+	}
+	if err := checkSignatures(sigs[:2], nodes, block); err == nil {
+		t.Fatal("2 of 4 signatures were accepted")
+	}
+	if err := checkSignatures(append(sigs[:2:2], sigs[0]), nodes, block); err == nil {
+		t.Fatal("a duplicate signature was accepted")
+	}
+
+	forged := append([]liteclient.LiteServerSignatureC(nil), sigs[:3]...)
+	forged[0].Signature = append([]byte(nil), forged[0].Signature...)
+	forged[0].Signature[0] ^= 1
+	if err := checkSignatures(forged, nodes, block); err == nil {
+		t.Fatal("a forged signature was accepted")
+	}
+
+	other := block
+	other.FileHash[0] ^= 1
+	if err := checkSignatures(sigs[:3], nodes, other); err == nil {
+		t.Fatal("signatures of another block were accepted")
+	}
+}
+
+// shardLeaf is a bt_leaf of the shard hashes with the descr of one shard top block.
+func shardLeaf(t *testing.T, seqno uint32, root, file byte) *boc.Cell {
+	t.Helper()
+	var desc tlb.ShardDesc
+	desc.SumType = "New"
+	desc.New.SeqNo = seqno
+	desc.New.RootHash[0] = root
+	desc.New.FileHash[0] = file
+	c := boc.NewCell()
+	if err := c.WriteBit(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := tlb.Marshal(c, desc); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// masterBlockProof builds a masterchain block whose workchain 0 has shards 0x4000... and 0xc000..., and
+// proves it with the branch of the left shard pruned, the way a liteserver proves one shard top.
+func masterBlockProof(t *testing.T) ([]byte, ton.BlockIDExt) {
+	t.Helper()
+	must := func(err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	fork := boc.NewCell()
+	must(fork.WriteBit(true))
+	must(fork.AddRef(shardLeaf(t, 10, 1, 2)))
+	must(fork.AddRef(shardLeaf(t, 20, 3, 4)))
+
+	var hashes tlb.HashmapE[tlb.Uint32, tlb.Ref[boc.Cell]]
+	hashes.Put(0, tlb.Ref[boc.Cell]{Value: *fork})
+	mc := boc.NewCell()
+	must(mc.WriteUint(0xcca5, 16))
+	must(mc.WriteBit(false))
+	must(tlb.Marshal(mc, hashes))
+
+	extra := boc.NewCell()
+	must(extra.WriteUint(0x4a33f6fd, 32))
+	for i := 0; i < 3; i++ {
+		must(extra.AddRef(boc.NewCell()))
+	}
+	must(extra.WriteBytes(make([]byte, 64)))
+	must(extra.WriteBit(true))
+	must(extra.AddRef(mc))
+
+	block := boc.NewCell()
+	must(block.WriteUint(0x11ef55aa, 32))
+	for i := 0; i < 3; i++ {
+		ref := boc.NewCell()
+		must(ref.WriteUint(uint64(i), 8))
+		must(block.AddRef(ref))
+	}
+	must(block.AddRef(extra))
+
+	prover, err := boc.NewMerkleProver(block)
+	must(err)
+	cur := prover.Cursor()
+	cur.Ref(3).Ref(3).Ref(0).Ref(0).Ref(0).Prune()
+	proof, err := prover.CreateProof(cur)
+	must(err)
+	hash, err := block.Hash256()
+	must(err)
+	return proof, ton.BlockIDExt{BlockID: ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: 100}, RootHash: hash}
+}
+
+func TestCheckShardTop(t *testing.T) {
+	proof, mc := masterBlockProof(t)
+	right := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: 0, Shard: 0xc000000000000000, Seqno: 20}}
+	right.RootHash[0], right.FileHash[0] = 3, 4
+
+	sp := liteclient.LiteServerShardBlockProofC{
+		MasterchainId: liteclient.BlockIDExt(mc),
+		Links:         []liteclient.LiteServerShardBlockLinkC{{Id: liteclient.BlockIDExt(right), Proof: proof}},
+	}
+	if err := verifyShardProof(sp, right); err != nil {
+		t.Fatalf("shard top: %v", err)
+	}
+
+	bad := func(name string, claim ton.BlockIDExt) {
+		t.Helper()
+		if err := checkShardTop(proof, claim); err == nil {
+			t.Errorf("%s was accepted", name)
+		}
+	}
+	wrong := right
+	wrong.Seqno++
+	bad("another seqno", wrong)
+	wrong = right
+	wrong.FileHash[0]++
+	bad("another file hash", wrong)
+	wrong = right
+	wrong.Shard = 0x8000000000000000
+	bad("a shard that is split", wrong)
+	wrong = right
+	wrong.Workchain = 1
+	bad("another workchain", wrong)
+	left := ton.BlockIDExt{BlockID: ton.BlockID{Workchain: 0, Shard: 0x4000000000000000, Seqno: 10}}
+	left.RootHash[0], left.FileHash[0] = 1, 2
+	bad("a pruned shard", left)
+
+	// the chain must start at the masterchain block the first link proves
+	sp.MasterchainId.Seqno++
+	sp.MasterchainId.RootHash[0] ^= 1
+	if err := verifyShardProof(sp, right); err == nil {
+		t.Error("a proof of another masterchain block was accepted")
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
@@ -105,7 +104,7 @@ func writeCSV(path string, results []Result) error {
 	w := csv.NewWriter(f)
 	defer w.Flush()
 
-	header := []string{"config", "targets", "mode", "concurrency", "total", "success", "errors", "duration_ms", "rps", "avg_ms", "p50_ms", "p90_ms", "p95_ms", "p99_ms", "max_ms"}
+	header := []string{"config", "targets", "mode", "concurrency", "total", "success", "errors", "duration_ms", "rps", "avg_ms", "p50_ms", "p90_ms", "p95_ms", "p99_ms", "max_ms", "not_found", "age_bucket", "gas_failures", "vm_failures", "exit_codes", "bytes", "mb_per_sec", "proof_failures", "hop_p95_ms", "accepted", "rejected", "back_pressure", "payload_avg_bytes", "payload_p50_bytes", "payload_p95_bytes", "payload_p99_bytes", "connect_rate", "handshake_p50_ms", "handshake_p95_ms", "handshake_p99_ms", "storm_recovery_ms", "clients", "clients_refused", "clients_starved", "fairness_index", "verify_checked", "verify_mismatches", "profile", "soak_intervals", "soak_drift", "session_active_p95_ms", "session_think_avg_ms", "session_request_rate", "agents", "log_dropped"}
 	if err := w.Write(header); err != nil {
 		return err
 	}
@@ -126,6 +125,41 @@ func writeCSV(path string, results []Result) error {
 			fmt.Sprintf("%.4f", r.P95Ms),
 			fmt.Sprintf("%.4f", r.P99Ms),
 			fmt.Sprintf("%.4f", r.MaxMs),
+			strconv.Itoa(r.NotFound),
+			r.AgeBucket,
+			strconv.Itoa(r.GasFailures),
+			strconv.Itoa(r.VMFailures),
+			formatExitCodes(r.ExitCodes),
+			strconv.FormatInt(r.Bytes, 10),
+			fmt.Sprintf("%.4f", r.MBps),
+			strconv.Itoa(r.ProofFailures),
+			fmt.Sprintf("%.4f", r.HopP95Ms),
+			strconv.Itoa(r.Accepted),
+			strconv.Itoa(r.Rejected),
+			strconv.Itoa(r.BackPressure),
+			fmt.Sprintf("%.1f", r.PayloadAvgBytes),
+			fmt.Sprintf("%.1f", r.PayloadP50Bytes),
+			fmt.Sprintf("%.1f", r.PayloadP95Bytes),
+			fmt.Sprintf("%.1f", r.PayloadP99Bytes),
+			fmt.Sprintf("%.2f", r.ConnectRate),
+			fmt.Sprintf("%.4f", r.HandshakeP50Ms),
+			fmt.Sprintf("%.4f", r.HandshakeP95Ms),
+			fmt.Sprintf("%.4f", r.HandshakeP99Ms),
+			fmt.Sprintf("%.4f", r.StormRecoveryMs),
+			strconv.Itoa(r.Clients),
+			strconv.Itoa(r.ClientsRefused),
+			strconv.Itoa(r.ClientsStarved),
+			fmt.Sprintf("%.4f", r.FairnessIndex),
+			strconv.Itoa(r.VerifyChecked),
+			strconv.Itoa(r.VerifyMismatches),
+			r.Profile,
+			strconv.Itoa(len(r.SoakIntervals)),
+			strings.Join(r.SoakDrift, "; "),
+			fmt.Sprintf("%.2f", r.SessionActiveP95Ms),
+			fmt.Sprintf("%.2f", r.SessionThinkAvgMs),
+			fmt.Sprintf("%.2f", r.SessionRequestRate),
+			strconv.Itoa(r.Agents),
+			strconv.Itoa(r.LogDropped),
 		}
 		if err := w.Write(row); err != nil {
 			return err
@@ -154,14 +188,15 @@ func readResultsJSON(path string) ([]Result, error) {
 	return results, nil
 }
 
-func regenerateReport(reportFrom, reqLogSpec string, maxPoints int) error {
+// regenerateReport rewrites the reports of a results dir and returns its checks against slo.
+func regenerateReport(reportFrom, reqLogSpec string, maxPoints int, exports []exportTarget, slo sloLimits) ([]summaryCheck, error) {
 	reportDir := strings.TrimSpace(reportFrom)
 	if reportDir == "" {
-		return fmt.Errorf("report-from is empty")
+		return nil, fmt.Errorf("report-from is empty")
 	}
 	info, err := os.Stat(reportDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if !info.IsDir() {
 		reportDir = filepath.Dir(reportDir)
@@ -169,7 +204,7 @@ func regenerateReport(reportFrom, reqLogSpec string, maxPoints int) error {
 	summaryPath := filepath.Join(reportDir, "summary.json")
 	results, err := readResultsJSON(summaryPath)
 	if err != nil {
-		return fmt.Errorf("failed to read %s: %w", summaryPath, err)
+		return nil, fmt.Errorf("failed to read %s: %w", summaryPath, err)
 	}
 
 	reqLogSpec = strings.TrimSpace(reqLogSpec)
@@ -178,7 +213,10 @@ func regenerateReport(reportFrom, reqLogSpec string, maxPoints int) error {
 	case "", "off":
 		reqLogPath = ""
 	case "auto":
-		reqLogPath = filepath.Join(reportDir, "requests.jsonl")
+		reqLogPath = resolveRequestLog(reportDir, results)
+		if _, err := os.Stat(reqLogPath); reqLogPath == "" || err != nil {
+			reqLogPath = findRequestLog(reportDir)
+		}
 	default:
 		reqLogPath = reqLogSpec
 	}
@@ -188,37 +226,49 @@ func regenerateReport(reportFrom, reqLogSpec string, maxPoints int) error {
 	var errorSeriesData map[errorSeriesKey]errorSeries
 	if reqLogPath != "" {
 		if _, err := os.Stat(reqLogPath); err != nil {
-			return fmt.Errorf("request log not found: %s", reqLogPath)
-		}
-		md, err := buildMethodSeriesFromLog(reqLogPath)
-		if err != nil {
-			return fmt.Errorf("failed to parse request log: %w", err)
+			return nil, fmt.Errorf("request log not found: %s", reqLogPath)
 		}
-		methodData = md
-		ed, err := buildErrorSummaryFromLog(reqLogPath)
+		agg, err := aggregateRequestLog(reqLogPath)
 		if err != nil {
-			return fmt.Errorf("failed to build error summary: %w", err)
+			return nil, fmt.Errorf("failed to parse request log: %w", err)
 		}
-		errorSummary = ed
-		es, err := buildErrorSeriesFromLog(reqLogPath)
-		if err != nil {
-			return fmt.Errorf("failed to build error series: %w", err)
+		methodData = agg.methodSeries()
+		errorSummary = agg.errorSummary()
+		errorSeriesData = agg.errorSeriesMap()
+	}
+
+	samples, err := readErrorSamples(filepath.Join(reportDir, "error_samples.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read error_samples.json: %w", err)
+	}
+
+	var freshness []freshnessSeries
+	if b, err := os.ReadFile(filepath.Join(reportDir, "freshness.json")); err == nil {
+		if err := json.Unmarshal(b, &freshness); err != nil {
+			return nil, fmt.Errorf("failed to read freshness.json: %w", err)
 		}
-		errorSeriesData = es
+	}
+
+	writeExports(exports, reportDir, results, methodData, errorSeriesData)
+
+	checks := summaryChecks(results, slo)
+	if err := writeMarkdownSummary(filepath.Join(reportDir, "summary.md"), results, errorSummary, checks); err != nil {
+		return nil, err
 	}
 
 	reportPath := filepath.Join(reportDir, "report.html")
-	if err := writeHTMLReport(reportPath, results, methodData, errorSummary, errorSeriesData, maxPoints); err != nil {
-		return err
+	if err := writeHTMLReport(reportPath, results, methodData, errorSummary, errorSeriesData, samples, freshness, maxPoints); err != nil {
+		return nil, err
 	}
 	fmt.Printf("\nReport written to: %s\n", reportPath)
-	return nil
+	return checks, nil
 }
 
-func writeHTMLReport(path string, results []Result, methods map[methodKey]methodSeries, errorsSummary []errorSummaryEntry, errorSeries map[errorSeriesKey]errorSeries, maxPoints int) error {
+func writeHTMLReport(path string, results []Result, methods map[methodKey]methodSeries, errorsSummary []errorSummaryEntry, errorSeries map[errorSeriesKey]errorSeries, samples []errorSample, freshness []freshnessSeries, maxPoints int) error {
 	if maxPoints < 0 {
 		maxPoints = 0
 	}
+	fresh := buildFreshnessChart(results, freshness, maxPoints)
 	results = downsampleResults(results, maxPoints)
 	methods = downsampleMethodSeries(methods, maxPoints)
 	errorSeries = downsampleErrorSeries(errorSeries, maxPoints)
@@ -226,18 +276,32 @@ func writeHTMLReport(path string, results []Result, methods map[methodKey]method
 	sanity := buildSanityBlock(results)
 	origMethods := methods
 	summarySection := buildSummarySection(results, configs, origMethods)
+	ageSection := buildAgeSection(results, configs)
+	runMethodSection := buildRunMethodSection(results, configs)
+	pagesSection := buildPagesSection(results, configs)
+	proofsSection := buildProofsSection(results, configs)
+	sendSection := buildSendSection(results, configs)
+	connectSection := buildConnectSection(results, configs)
+	clientsSection := buildClientsSection(results, configs)
+	verifySection := buildVerifySection(results, configs)
+	soakSection := buildSoakSection(results, configs)
+	sessionSection := buildSessionSection(results, configs)
+	freshnessSection := buildFreshnessSection(freshness)
 	errorsSection := buildErrorsSection(errorsSummary, configs)
+	samplesSection := buildErrorSamplesSection(samples, configs)
 	chartsSection := buildChartsSection(configs)
 	methodEntries := flattenMethodSeries(methods)
 	errorEntries := flattenErrorSeries(errorSeries)
 	reportJSON, _ := json.Marshal(struct {
-		Results []Result            `json:"results"`
-		Methods []methodSeriesEntry `json:"methods"`
-		Errors  []errorSeriesEntry  `json:"errors"`
+		Results   []Result            `json:"results"`
+		Methods   []methodSeriesEntry `json:"methods"`
+		Errors    []errorSeriesEntry  `json:"errors"`
+		Freshness *freshnessChart     `json:"freshness,omitempty"`
 	}{
-		Results: results,
-		Methods: methodEntries,
-		Errors:  errorEntries,
+		Results:   results,
+		Methods:   methodEntries,
+		Errors:    errorEntries,
+		Freshness: fresh,
 	})
 	tmpl := strings.TrimSpace(reportTemplate)
 	if tmpl == "" {
@@ -247,7 +311,19 @@ func writeHTMLReport(path string, results []Result, methods map[methodKey]method
 	body := strings.ReplaceAll(tmpl, "{{TIME}}", time.Now().Format(time.RFC3339))
 	body = strings.ReplaceAll(body, "{{SANITY}}", sanity)
 	body = strings.ReplaceAll(body, "{{SUMMARY_SECTION}}", summarySection)
+	body = strings.ReplaceAll(body, "{{AGE_SECTION}}", ageSection)
+	body = strings.ReplaceAll(body, "{{RUNMETHOD_SECTION}}", runMethodSection)
+	body = strings.ReplaceAll(body, "{{PAGES_SECTION}}", pagesSection)
+	body = strings.ReplaceAll(body, "{{PROOFS_SECTION}}", proofsSection)
+	body = strings.ReplaceAll(body, "{{SEND_SECTION}}", sendSection)
+	body = strings.ReplaceAll(body, "{{CONNECT_SECTION}}", connectSection)
+	body = strings.ReplaceAll(body, "{{CLIENTS_SECTION}}", clientsSection)
+	body = strings.ReplaceAll(body, "{{VERIFY_SECTION}}", verifySection)
+	body = strings.ReplaceAll(body, "{{SOAK_SECTION}}", soakSection)
+	body = strings.ReplaceAll(body, "{{SESSION_SECTION}}", sessionSection)
+	body = strings.ReplaceAll(body, "{{FRESHNESS_SECTION}}", freshnessSection)
 	body = strings.ReplaceAll(body, "{{ERRORS_SECTION}}", errorsSection)
+	body = strings.ReplaceAll(body, "{{ERROR_SAMPLES_SECTION}}", samplesSection)
 	body = strings.ReplaceAll(body, "{{CHARTS_SECTION}}", chartsSection)
 	body = strings.ReplaceAll(body, "{{MAX_POINTS}}", strconv.Itoa(maxPoints))
 	body = strings.ReplaceAll(body, "{{REPORT_JSON}}", string(reportJSON))
@@ -255,6 +331,41 @@ func writeHTMLReport(path string, results []Result, methods map[methodKey]method
 	return os.WriteFile(path, []byte(body), 0o644)
 }
 
+func writePayloadsCSV(path string, results []Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	defer w.Flush()
+
+	if err := w.Write([]string{"config", "mode", "concurrency", "request", "count", "bytes", "avg_bytes", "p50_bytes", "p95_bytes", "p99_bytes", "mb_per_sec"}); err != nil {
+		return err
+	}
+	for _, r := range results {
+		for _, p := range r.Payloads {
+			row := []string{
+				r.Config,
+				r.Mode,
+				strconv.Itoa(r.Concurrency),
+				p.Method,
+				strconv.Itoa(p.Count),
+				strconv.FormatInt(p.Bytes, 10),
+				fmt.Sprintf("%.1f", p.AvgBytes),
+				fmt.Sprintf("%.1f", p.P50Bytes),
+				fmt.Sprintf("%.1f", p.P95Bytes),
+				fmt.Sprintf("%.1f", p.P99Bytes),
+				fmt.Sprintf("%.4f", p.MBps),
+			}
+			if err := w.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	return w.Error()
+}
+
 func writeErrorsCSV(path string, entries []errorSummaryEntry) error {
 	f, err := os.Create(path)
 	if err != nil {
@@ -347,64 +458,770 @@ func buildHTMLTable(results []Result) string {
 	return b.String()
 }
 
-func uniqueConfigs(results []Result) []string {
-	if len(results) == 0 {
+func uniqueConfigs(results []Result) []string {
+	if len(results) == 0 {
+		return nil
+	}
+	seen := map[string]bool{}
+	var configs []string
+	for _, r := range results {
+		if !seen[r.Config] {
+			seen[r.Config] = true
+			configs = append(configs, r.Config)
+		}
+	}
+	sort.Strings(configs)
+	return configs
+}
+
+func buildSummarySection(results []Result, configs []string, methods map[methodKey]methodSeries) string {
+	if len(results) == 0 || len(configs) == 0 {
+		return ""
+	}
+	byConfig := map[string][]Result{}
+	for _, r := range results {
+		byConfig[r.Config] = append(byConfig[r.Config], r)
+	}
+
+	var b strings.Builder
+	b.WriteString("<section class=\"section\">")
+	b.WriteString("<h2>Summary</h2>")
+	b.WriteString("<div class=\"config-grid\">")
+	for _, cfg := range configs {
+		list := byConfig[cfg]
+		if len(list) == 0 {
+			continue
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Mode != list[j].Mode {
+				return list[i].Mode < list[j].Mode
+			}
+			return list[i].Concurrency < list[j].Concurrency
+		})
+		title := htmlEsc(cfg)
+		if list[0].Targets != "" {
+			title += " — " + htmlEsc(list[0].Targets)
+		}
+		b.WriteString("<div class=\"card\">")
+		b.WriteString("<div class=\"summary-title\">" + title + "</div>")
+		b.WriteString(buildSummaryTable(list))
+		if mt := buildMethodSummaryTable(cfg, methods); mt != "" {
+			b.WriteString("<div class=\"summary-title\">Methods</div>")
+			b.WriteString(mt)
+		}
+		b.WriteString("</div>")
+	}
+	b.WriteString("</div>")
+	b.WriteString("</section>")
+	return b.String()
+}
+
+func buildAgeSection(results []Result, configs []string) string {
+	byConfig := map[string][]Result{}
+	for _, r := range results {
+		if r.AgeBucket == "" {
+			continue
+		}
+		byConfig[r.Config] = append(byConfig[r.Config], r)
+	}
+	if len(byConfig) == 0 {
+		return ""
+	}
+
+	headers := []string{"Bucket", "Seqno", "Conc", "Total", "OK", "Not found", "NF %", "Avg ms", "P50", "P95", "P99"}
+	var b strings.Builder
+	b.WriteString("<section class=\"section\">")
+	b.WriteString("<h2>Archive depth</h2>")
+	b.WriteString("<div class=\"config-grid\">")
+	for _, cfg := range configs {
+		list := byConfig[cfg]
+		if len(list) == 0 {
+			continue
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].AgeMinSec != list[j].AgeMinSec {
+				return list[i].AgeMinSec < list[j].AgeMinSec
+			}
+			return list[i].Concurrency < list[j].Concurrency
+		})
+		b.WriteString("<div class=\"card\">")
+		b.WriteString("<div class=\"summary-title\">" + htmlEsc(cfg) + "</div>")
+		b.WriteString("<table class=\"table\"><thead><tr>")
+		for _, h := range headers {
+			b.WriteString("<th>" + h + "</th>")
+		}
+		b.WriteString("</tr></thead><tbody>")
+		for _, r := range list {
+			nfPct := 0.0
+			if r.Total > 0 {
+				nfPct = float64(r.NotFound) / float64(r.Total) * 100
+			}
+			b.WriteString("<tr class=\"item\">")
+			b.WriteString("<td>" + htmlEsc(r.AgeBucket) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%d-%d", r.SeqnoFrom, r.SeqnoTo) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Concurrency) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Total) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Success) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.NotFound) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", nfPct) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.AvgMs) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P50Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P95Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P99Ms) + "</td>")
+			b.WriteString("</tr>")
+		}
+		b.WriteString("</tbody></table>")
+		b.WriteString("<div class=\"chart\"><div class=\"age-root\" data-config=\"" + htmlEsc(cfg) + "\"></div></div>")
+		b.WriteString("</div>")
+	}
+	b.WriteString("</div>")
+	b.WriteString("</section>")
+	return b.String()
+}
+
+func buildRunMethodSection(results []Result, configs []string) string {
+	byConfig := map[string][]Result{}
+	for _, r := range results {
+		if r.Mode != string(ModeRunMethod) {
+			continue
+		}
+		byConfig[r.Config] = append(byConfig[r.Config], r)
+	}
+	if len(byConfig) == 0 {
+		return ""
+	}
+
+	headers := []string{"Conc", "Calls", "Served", "Transport err", "Gas fail", "VM fail", "Exit codes", "P50", "P95", "P99"}
+	var b strings.Builder
+	b.WriteString("<section class=\"section\">")
+	b.WriteString("<h2>Get-methods</h2>")
+	b.WriteString("<div class=\"config-grid\">")
+	for _, cfg := range configs {
+		list := byConfig[cfg]
+		if len(list) == 0 {
+			continue
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Concurrency < list[j].Concurrency })
+		b.WriteString("<div class=\"card\">")
+		b.WriteString("<div class=\"summary-title\">" + htmlEsc(cfg) + "</div>")
+		b.WriteString("<table class=\"table\"><thead><tr>")
+		for _, h := range headers {
+			b.WriteString("<th>" + h + "</th>")
+		}
+		b.WriteString("</tr></thead><tbody>")
+		for _, r := range list {
+			b.WriteString("<tr class=\"item\">")
+			b.WriteString("<td>" + strconv.Itoa(r.Concurrency) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Total) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Success) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Errors) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.GasFailures) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.VMFailures) + "</td>")
+			b.WriteString("<td><span class=\"badge\">" + htmlEsc(formatExitCodes(r.ExitCodes)) + "</span></td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P50Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P95Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P99Ms) + "</td>")
+			b.WriteString("</tr>")
+		}
+		b.WriteString("</tbody></table>")
+		b.WriteString("</div>")
+	}
+	b.WriteString("</div>")
+	b.WriteString("</section>")
+	return b.String()
+}
+
+func buildPagesSection(results []Result, configs []string) string {
+	byConfig := map[string][]Result{}
+	for _, r := range results {
+		if len(r.Pages) == 0 {
+			continue
+		}
+		byConfig[r.Config] = append(byConfig[r.Config], r)
+	}
+	if len(byConfig) == 0 {
+		return ""
+	}
+
+	headers := []string{"Mode", "Conc", "Page", "Requests", "Txs", "Avg ms", "P50", "P95", "P99"}
+	var b strings.Builder
+	b.WriteString("<section class=\"section\">")
+	b.WriteString("<h2>Transaction history depth</h2>")
+	b.WriteString("<div class=\"config-grid\">")
+	for _, cfg := range configs {
+		list := byConfig[cfg]
+		if len(list) == 0 {
+			continue
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Mode != list[j].Mode {
+				return list[i].Mode < list[j].Mode
+			}
+			return list[i].Concurrency < list[j].Concurrency
+		})
+		b.WriteString("<div class=\"card\">")
+		b.WriteString("<div class=\"summary-title\">" + htmlEsc(cfg) + "</div>")
+		b.WriteString("<table class=\"table\"><thead><tr>")
+		for _, h := range headers {
+			b.WriteString("<th>" + h + "</th>")
+		}
+		b.WriteString("</tr></thead><tbody>")
+		for _, r := range list {
+			for _, p := range r.Pages {
+				b.WriteString("<tr class=\"item\">")
+				b.WriteString("<td>" + htmlEsc(r.Mode) + "</td>")
+				b.WriteString("<td>" + strconv.Itoa(r.Concurrency) + "</td>")
+				b.WriteString("<td>" + strconv.Itoa(p.Page) + "</td>")
+				b.WriteString("<td>" + strconv.Itoa(p.Requests) + "</td>")
+				b.WriteString("<td>" + strconv.Itoa(p.Txs) + "</td>")
+				b.WriteString("<td>" + fmt.Sprintf("%.1f", p.AvgMs) + "</td>")
+				b.WriteString("<td>" + fmt.Sprintf("%.1f", p.P50Ms) + "</td>")
+				b.WriteString("<td>" + fmt.Sprintf("%.1f", p.P95Ms) + "</td>")
+				b.WriteString("<td>" + fmt.Sprintf("%.1f", p.P99Ms) + "</td>")
+				b.WriteString("</tr>")
+			}
+		}
+		b.WriteString("</tbody></table>")
+		b.WriteString("</div>")
+	}
+	b.WriteString("</div>")
+	b.WriteString("</section>")
+	return b.String()
+}
+
+func buildProofsSection(results []Result, configs []string) string {
+	byConfig := map[string][]Result{}
+	for _, r := range results {
+		if r.Mode != string(ModeProofs) {
+			continue
+		}
+		byConfig[r.Config] = append(byConfig[r.Config], r)
+	}
+	if len(byConfig) == 0 {
+		return ""
+	}
+
+	headers := []string{"Conc", "Clients", "Synced", "Verify fail", "Proof calls", "Links", "Hop P50", "Hop P95", "Hop P99", "Sync P50", "Sync P95", "Sync P99"}
+	var b strings.Builder
+	b.WriteString("<section class=\"section\">")
+	b.WriteString("<h2>Light-client sync</h2>")
+	b.WriteString("<div class=\"config-grid\">")
+	for _, cfg := range configs {
+		list := byConfig[cfg]
+		if len(list) == 0 {
+			continue
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Concurrency < list[j].Concurrency })
+		b.WriteString("<div class=\"card\">")
+		b.WriteString("<div class=\"summary-title\">" + htmlEsc(cfg) + "</div>")
+		b.WriteString("<table class=\"table\"><thead><tr>")
+		for _, h := range headers {
+			b.WriteString("<th>" + h + "</th>")
+		}
+		b.WriteString("</tr></thead><tbody>")
+		for _, r := range list {
+			b.WriteString("<tr class=\"item\">")
+			b.WriteString("<td>" + strconv.Itoa(r.Concurrency) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Total) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Success) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.ProofFailures) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.ProofCalls) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.ProofLinks) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.HopP50Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.HopP95Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.HopP99Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P50Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P95Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P99Ms) + "</td>")
+			b.WriteString("</tr>")
+		}
+		b.WriteString("</tbody></table>")
+		b.WriteString("</div>")
+	}
+	b.WriteString("</div>")
+	b.WriteString("</section>")
+	return b.String()
+}
+
+func buildSendSection(results []Result, configs []string) string {
+	byConfig := map[string][]Result{}
+	for _, r := range results {
+		if r.Mode != string(ModeSend) {
+			continue
+		}
+		byConfig[r.Config] = append(byConfig[r.Config], r)
+	}
+	if len(byConfig) == 0 {
+		return ""
+	}
+
+	headers := []string{"Conc", "Sent", "Accepted", "Rejected", "Back-pressure", "Other err", "Codes", "RPS", "P50", "P95", "P99"}
+	var b strings.Builder
+	b.WriteString("<section class=\"section\">")
+	b.WriteString("<h2>SendMessage</h2>")
+	b.WriteString("<div class=\"config-grid\">")
+	for _, cfg := range configs {
+		list := byConfig[cfg]
+		if len(list) == 0 {
+			continue
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Concurrency < list[j].Concurrency })
+		b.WriteString("<div class=\"card\">")
+		b.WriteString("<div class=\"summary-title\">" + htmlEsc(cfg) + "</div>")
+		b.WriteString("<table class=\"table\"><thead><tr>")
+		for _, h := range headers {
+			b.WriteString("<th>" + h + "</th>")
+		}
+		b.WriteString("</tr></thead><tbody>")
+		for _, r := range list {
+			b.WriteString("<tr class=\"item\">")
+			b.WriteString("<td>" + strconv.Itoa(r.Concurrency) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Total) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Accepted) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Rejected) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.BackPressure) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Errors-r.BackPressure) + "</td>")
+			b.WriteString("<td><span class=\"badge\">" + htmlEsc(formatExitCodes(r.ExitCodes)) + "</span></td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.RPS) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P50Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P95Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P99Ms) + "</td>")
+			b.WriteString("</tr>")
+		}
+		b.WriteString("</tbody></table>")
+		b.WriteString("</div>")
+	}
+	b.WriteString("</div>")
+	b.WriteString("</section>")
+	return b.String()
+}
+
+func buildConnectSection(results []Result, configs []string) string {
+	byConfig := map[string][]Result{}
+	for _, r := range results {
+		if !isConnectMode(r.Mode) {
+			continue
+		}
+		byConfig[r.Config] = append(byConfig[r.Config], r)
+	}
+	if len(byConfig) == 0 {
+		return ""
+	}
+
+	headers := []string{"Target", "Conc", "Rate/s", "Attempts", "OK", "Fail %", "Handshake P50", "P95", "P99", "Connect+query P95", "Recovery"}
+	var b strings.Builder
+	b.WriteString("<section class=\"section\">")
+	b.WriteString("<h2>Connections</h2>")
+	b.WriteString("<div class=\"config-grid\">")
+	for _, cfg := range configs {
+		list := byConfig[cfg]
+		if len(list) == 0 {
+			continue
+		}
+		sort.SliceStable(list, func(i, j int) bool {
+			if list[i].Mode != list[j].Mode {
+				return list[i].Mode < list[j].Mode
+			}
+			return list[i].Concurrency < list[j].Concurrency
+		})
+		b.WriteString("<div class=\"card\">")
+		b.WriteString("<div class=\"summary-title\">" + htmlEsc(cfg) + "</div>")
+		b.WriteString("<table class=\"table\"><thead><tr>")
+		for _, h := range headers {
+			b.WriteString("<th>" + h + "</th>")
+		}
+		b.WriteString("</tr></thead><tbody>")
+		for _, r := range list {
+			failPct := 0.0
+			if r.Total > 0 {
+				failPct = float64(r.Errors) * 100 / float64(r.Total)
+			}
+			rate, recovery := "-", "-"
+			if r.ConnectRate > 0 {
+				rate = fmt.Sprintf("%.1f", r.ConnectRate)
+			}
+			if r.StormRecoveryMs > 0 {
+				recovery = fmt.Sprintf("%.0f ms", r.StormRecoveryMs)
+			}
+			b.WriteString("<tr class=\"item\">")
+			b.WriteString("<td>" + htmlEsc(strings.TrimPrefix(r.Mode, connectModePrefix)) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Concurrency) + "</td>")
+			b.WriteString("<td>" + rate + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Total) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(r.Success) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", failPct) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.HandshakeP50Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.HandshakeP95Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.HandshakeP99Ms) + "</td>")
+			b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P95Ms) + "</td>")
+			b.WriteString("<td>" + recovery + "</td>")
+			b.WriteString("</tr>")
+		}
+		b.WriteString("</tbody></table>")
+		b.WriteString("</div>")
+	}
+	b.WriteString("</div>")
+	b.WriteString("</section>")
+	return b.String()
+}
+
+func buildClientsSection(results []Result, configs []string) string {
+	byConfig := map[string][]Result{}
+	for _, r := range results {
+		if r.Clients == 0 {
+			continue
+		}
+		byConfig[r.Config] = append(byConfig[r.Config], r)
+	}
+	if len(byConfig) == 0 {
+		return ""
+	}
+
+	headers := []string{"Mode", "Conc", "Server", "Clients", "Refused", "Starved", "Jobs", "Err %", "Avg", "P95", "Client P95 min–max", "Fairness"}
+	var b strings.Builder
+	b.WriteString("<section class=\"section\">")
+	b.WriteString("<h2>Independent clients</h2>")
+	b.WriteString("<div class=\"config-grid\">")
+	for _, cfg := range configs {
+		list := byConfig[cfg]
+		if len(list) == 0 {
+			continue
+		}
+		sort.SliceStable(list, func(i, j int) bool {
+			if list[i].Mode != list[j].Mode {
+				return list[i].Mode < list[j].Mode
+			}
+			return list[i].Concurrency < list[j].Concurrency
+		})
+		b.WriteString("<div class=\"card\">")
+		b.WriteString("<div class=\"summary-title\">" + htmlEsc(cfg) + "</div>")
+		b.WriteString("<table class=\"table\"><thead><tr>")
+		for _, h := range headers {
+			b.WriteString("<th>" + h + "</th>")
+		}
+		b.WriteString("</tr></thead><tbody>")
+		for _, r := range list {
+			for i, st := range r.ClientServers {
+				mode, conc, fairness := "", "", ""
+				if i == 0 {
+					mode, conc, fairness = htmlEsc(r.Mode), strconv.Itoa(r.Concurrency), fmt.Sprintf("%.3f", r.FairnessIndex)
+				}
+				b.WriteString("<tr class=\"item\">")
+				b.WriteString("<td>" + mode + "</td>")
+				b.WriteString("<td>" + conc + "</td>")
+				b.WriteString("<td>" + htmlEsc(st.Host) + "</td>")
+				b.WriteString("<td>" + strconv.Itoa(st.Clients) + "</td>")
+				b.WriteString("<td>" + strconv.Itoa(st.Refused) + "</td>")
+				b.WriteString("<td>" + strconv.Itoa(st.Starved) + "</td>")
+				b.WriteString("<td>" + strconv.Itoa(st.Jobs) + "</td>")
+				b.WriteString("<td>" + fmt.Sprintf("%.1f", st.ErrorRate*100) + "</td>")
+				b.WriteString("<td>" + fmt.Sprintf("%.1f", st.AvgMs) + "</td>")
+				b.WriteString("<td>" + fmt.Sprintf("%.1f", st.P95Ms) + "</td>")
+				b.WriteString("<td>" + fmt.Sprintf("%.1f–%.1f", st.MinP95Ms, st.MaxP95Ms) + "</td>")
+				b.WriteString("<td>" + fairness + "</td>")
+				b.WriteString("</tr>")
+			}
+		}
+		b.WriteString("</tbody></table>")
+		b.WriteString("</div>")
+	}
+	b.WriteString("</div>")
+	b.WriteString("</section>")
+	return b.String()
+}
+
+func buildSessionSection(results []Result, configs []string) string {
+	byConfig := map[string][]Result{}
+	for _, r := range results {
+		if r.Mode == string(ModeSession) {
+			byConfig[r.Config] = append(byConfig[r.Config], r)
+		}
+	}
+	if len(byConfig) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("<section class=\"section\">")
+	b.WriteString("<h2>Sessions</h2>")
+	b.WriteString("<div class=\"config-grid\">")
+	for _, cfg := range configs {
+		list := byConfig[cfg]
+		if len(list) == 0 {
+			continue
+		}
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Concurrency < list[j].Concurrency })
+		b.WriteString("<div class=\"card\">")
+		b.WriteString("<div class=\"summary-title\">" + htmlEsc(cfg) + "</div>")
+		b.WriteString("<table class=\"table\"><thead><tr>")
+		for _, h := range []string{"Users", "Sessions/s", "Req/s", "Session P50", "Session P95", "Active P50", "Active P95", "Think avg", "Failed"} {
+			b.WriteString("<th>" + h + "</th>")
+		}
+		b.WriteString("</tr></thead><tbody>")
+		for _, r := range list {
+			b.WriteString("<tr class=\"item\">")
+			b.WriteString("<td>" + strconv.Itoa(r.Concurrency) + "</td>")
+			b.WriteString(fmt.Sprintf("<td>%.2f</td><td>%.1f</td>", r.RPS, r.SessionRequestRate))
+			b.WriteString(fmt.Sprintf("<td>%.0f ms</td><td>%.0f ms</td>", r.P50Ms, r.P95Ms))
+			b.WriteString(fmt.Sprintf("<td>%.0f ms</td><td>%.0f ms</td>", r.SessionActiveP50Ms, r.SessionActiveP95Ms))
+			b.WriteString(fmt.Sprintf("<td>%.0f ms</td>", r.SessionThinkAvgMs))
+			b.WriteString("<td>" + strconv.Itoa(r.Errors) + "</td>")
+			b.WriteString("</tr>")
+			b.WriteString("<tr class=\"item\"><td colspan=\"9\"><table class=\"table method-table\"><thead><tr>")
+			for _, h := range []string{"Step", "Requests", "Errors", "Avg", "P50", "P95", "P99"} {
+				b.WriteString("<th>" + h + "</th>")
+			}
+			b.WriteString("</tr></thead><tbody>")
+			for _, st := range r.SessionSteps {
+				b.WriteString("<tr class=\"item\">")
+				b.WriteString("<td class=\"indent\">" + htmlEsc(st.Step) + "</td>")
+				b.WriteString("<td>" + strconv.Itoa(st.Requests) + "</td>")
+				b.WriteString("<td>" + strconv.Itoa(st.Errors) + "</td>")
+				b.WriteString(fmt.Sprintf("<td>%.1f</td><td>%.1f</td><td>%.1f</td><td>%.1f</td>", st.AvgMs, st.P50Ms, st.P95Ms, st.P99Ms))
+				b.WriteString("</tr>")
+			}
+			b.WriteString("</tbody></table></td></tr>")
+		}
+		b.WriteString("</tbody></table>")
+		b.WriteString("</div>")
+	}
+	b.WriteString("</div>")
+	b.WriteString("</section>")
+	return b.String()
+}
+
+func buildSoakSection(results []Result, configs []string) string {
+	var list []Result
+	for _, cfg := range configs {
+		for _, r := range results {
+			if r.Config == cfg && len(r.SoakIntervals) > 0 {
+				list = append(list, r)
+			}
+		}
+	}
+	if len(list) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("<section class=\"section\">")
+	b.WriteString("<h2>Soak</h2>")
+	b.WriteString("<table class=\"table\"><thead><tr>")
+	for _, h := range []string{"Config", "Mode", "Conc", "Intervals", "First P95", "Last P95", "First RPS", "Last RPS", "Drift"} {
+		b.WriteString("<th>" + h + "</th>")
+	}
+	b.WriteString("</tr></thead><tbody>")
+	for _, r := range list {
+		first, last := r.SoakIntervals[0], r.SoakIntervals[len(r.SoakIntervals)-1]
+		drift := "none"
+		if len(r.SoakDrift) > 0 {
+			drift = htmlEsc(strings.Join(r.SoakDrift, "; "))
+		}
+		b.WriteString("<tr class=\"item\">")
+		b.WriteString("<td>" + htmlEsc(r.Config) + "</td>")
+		b.WriteString("<td>" + htmlEsc(r.Mode) + "</td>")
+		b.WriteString("<td>" + strconv.Itoa(r.Concurrency) + "</td>")
+		b.WriteString("<td>" + strconv.Itoa(len(r.SoakIntervals)) + "</td>")
+		b.WriteString(fmt.Sprintf("<td>%.1f ms</td><td>%.1f ms</td>", first.P95Ms, last.P95Ms))
+		b.WriteString(fmt.Sprintf("<td>%.1f</td><td>%.1f</td>", first.RPS, last.RPS))
+		b.WriteString("<td>" + drift + "</td>")
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</tbody></table>")
+
+	for _, r := range list {
+		b.WriteString("<details class=\"card\">")
+		b.WriteString("<summary class=\"summary-title\">" + htmlEsc(r.Config) + " · " + htmlEsc(r.Mode) + " · c" + strconv.Itoa(r.Concurrency) + " · intervals</summary>")
+		b.WriteString("<table class=\"table\"><thead><tr>")
+		for _, h := range []string{"#", "Start", "RPS", "P50", "P95", "P99", "Max", "Errors", "Drift"} {
+			b.WriteString("<th>" + h + "</th>")
+		}
+		b.WriteString("</tr></thead><tbody>")
+		for _, iv := range r.SoakIntervals {
+			class := "item"
+			if len(iv.Drift) > 0 {
+				class = "item drift"
+			}
+			b.WriteString("<tr class=\"" + class + "\">")
+			b.WriteString("<td>" + strconv.Itoa(iv.Index) + "</td>")
+			b.WriteString("<td>" + time.UnixMilli(iv.StartMs).UTC().Format("15:04:05") + "</td>")
+			b.WriteString(fmt.Sprintf("<td>%.1f</td><td>%.1f</td><td>%.1f</td><td>%.1f</td><td>%.0f</td>", iv.RPS, iv.P50Ms, iv.P95Ms, iv.P99Ms, iv.MaxMs))
+			b.WriteString(fmt.Sprintf("<td>%d (%.2f%%)</td>", iv.Errors, iv.ErrorRate*100))
+			b.WriteString("<td>" + htmlEsc(strings.Join(iv.Drift, ", ")) + "</td>")
+			b.WriteString("</tr>")
+		}
+		b.WriteString("</tbody></table>")
+		b.WriteString("</details>")
+	}
+	b.WriteString("</section>")
+	return b.String()
+}
+
+func buildVerifySection(results []Result, configs []string) string {
+	byConfig := map[string]Result{}
+	for _, r := range results {
+		if r.Mode == string(ModeVerify) {
+			byConfig[r.Config] = r
+		}
+	}
+	if len(byConfig) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("<section class=\"section\">")
+	b.WriteString("<h2>Consistency</h2>")
+	b.WriteString("<table class=\"table\"><thead><tr>")
+	for _, h := range []string{"Config", "Requests", "Errors", "Compared", "Mismatches"} {
+		b.WriteString("<th>" + h + "</th>")
+	}
+	b.WriteString("</tr></thead><tbody>")
+	for _, cfg := range configs {
+		r, ok := byConfig[cfg]
+		if !ok {
+			continue
+		}
+		b.WriteString("<tr class=\"item\">")
+		b.WriteString("<td>" + htmlEsc(cfg) + "</td>")
+		b.WriteString("<td>" + strconv.Itoa(r.Total) + "</td>")
+		b.WriteString("<td>" + strconv.Itoa(r.Errors) + "</td>")
+		b.WriteString("<td>" + strconv.Itoa(r.VerifyChecked) + "</td>")
+		b.WriteString("<td>" + strconv.Itoa(r.VerifyMismatches) + "</td>")
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</tbody></table>")
+
+	for _, cfg := range configs {
+		r, ok := byConfig[cfg]
+		if !ok || len(r.VerifyDiffs) == 0 {
+			continue
+		}
+		b.WriteString("<div class=\"card\">")
+		b.WriteString("<div class=\"summary-title\">" + htmlEsc(cfg) + " · mismatches</div>")
+		b.WriteString("<table class=\"table\"><thead><tr>")
+		for _, h := range []string{"Request", "Params", "Answer", "Reference", "Reference from"} {
+			b.WriteString("<th>" + h + "</th>")
+		}
+		b.WriteString("</tr></thead><tbody>")
+		for _, d := range r.VerifyDiffs {
+			b.WriteString("<tr class=\"item\">")
+			b.WriteString("<td>" + htmlEsc(d.Kind) + "</td>")
+			b.WriteString("<td>" + htmlEsc(d.Params) + "</td>")
+			b.WriteString("<td><code>" + htmlEsc(d.Hash) + "</code></td>")
+			b.WriteString("<td><code>" + htmlEsc(d.Reference) + "</code></td>")
+			b.WriteString("<td>" + htmlEsc(strings.Join(d.RefConfigs, ", ")) + "</td>")
+			b.WriteString("</tr>")
+		}
+		b.WriteString("</tbody></table>")
+		b.WriteString("</div>")
+	}
+	b.WriteString("</section>")
+	return b.String()
+}
+
+// freshnessChart is the lag chart data: per-server lag and the total load of all workloads on one timeline.
+type freshnessChart struct {
+	StartMs int64             `json:"start_ms"`
+	Sec     []int             `json:"sec"`
+	Load    []float64         `json:"load"`
+	Series  []freshnessSeries `json:"series"`
+}
+
+func buildFreshnessChart(results []Result, freshness []freshnessSeries, maxPoints int) *freshnessChart {
+	if len(freshness) == 0 || len(freshness[0].Sec) == 0 {
 		return nil
 	}
-	seen := map[string]bool{}
-	var configs []string
+	fc := &freshnessChart{StartMs: freshness[0].StartMs, Sec: freshness[0].Sec}
+	fc.Load = make([]float64, len(fc.Sec))
 	for _, r := range results {
-		if !seen[r.Config] {
-			seen[r.Config] = true
-			configs = append(configs, r.Config)
+		if r.SeriesStart == 0 {
+			continue
+		}
+		offset := int((r.SeriesStart - fc.StartMs + 500) / 1000)
+		for i, v := range r.SeriesRPS {
+			sec := i + 1
+			if i < len(r.SeriesSec) {
+				sec = r.SeriesSec[i]
+			}
+			if idx := offset + sec - 1; idx >= 0 && idx < len(fc.Load) {
+				fc.Load[idx] += v
+			}
 		}
 	}
-	sort.Strings(configs)
-	return configs
+	fc.Series = freshness
+	if maxPoints > 0 && len(fc.Sec) > maxPoints {
+		idxs := sampleIndices(len(fc.Sec), maxPoints)
+		fc.Sec = sampleInts(fc.Sec, idxs)
+		fc.Load = sampleFloats(fc.Load, idxs)
+		fc.Series = make([]freshnessSeries, len(freshness))
+		for i, fs := range freshness {
+			fs.Lag = sampleFloats(fs.Lag, idxs)
+			fc.Series[i] = fs
+		}
+	}
+	return fc
 }
 
-func buildSummarySection(results []Result, configs []string, methods map[methodKey]methodSeries) string {
-	if len(results) == 0 || len(configs) == 0 {
+func buildFreshnessSection(freshness []freshnessSeries) string {
+	if len(freshness) == 0 {
 		return ""
 	}
-	byConfig := map[string][]Result{}
-	for _, r := range results {
-		byConfig[r.Config] = append(byConfig[r.Config], r)
-	}
-
 	var b strings.Builder
 	b.WriteString("<section class=\"section\">")
-	b.WriteString("<h2>Summary</h2>")
-	b.WriteString("<div class=\"config-grid\">")
-	for _, cfg := range configs {
-		list := byConfig[cfg]
-		if len(list) == 0 {
-			continue
-		}
-		sort.Slice(list, func(i, j int) bool {
-			if list[i].Mode != list[j].Mode {
-				return list[i].Mode < list[j].Mode
-			}
-			return list[i].Concurrency < list[j].Concurrency
-		})
-		title := htmlEsc(cfg)
-		if list[0].Targets != "" {
-			title += " — " + htmlEsc(list[0].Targets)
-		}
-		b.WriteString("<div class=\"card\">")
-		b.WriteString("<div class=\"summary-title\">" + title + "</div>")
-		b.WriteString(buildSummaryTable(list))
-		if mt := buildMethodSummaryTable(cfg, methods); mt != "" {
-			b.WriteString("<div class=\"summary-title\">Methods</div>")
-			b.WriteString(mt)
-		}
-		b.WriteString("</div>")
+	b.WriteString("<h2>Chain-tip freshness</h2>")
+	b.WriteString("<table class=\"table\"><thead><tr>")
+	for _, h := range []string{"Config", "Server", "Polls", "Errors", "Last seqno", "Avg lag", "Max lag", "Avail P50", "Avail P95", "Avail max"} {
+		b.WriteString("<th>" + h + "</th>")
 	}
-	b.WriteString("</div>")
+	b.WriteString("</tr></thead><tbody>")
+	for _, s := range freshness {
+		b.WriteString("<tr class=\"item\">")
+		b.WriteString("<td>" + htmlEsc(s.Config) + "</td>")
+		b.WriteString("<td>" + htmlEsc(s.Host) + "</td>")
+		b.WriteString("<td>" + strconv.Itoa(s.Polls) + "</td>")
+		b.WriteString("<td>" + strconv.Itoa(s.Errors) + "</td>")
+		b.WriteString("<td>" + strconv.FormatUint(uint64(s.LastSeqno), 10) + "</td>")
+		b.WriteString("<td>" + fmt.Sprintf("%.2f", s.AvgLag) + "</td>")
+		b.WriteString("<td>" + strconv.Itoa(s.MaxLag) + "</td>")
+		b.WriteString("<td>" + fmt.Sprintf("%.0f ms", s.AvailP50Ms) + "</td>")
+		b.WriteString("<td>" + fmt.Sprintf("%.0f ms", s.AvailP95Ms) + "</td>")
+		b.WriteString("<td>" + fmt.Sprintf("%.0f ms", s.AvailMaxMs) + "</td>")
+		b.WriteString("</tr>")
+	}
+	b.WriteString("</tbody></table>")
+	b.WriteString("<div class=\"chart-block\"><div class=\"chart-title\">Lag behind the highest seen seqno (blocks) and total load (RPS)</div>")
+	b.WriteString("<div class=\"freshness-root\"></div></div>")
 	b.WriteString("</section>")
 	return b.String()
 }
 
+func formatExitCodes(codes map[string]int) string {
+	if len(codes) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(codes))
+	for k := range codes {
+		keys = append(keys, k)
+	}
+	// numeric exit codes in order, then named answers such as account_not_found
+	sort.Slice(keys, func(i, j int) bool {
+		a, errA := strconv.Atoi(keys[i])
+		b, errB := strconv.Atoi(keys[j])
+		if (errA == nil) != (errB == nil) {
+			return errA == nil
+		}
+		if errA == nil && a != b {
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+":"+strconv.Itoa(codes[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
 func buildErrorsSection(errorsSummary []errorSummaryEntry, configs []string) string {
 	if len(configs) == 0 {
 		return ""
@@ -437,6 +1254,58 @@ func buildErrorsSection(errorsSummary []errorSummaryEntry, configs []string) str
 	return b.String()
 }
 
+// buildErrorSamplesSection lists the kept error samples of each config, one collapsible table per config.
+func buildErrorSamplesSection(samples []errorSample, configs []string) string {
+	if len(samples) == 0 {
+		return ""
+	}
+	byConfig := map[string][]errorSample{}
+	for _, e := range samples {
+		byConfig[e.Config] = append(byConfig[e.Config], e)
+	}
+	var b strings.Builder
+	b.WriteString("<section class=\"section\">")
+	b.WriteString("<h2>Error samples</h2>")
+	for _, cfg := range configs {
+		list := byConfig[cfg]
+		if len(list) == 0 {
+			continue
+		}
+		b.WriteString("<details class=\"card\">")
+		b.WriteString("<summary class=\"summary-title\">" + htmlEsc(cfg) + " · " + strconv.Itoa(len(list)) + " samples</summary>")
+		b.WriteString("<table class=\"table\"><thead><tr>")
+		for _, h := range []string{"Class", "Time", "Mode", "Conc", "Request", "LS code", "Params", "Latency", "Error"} {
+			b.WriteString("<th>" + h + "</th>")
+		}
+		b.WriteString("</tr></thead><tbody>")
+		for _, e := range list {
+			code := ""
+			if e.LSCode != nil {
+				code = strconv.Itoa(int(*e.LSCode))
+			}
+			ts := e.Ts
+			if t, err := time.Parse(time.RFC3339Nano, e.Ts); err == nil {
+				ts = t.Format("15:04:05.000")
+			}
+			b.WriteString("<tr class=\"item\">")
+			b.WriteString("<td><span class=\"badge\">" + htmlEsc(e.Class) + "</span></td>")
+			b.WriteString("<td>" + htmlEsc(ts) + "</td>")
+			b.WriteString("<td>" + htmlEsc(e.Mode) + "</td>")
+			b.WriteString("<td>" + strconv.Itoa(e.Concurrency) + "</td>")
+			b.WriteString("<td>" + htmlEsc(e.Request) + "</td>")
+			b.WriteString("<td>" + code + "</td>")
+			b.WriteString("<td>" + htmlEsc(formatSampleParams(e.Params)) + "</td>")
+			b.WriteString("<td>" + strconv.FormatInt(e.LatencyMs, 10) + " ms</td>")
+			b.WriteString("<td>" + htmlEsc(e.Error) + "</td>")
+			b.WriteString("</tr>")
+		}
+		b.WriteString("</tbody></table>")
+		b.WriteString("</details>")
+	}
+	b.WriteString("</section>")
+	return b.String()
+}
+
 func buildChartsSection(configs []string) string {
 	if len(configs) == 0 {
 		return ""
@@ -456,9 +1325,19 @@ func buildChartsSection(configs []string) string {
 	return b.String()
 }
 
-func buildSanityBlock(results []Result) string {
+// sanityGroup is the results of one mode and concurrency across configs, compared to the best of them.
+type sanityGroup struct {
+	Mode    string
+	Conc    int
+	Results []Result
+	BestRPS float64
+	BestAvg float64
+}
+
+// sanityGroups groups results run with the same mode and concurrency on more than one config.
+func sanityGroups(results []Result) []sanityGroup {
 	if len(results) < 2 {
-		return ""
+		return nil
 	}
 	type key struct {
 		Mode string
@@ -479,39 +1358,47 @@ func buildSanityBlock(results []Result) string {
 		return keys[i].Conc < keys[j].Conc
 	})
 
-	var b strings.Builder
-	b.WriteString("<section class=\"section sanity\">")
-	b.WriteString("<h2>Sanity check</h2>")
-	b.WriteString("<div class=\"hint\">Compare RPS and latency across configs for identical mode/concurrency.</div>")
+	var out []sanityGroup
 	for _, k := range keys {
 		list := group[k]
 		if len(list) < 2 {
 			continue
 		}
 		sort.Slice(list, func(i, j int) bool { return list[i].Config < list[j].Config })
-
-		bestRPS := list[0].RPS
-		bestAvg := list[0].AvgMs
+		g := sanityGroup{Mode: k.Mode, Conc: k.Conc, Results: list, BestRPS: list[0].RPS, BestAvg: list[0].AvgMs}
 		for _, r := range list[1:] {
-			if r.RPS > bestRPS {
-				bestRPS = r.RPS
+			if r.RPS > g.BestRPS {
+				g.BestRPS = r.RPS
 			}
-			if r.AvgMs < bestAvg {
-				bestAvg = r.AvgMs
+			if r.AvgMs < g.BestAvg {
+				g.BestAvg = r.AvgMs
 			}
 		}
+		out = append(out, g)
+	}
+	return out
+}
 
+func buildSanityBlock(results []Result) string {
+	if len(results) < 2 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("<section class=\"section sanity\">")
+	b.WriteString("<h2>Sanity check</h2>")
+	b.WriteString("<div class=\"hint\">Compare RPS and latency across configs for identical mode/concurrency.</div>")
+	for _, g := range sanityGroups(results) {
 		b.WriteString("<div class=\"card\">")
-		b.WriteString("<div class=\"summary-title\">" + htmlEsc(k.Mode) + " · concurrency " + strconv.Itoa(k.Conc) + "</div>")
+		b.WriteString("<div class=\"summary-title\">" + htmlEsc(g.Mode) + " · concurrency " + strconv.Itoa(g.Conc) + "</div>")
 		b.WriteString("<table class=\"table\"><thead><tr>")
 		headers := []string{"Config", "RPS", "Avg ms", "P50", "ΔRPS vs best", "ΔAvg vs best"}
 		for _, h := range headers {
 			b.WriteString("<th>" + h + "</th>")
 		}
 		b.WriteString("</tr></thead><tbody>")
-		for _, r := range list {
-			dRPS := percentDelta(r.RPS, bestRPS)
-			dAvg := percentDelta(r.AvgMs, bestAvg)
+		for _, r := range g.Results {
+			dRPS := percentDelta(r.RPS, g.BestRPS)
+			dAvg := percentDelta(r.AvgMs, g.BestAvg)
 			b.WriteString("<tr class=\"item\">")
 			b.WriteString("<td>" + htmlEsc(r.Config) + "</td>")
 			b.WriteString("<td>" + fmt.Sprintf("%.2f", r.RPS) + "</td>")
@@ -547,7 +1434,7 @@ func deltaBadge(v float64) string {
 }
 
 func buildSummaryTable(results []Result) string {
-	headers := []string{"Mode", "Conc", "Total", "OK", "Err", "Duration ms", "RPS", "Avg ms", "P50", "P90", "P95", "P99", "Max"}
+	headers := []string{"Mode", "Conc", "Total", "OK", "Err", "Duration ms", "RPS", "MB/s", "Avg KB", "P95 KB", "Avg ms", "P50", "P90", "P95", "P99", "Max"}
 	var b strings.Builder
 	b.WriteString("<table class=\"table\">\n")
 	b.WriteString("<thead><tr>")
@@ -566,6 +1453,9 @@ func buildSummaryTable(results []Result) string {
 		b.WriteString("<td>" + strconv.Itoa(r.Errors) + "</td>")
 		b.WriteString("<td>" + strconv.FormatInt(r.Duration.Milliseconds(), 10) + "</td>")
 		b.WriteString("<td>" + fmt.Sprintf("%.2f", r.RPS) + "</td>")
+		b.WriteString("<td>" + fmt.Sprintf("%.2f", r.MBps) + "</td>")
+		b.WriteString("<td>" + fmt.Sprintf("%.1f", r.PayloadAvgBytes/1024) + "</td>")
+		b.WriteString("<td>" + fmt.Sprintf("%.1f", r.PayloadP95Bytes/1024) + "</td>")
 		b.WriteString("<td>" + fmt.Sprintf("%.1f", r.AvgMs) + "</td>")
 		b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P50Ms) + "</td>")
 		b.WriteString("<td>" + fmt.Sprintf("%.1f", r.P90Ms) + "</td>")
@@ -679,6 +1569,8 @@ func downsampleResults(results []Result, maxPoints int) []Result {
 		out[i].SeriesP90 = sampleFloats(r.SeriesP90, idxs)
 		out[i].SeriesP95 = sampleFloats(r.SeriesP95, idxs)
 		out[i].SeriesP99 = sampleFloats(r.SeriesP99, idxs)
+		out[i].SeriesMBps = sampleFloats(r.SeriesMBps, idxs)
+		out[i].SeriesTarget = sampleFloats(r.SeriesTarget, idxs)
 	}
 	return out
 }
@@ -880,15 +1772,80 @@ func flattenMethodSeries(methods map[methodKey]methodSeries) []methodSeriesEntry
 	return out
 }
 
+// Liteserver error codes, as in ton/ton-types.h ErrorCode. error and warning are plain ls_error answers.
+const (
+	lsCodeError          = 601
+	lsCodeWarning        = 602
+	lsCodeProtoViolation = 621
+	lsCodeNotReady       = 651
+	lsCodeTimeout        = 652
+	lsCodeCancelled      = 653
+)
+
+// liteServerError extracts the code and message of a liteclient.LiteServerErrorC from an error text.
+func liteServerError(s string) (code int32, msg string, ok bool) {
+	i := strings.Index(s, "error code: ")
+	if i < 0 {
+		return 0, "", false
+	}
+	rest := s[i+len("error code: "):]
+	num, msg, ok := strings.Cut(rest, " message: ")
+	if !ok {
+		return 0, "", false
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return int32(n), msg, true
+}
+
+// classifyError buckets an error by its text, liteserver errors by message and then by code.
 func classifyError(s string) string {
 	v := strings.ToLower(strings.TrimSpace(s))
-	switch {
-	case v == "":
+	if v == "" {
 		return "unknown"
+	}
+	code, msg, isLS := liteServerError(v)
+	if !isLS {
+		msg = v
+	}
+	switch {
+	case strings.Contains(msg, "not applied"):
+		return "block_not_applied"
+	case strings.Contains(msg, "account not found"):
+		return "account_not_found"
+	case strings.Contains(msg, "not found"):
+		return "not_found"
+	case strings.Contains(msg, "unknown query"):
+		return "unknown_query"
+	case strings.Contains(msg, "proof"):
+		return "proof"
+	case strings.Contains(msg, "load generator saturated"):
+		return "saturated"
+	case strings.Contains(msg, "too many") || strings.Contains(msg, "rate limit") || strings.Contains(msg, "ratelimit") || strings.Contains(msg, "overload"):
+		return "rate_limited"
+	}
+	if isLS {
+		switch code {
+		case lsCodeNotReady:
+			return "ls_notready"
+		case lsCodeTimeout:
+			return "ls_timeout"
+		case lsCodeCancelled:
+			return "ls_cancelled"
+		case lsCodeProtoViolation:
+			return "ls_protoviolation"
+		}
+		return "ls_error"
+	}
+	switch {
+	case strings.Contains(v, "no connections available"):
+		return "no_connections"
 	case strings.Contains(v, "context deadline exceeded") || strings.Contains(v, "timeout"):
 		return "timeout"
-	case strings.Contains(v, "unknown query"):
-		return "unknown_query"
+	case strings.Contains(v, "connection refused"):
+		return "conn_refused"
 	case strings.Contains(v, "connection reset"):
 		return "conn_reset"
 	case strings.Contains(v, "broken pipe"):
@@ -897,75 +1854,27 @@ func classifyError(s string) string {
 		return "eof"
 	case strings.Contains(v, "canceled"):
 		return "canceled"
-	case strings.Contains(v, "not found"):
-		return "not_found"
 	default:
 		return "other"
 	}
 }
 
-func buildErrorSummaryFromLog(path string) ([]errorSummaryEntry, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	counts := map[errorKey]int{}
-	scanner := bufio.NewScanner(f)
-	buf := make([]byte, 0, 1024*1024)
-	scanner.Buffer(buf, 10*1024*1024)
-	for scanner.Scan() {
-		var e logEntry
-		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
-			continue
-		}
-		if e.OK {
-			continue
-		}
-		code := classifyError(e.Error)
-		key := errorKey{
-			Config:      e.Config,
-			Mode:        e.Mode,
-			Concurrency: e.Concurrency,
-			Request:     e.Request,
-			Code:        code,
-			Error:       e.Error,
-		}
-		counts[key]++
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
+// isNotFound reports whether an error class is a "no such object" answer rather than a failure of the server.
+func isNotFound(class string) bool {
+	switch class {
+	case "not_found", "account_not_found", "block_not_applied":
+		return true
 	}
+	return false
+}
 
-	out := make([]errorSummaryEntry, 0, len(counts))
-	for k, v := range counts {
-		out = append(out, errorSummaryEntry{
-			Config:      k.Config,
-			Mode:        k.Mode,
-			Concurrency: k.Concurrency,
-			Request:     k.Request,
-			Code:        k.Code,
-			Error:       k.Error,
-			Count:       v,
-		})
+// isBlockUnavailable reports error classes meaning the server does not have the block (pruned or not applied).
+func isBlockUnavailable(class string) bool {
+	switch class {
+	case "not_found", "block_not_applied", "ls_notready":
+		return true
 	}
-	sort.Slice(out, func(i, j int) bool {
-		if out[i].Count != out[j].Count {
-			return out[i].Count > out[j].Count
-		}
-		if out[i].Config != out[j].Config {
-			return out[i].Config < out[j].Config
-		}
-		if out[i].Mode != out[j].Mode {
-			return out[i].Mode < out[j].Mode
-		}
-		if out[i].Concurrency != out[j].Concurrency {
-			return out[i].Concurrency < out[j].Concurrency
-		}
-		return out[i].Request < out[j].Request
-	})
-	return out, nil
+	return false
 }
 
 func flattenErrorSeries(errors map[errorSeriesKey]errorSeries) []errorSeriesEntry {
@@ -998,239 +1907,3 @@ func flattenErrorSeries(errors map[errorSeriesKey]errorSeries) []errorSeriesEntr
 	})
 	return out
 }
-
-func buildErrorSeriesFromLog(path string) (map[errorSeriesKey]errorSeries, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	type bounds struct {
-		min time.Time
-		max time.Time
-	}
-	boundsMap := map[errorSeriesKey]bounds{}
-
-	scanner := bufio.NewScanner(f)
-	buf := make([]byte, 0, 1024*1024)
-	scanner.Buffer(buf, 10*1024*1024)
-	for scanner.Scan() {
-		var e logEntry
-		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
-			continue
-		}
-		if e.OK {
-			continue
-		}
-		t, err := time.Parse(time.RFC3339Nano, e.Ts)
-		if err != nil {
-			continue
-		}
-		code := classifyError(e.Error)
-		key := errorSeriesKey{Config: e.Config, Mode: e.Mode, Concurrency: e.Concurrency, Code: code}
-		if b, ok := boundsMap[key]; ok {
-			if t.Before(b.min) {
-				b.min = t
-			}
-			if t.After(b.max) {
-				b.max = t
-			}
-			boundsMap[key] = b
-		} else {
-			boundsMap[key] = bounds{min: t, max: t}
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	_, _ = f.Seek(0, 0)
-	type agg struct {
-		start time.Time
-		cnt   []int64
-	}
-	aggs := map[errorSeriesKey]*agg{}
-	for k, b := range boundsMap {
-		secs := int(b.max.Sub(b.min).Seconds()) + 1
-		if secs < 1 {
-			secs = 1
-		}
-		aggs[k] = &agg{
-			start: b.min,
-			cnt:   make([]int64, secs),
-		}
-	}
-
-	scanner = bufio.NewScanner(f)
-	scanner.Buffer(buf, 10*1024*1024)
-	for scanner.Scan() {
-		var e logEntry
-		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
-			continue
-		}
-		if e.OK {
-			continue
-		}
-		t, err := time.Parse(time.RFC3339Nano, e.Ts)
-		if err != nil {
-			continue
-		}
-		code := classifyError(e.Error)
-		key := errorSeriesKey{Config: e.Config, Mode: e.Mode, Concurrency: e.Concurrency, Code: code}
-		a := aggs[key]
-		if a == nil {
-			continue
-		}
-		sec := int(t.Sub(a.start).Seconds())
-		if sec < 0 {
-			sec = 0
-		}
-		if sec >= len(a.cnt) {
-			sec = len(a.cnt) - 1
-		}
-		a.cnt[sec]++
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	out := map[errorSeriesKey]errorSeries{}
-	for k, a := range aggs {
-		series := errorSeries{
-			Start: a.start.UTC().UnixMilli(),
-			Sec:   make([]int, len(a.cnt)),
-			Cnt:   make([]float64, len(a.cnt)),
-		}
-		for i := 0; i < len(a.cnt); i++ {
-			series.Sec[i] = i + 1
-			series.Cnt[i] = float64(a.cnt[i])
-		}
-		out[k] = series
-	}
-	return out, nil
-}
-
-func buildMethodSeriesFromLog(path string) (map[methodKey]methodSeries, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-
-	type bounds struct {
-		min time.Time
-		max time.Time
-	}
-	boundsMap := map[methodKey]bounds{}
-
-	scanner := bufio.NewScanner(f)
-	buf := make([]byte, 0, 1024*1024)
-	scanner.Buffer(buf, 10*1024*1024)
-	for scanner.Scan() {
-		var e logEntry
-		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
-			continue
-		}
-		t, err := time.Parse(time.RFC3339Nano, e.Ts)
-		if err != nil {
-			continue
-		}
-		key := methodKey{Config: e.Config, Mode: e.Mode, Concurrency: e.Concurrency, Method: e.Request}
-		if b, ok := boundsMap[key]; ok {
-			if t.Before(b.min) {
-				b.min = t
-			}
-			if t.After(b.max) {
-				b.max = t
-			}
-			boundsMap[key] = b
-		} else {
-			boundsMap[key] = bounds{min: t, max: t}
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	_, _ = f.Seek(0, 0)
-	type agg struct {
-		start time.Time
-		per   [][]int64
-		ok    []int64
-		err   []int64
-		total int
-	}
-	aggs := map[methodKey]*agg{}
-
-	for k, b := range boundsMap {
-		secs := int(b.max.Sub(b.min).Seconds()) + 1
-		if secs < 1 {
-			secs = 1
-		}
-		aggs[k] = &agg{
-			start: b.min,
-			per:   make([][]int64, secs),
-			ok:    make([]int64, secs),
-			err:   make([]int64, secs),
-		}
-	}
-
-	scanner = bufio.NewScanner(f)
-	scanner.Buffer(buf, 10*1024*1024)
-	for scanner.Scan() {
-		var e logEntry
-		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
-			continue
-		}
-		t, err := time.Parse(time.RFC3339Nano, e.Ts)
-		if err != nil {
-			continue
-		}
-		key := methodKey{Config: e.Config, Mode: e.Mode, Concurrency: e.Concurrency, Method: e.Request}
-		a := aggs[key]
-		if a == nil {
-			continue
-		}
-		sec := int(t.Sub(a.start).Seconds())
-		if sec < 0 {
-			sec = 0
-		}
-		if sec >= len(a.ok) {
-			sec = len(a.ok) - 1
-		}
-		a.total++
-		if e.OK {
-			a.ok[sec]++
-			a.per[sec] = append(a.per[sec], e.LatencyMs)
-		} else {
-			a.err[sec]++
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-
-	out := map[methodKey]methodSeries{}
-	for k, a := range aggs {
-		p50, p90, p95, p99 := percentileSeries(a.per)
-		series := methodSeries{
-			Sec:   make([]int, len(a.ok)),
-			P50:   p50,
-			P90:   p90,
-			P95:   p95,
-			P99:   p99,
-			OK:    make([]float64, len(a.ok)),
-			Err:   make([]float64, len(a.ok)),
-			Total: a.total,
-			Start: a.start.UTC().UnixMilli(),
-		}
-		for i := 0; i < len(a.ok); i++ {
-			series.Sec[i] = i + 1
-			series.OK[i] = float64(a.ok[i])
-			series.Err[i] = float64(a.err[i])
-		}
-		out[k] = series
-	}
-	return out, nil
-}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/tonkeeper/tongo/liteclient"
+)
+
+func TestLiteServerError(t *testing.T) {
+	tests := []struct {
+		in   string
+		code int32
+		msg  string
+		ok   bool
+	}{
+		{"error code: 651 message: block is not applied", 651, "block is not applied", true},
+		{"getblock: error code: 652 message: timeout", 652, "timeout", true},
+		{"error code: 4294967295 message: x", -1, "x", true},
+		{"error code: 621 message: ", 621, "", true},
+		{"error code: abc message: x", 0, "", false},
+		{"error code: 651", 0, "", false},
+		{"connection refused", 0, "", false},
+	}
+	for _, tt := range tests {
+		code, msg, ok := liteServerError(tt.in)
+		if code != tt.code || msg != tt.msg || ok != tt.ok {
+			t.Errorf("liteServerError(%q) = %d, %q, %v, want %d, %q, %v", tt.in, code, msg, ok, tt.code, tt.msg, tt.ok)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	ls := func(code int32, msg string) string {
+		return fmt.Sprintf("wrapped: %v", liteclient.LiteServerErrorC{Code: uint32(code), Message: msg})
+	}
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "unknown"},
+		{ls(lsCodeProtoViolation, "bad query"), "ls_protoviolation"},
+		{ls(lsCodeNotReady, "state already gc'd"), "ls_notready"},
+		{ls(lsCodeNotReady, "block is not applied"), "block_not_applied"},
+		{ls(lsCodeTimeout, "query timed out"), "ls_timeout"},
+		{ls(lsCodeCancelled, "cancelled"), "ls_cancelled"},
+		{ls(lsCodeError, "account not found"), "account_not_found"},
+		{ls(lsCodeError, "block not found"), "not_found"},
+		{ls(lsCodeError, "unknown query"), "unknown_query"},
+		{ls(lsCodeError, "too many requests"), "rate_limited"},
+		{ls(lsCodeError, "something else"), "ls_error"},
+		{"bad merkle proof", "proof"},
+		{"load generator saturated", "saturated"},
+		{"rate limit exceeded", "rate_limited"},
+		{"pool: no connections available", "no_connections"},
+		{"context deadline exceeded", "timeout"},
+		{"dial tcp: connection refused", "conn_refused"},
+		{"read: connection reset by peer", "conn_reset"},
+		{"write: broken pipe", "broken_pipe"},
+		{"unexpected EOF", "eof"},
+		{"context canceled", "canceled"},
+		{"whatever", "other"},
+	}
+	for _, tt := range tests {
+		if got := classifyError(tt.in); got != tt.want {
+			t.Errorf("classifyError(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNotFoundClasses(t *testing.T) {
+	for _, class := range []string{"not_found", "account_not_found", "block_not_applied"} {
+		if !isNotFound(class) {
+			t.Errorf("isNotFound(%q) = false", class)
+		}
+	}
+	for _, class := range []string{"not_found", "block_not_applied", "ls_notready"} {
+		if !isBlockUnavailable(class) {
+			t.Errorf("isBlockUnavailable(%q) = false", class)
+		}
+	}
+	for _, class := range []string{"timeout", "ls_timeout", "other"} {
+		if isNotFound(class) || isBlockUnavailable(class) {
+			t.Errorf("%q counted as not found", class)
+		}
+	}
+}
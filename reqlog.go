@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// requestLogCompressions are the --request-log-compress values, by the extension they add.
+var requestLogCompressions = map[string]string{
+	"none": "",
+	"gzip": ".gz",
+	"zstd": ".zst",
+}
+
+// requestLogFormats are the --request-log-format values, by the extension of the log.
+var requestLogFormats = map[string]string{
+	"jsonl":  ".jsonl",
+	"binary": ".bin",
+}
+
+// requestLogName is the name of the auto request log for a format and compression.
+func requestLogName(format, compress string) string {
+	return "requests" + requestLogFormats[format] + requestLogCompressions[compress]
+}
+
+// findRequestLog is the auto request log of a results dir, in whichever format and compression.
+func findRequestLog(dir string) string {
+	for _, f := range []string{"jsonl", "binary"} {
+		for _, c := range []string{"none", "gzip", "zstd"} {
+			path := filepath.Join(dir, requestLogName(f, c))
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return filepath.Join(dir, requestLogName("jsonl", "none"))
+}
+
+// isBinaryLog reports whether path ends in .bin, optionally followed by a compression extension.
+func isBinaryLog(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz", ".zst", ".zstd":
+		path = strings.TrimSuffix(path, filepath.Ext(path))
+	}
+	return strings.EqualFold(filepath.Ext(path), ".bin")
+}
+
+// logEncoder writes request log entries in one of the log formats.
+type logEncoder interface {
+	encode(e logEntry) error
+	// flush writes out entries the encoder still holds; the underlying writer is flushed by the caller.
+	flush() error
+}
+
+func newLogEncoder(w io.Writer, path string) (logEncoder, error) {
+	if isBinaryLog(path) {
+		return newBinLogWriter(w)
+	}
+	return jsonLogEncoder{json.NewEncoder(w)}, nil
+}
+
+type jsonLogEncoder struct{ enc *json.Encoder }
+
+func (j jsonLogEncoder) encode(e logEntry) error {
+	if e.Ts == "" && !e.t.IsZero() {
+		e.Ts = e.t.UTC().Format(time.RFC3339Nano)
+	}
+	return j.enc.Encode(e)
+}
+
+func (jsonLogEncoder) flush() error { return nil }
+
+// newLogCompressor wraps w for .gz or .zst; an append adds a new gzip member or zstd frame.
+func newLogCompressor(w io.Writer, path string) (io.WriteCloser, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz":
+		return gzip.NewWriter(w), nil
+	case ".zst", ".zstd":
+		return zstd.NewWriter(w)
+	}
+	return nopWriteCloser{w}, nil
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// openLogReader opens a request log, decompressing gzip or zstd by its magic bytes.
+func openLogReader(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReaderSize(f, 1<<20)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return readCloser{zr, f}, nil
+	case bytes.HasPrefix(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return readCloser{zr.IOReadCloser(), f}, nil
+	}
+	return readCloser{io.NopCloser(br), f}, nil
+}
+
+type readCloser struct {
+	io.ReadCloser
+	f *os.File
+}
+
+func (r readCloser) Close() error {
+	r.ReadCloser.Close()
+	return r.f.Close()
+}
+
+// readLogEntries calls fn for every entry of a request log; a tail cut short by a crash is skipped.
+func readLogEntries(path string, fn func(e logEntry)) error {
+	r, err := openLogReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	br := bufio.NewReaderSize(r, 1<<20)
+	if magic, _ := br.Peek(len(binLogMagic)); bytes.Equal(magic, binLogMagic) {
+		if err := readBinLog(br, fn); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+		return nil
+	}
+	scanner := bufio.NewScanner(br)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 10*1024*1024)
+	for scanner.Scan() {
+		var e logEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		e.t, _ = time.Parse(time.RFC3339Nano, e.Ts)
+		fn(e)
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	return nil
+}
+
+// secSeries counts requests per second, growing both ways since entries arrive slightly out of order.
+type secSeries struct {
+	base    int64 // unix second of index 0
+	per     [][]int64
+	ok      []int64
+	err     []int64
+	total   int
+	latency bool
+}
+
+func (s *secSeries) slot(t time.Time) int {
+	sec := t.Unix()
+	if len(s.ok) == 0 {
+		s.base = sec
+	}
+	if sec < s.base {
+		n := int(s.base - sec)
+		s.ok = append(make([]int64, n), s.ok...)
+		s.err = append(make([]int64, n), s.err...)
+		if s.latency {
+			s.per = append(make([][]int64, n), s.per...)
+		}
+		s.base = sec
+	}
+	i := int(sec - s.base)
+	for len(s.ok) <= i {
+		s.ok = append(s.ok, 0)
+		s.err = append(s.err, 0)
+		if s.latency {
+			s.per = append(s.per, nil)
+		}
+	}
+	return i
+}
+
+func (s *secSeries) add(t time.Time, ok bool, latencyMs int64) {
+	i := s.slot(t)
+	s.total++
+	if !ok {
+		s.err[i]++
+		return
+	}
+	s.ok[i]++
+	if s.latency {
+		s.per[i] = append(s.per[i], latencyMs)
+	}
+}
+
+func (s *secSeries) seconds() []int {
+	sec := make([]int, len(s.ok))
+	for i := range sec {
+		sec[i] = i + 1
+	}
+	return sec
+}
+
+// logAggregate is everything the report takes from the request log, built in a single pass.
+type logAggregate struct {
+	methods     map[methodKey]*secSeries
+	errorSeries map[errorSeriesKey]*secSeries
+	errorCounts map[errorKey]int
+}
+
+func aggregateRequestLog(path string) (*logAggregate, error) {
+	a := &logAggregate{
+		methods:     map[methodKey]*secSeries{},
+		errorSeries: map[errorSeriesKey]*secSeries{},
+		errorCounts: map[errorKey]int{},
+	}
+	err := readLogEntries(path, func(e logEntry) {
+		var code string
+		if !e.OK {
+			code = classifyError(e.Error)
+			a.errorCounts[errorKey{e.Config, e.Mode, e.Concurrency, e.Request, code, e.Error}]++
+		}
+		t := e.t
+		if t.IsZero() {
+			return
+		}
+		mk := methodKey{Config: e.Config, Mode: e.Mode, Concurrency: e.Concurrency, Method: e.Request}
+		m := a.methods[mk]
+		if m == nil {
+			m = &secSeries{latency: true}
+			a.methods[mk] = m
+		}
+		m.add(t, e.OK, e.LatencyMs)
+		if !e.OK {
+			ek := errorSeriesKey{Config: e.Config, Mode: e.Mode, Concurrency: e.Concurrency, Code: code}
+			s := a.errorSeries[ek]
+			if s == nil {
+				s = &secSeries{}
+				a.errorSeries[ek] = s
+			}
+			s.add(t, false, 0)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *logAggregate) methodSeries() map[methodKey]methodSeries {
+	out := make(map[methodKey]methodSeries, len(a.methods))
+	for k, s := range a.methods {
+		p50, p90, p95, p99 := percentileSeries(s.per)
+		out[k] = methodSeries{
+			Sec:   s.seconds(),
+			P50:   p50,
+			P90:   p90,
+			P95:   p95,
+			P99:   p99,
+			OK:    countsToFloat64(s.ok),
+			Err:   countsToFloat64(s.err),
+			Total: s.total,
+			Start: s.base * 1000,
+		}
+	}
+	return out
+}
+
+func (a *logAggregate) errorSummary() []errorSummaryEntry {
+	out := make([]errorSummaryEntry, 0, len(a.errorCounts))
+	for k, v := range a.errorCounts {
+		out = append(out, errorSummaryEntry{
+			Config:      k.Config,
+			Mode:        k.Mode,
+			Concurrency: k.Concurrency,
+			Request:     k.Request,
+			Code:        k.Code,
+			Error:       k.Error,
+			Count:       v,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		if out[i].Config != out[j].Config {
+			return out[i].Config < out[j].Config
+		}
+		if out[i].Mode != out[j].Mode {
+			return out[i].Mode < out[j].Mode
+		}
+		if out[i].Concurrency != out[j].Concurrency {
+			return out[i].Concurrency < out[j].Concurrency
+		}
+		return out[i].Request < out[j].Request
+	})
+	return out
+}
+
+func (a *logAggregate) errorSeriesMap() map[errorSeriesKey]errorSeries {
+	out := make(map[errorSeriesKey]errorSeries, len(a.errorSeries))
+	for k, s := range a.errorSeries {
+		out[k] = errorSeries{
+			Start: s.base * 1000,
+			Sec:   s.seconds(),
+			Cnt:   countsToFloat64(s.err),
+		}
+	}
+	return out
+}
+
+// runConvertLog is `ls-load convert-log IN [OUT]`: it rewrites a request log as JSONL (OUT "-" is stdout).
+func runConvertLog(args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		exitf("usage: ls-load convert-log IN [OUT]")
+	}
+	in := args[0]
+	out := ""
+	if len(args) == 2 {
+		out = args[1]
+	} else {
+		base, comp := in, ""
+		switch strings.ToLower(filepath.Ext(in)) {
+		case ".gz", ".zst", ".zstd":
+			comp = filepath.Ext(in)
+			base = strings.TrimSuffix(in, comp)
+		}
+		out = strings.TrimSuffix(base, filepath.Ext(base)) + ".jsonl" + comp
+		if out == in {
+			exitf("convert-log: %s is already JSONL, give an output path", in)
+		}
+	}
+	n, err := convertLog(in, out)
+	if err != nil {
+		exitf("convert-log: %v", err)
+	}
+	if out != "-" {
+		fmt.Printf("Converted %d entries to: %s\n", n, out)
+	}
+}
+
+func convertLog(in, out string) (int, error) {
+	var dst io.WriteCloser = nopWriteCloser{os.Stdout}
+	if out != "-" {
+		f, err := os.Create(out)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		dst = f
+	}
+	z, err := newLogCompressor(dst, out)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriterSize(z, 1<<20)
+	enc := jsonLogEncoder{json.NewEncoder(w)}
+	n := 0
+	var werr error
+	err = readLogEntries(in, func(e logEntry) {
+		if werr == nil {
+			werr = enc.encode(e)
+			n++
+		}
+	})
+	if err == nil {
+		err = werr
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = z.Close()
+	}
+	if err == nil {
+		err = dst.Close()
+	}
+	return n, err
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIsBinaryLog(t *testing.T) {
+	for path, want := range map[string]bool{
+		"requests.jsonl":     false,
+		"requests.jsonl.gz":  false,
+		"requests.bin":       true,
+		"requests.BIN.zst":   true,
+		"requests.bin.zstd":  true,
+		"requests.bin.gz":    true,
+		"dir.bin/requests":   false,
+		"requests.gz":        false,
+		"requests.bin.other": false,
+	} {
+		if got := isBinaryLog(path); got != want {
+			t.Errorf("isBinaryLog(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestAggregateRequestLog(t *testing.T) {
+	t0 := time.Unix(1700000000, 0)
+	entries := []logEntry{
+		{t: t0.Add(1500 * time.Millisecond), Config: "a", Mode: "blocks", Concurrency: 2, Request: "GetBlock", OK: true, LatencyMs: 30},
+		// stamped at the start, so an earlier second can arrive later
+		{t: t0, Config: "a", Mode: "blocks", Concurrency: 2, Request: "GetBlock", OK: true, LatencyMs: 10},
+		{t: t0.Add(200 * time.Millisecond), Config: "a", Mode: "blocks", Concurrency: 2, Request: "GetBlock", Error: "context deadline exceeded"},
+		{t: t0.Add(300 * time.Millisecond), Config: "a", Mode: "blocks", Concurrency: 2, Request: "GetBlock", Error: "context deadline exceeded"},
+		{t: t0.Add(2 * time.Second), Config: "a", Mode: "blocks", Concurrency: 2, Request: "GetBlockHeader", OK: true, LatencyMs: 5},
+	}
+	getBlock := methodKey{Config: "a", Mode: "blocks", Concurrency: 2, Method: "GetBlock"}
+	dir := t.TempDir()
+	for _, format := range []string{"jsonl", "binary"} {
+		for _, compress := range []string{"none", "gzip", "zstd"} {
+			name := requestLogName(format, compress)
+			written := filepath.Join(dir, name)
+			writeTestLog(t, written, entries)
+			// the format and compression are detected from the content, not the name
+			path := filepath.Join(dir, fmt.Sprintf("%s-%s.log", format, compress))
+			if err := os.Rename(written, path); err != nil {
+				t.Fatal(err)
+			}
+
+			agg, err := aggregateRequestLog(path)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			ms := agg.methodSeries()
+			if len(ms) != 2 {
+				t.Fatalf("%s: %d method series, want 2", name, len(ms))
+			}
+			m := ms[getBlock]
+			if m.Total != 4 || m.Start != t0.Unix()*1000 {
+				t.Fatalf("%s: GetBlock total %d from %d", name, m.Total, m.Start)
+			}
+			if len(m.OK) != 2 || m.OK[0] != 1 || m.OK[1] != 1 || m.Err[0] != 2 {
+				t.Fatalf("%s: GetBlock ok %v err %v", name, m.OK, m.Err)
+			}
+			if m.P50[0] != 10 || m.P50[1] != 30 {
+				t.Fatalf("%s: GetBlock p50 %v", name, m.P50)
+			}
+			es := agg.errorSummary()
+			if len(es) != 1 || es[0].Count != 2 || es[0].Code != "timeout" {
+				t.Fatalf("%s: errors %+v", name, es)
+			}
+			series := agg.errorSeriesMap()[errorSeriesKey{Config: "a", Mode: "blocks", Concurrency: 2, Code: "timeout"}]
+			if len(series.Cnt) != 1 || series.Cnt[0] != 2 {
+				t.Fatalf("%s: error series %+v", name, series)
+			}
+		}
+	}
+
+	if _, err := aggregateRequestLog(filepath.Join(dir, "missing.jsonl")); err == nil {
+		t.Fatal("missing log aggregated")
+	}
+}
+
+func TestAggregateRequestLogTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "requests.jsonl")
+	line := `{"ts":"2024-01-01T00:00:00Z","config":"a","mode":"blocks","concurrency":1,"request":"GetBlock","ok":true,"latency_ms":7}`
+	body := line + "\nnot json\n" + line + "\n" + line[:40]
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	agg, err := aggregateRequestLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := agg.methodSeries()[methodKey{Config: "a", Mode: "blocks", Concurrency: 1, Method: "GetBlock"}]; m.Total != 2 {
+		t.Fatalf("total %d, want the 2 complete lines", m.Total)
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// resultsLogName is the append-only file every finished Result is written to, one JSON line each.
+const resultsLogName = "results.jsonl"
+
+type cellKey struct {
+	Config      string
+	Mode        string
+	Concurrency int
+}
+
+// resultStore persists results as they finish so an interrupted run can be resumed with --resume.
+type resultStore struct {
+	mu   sync.Mutex
+	f    *os.File
+	done map[cellKey]bool // read-only after open
+}
+
+// openResultStore opens path for appending; on resume it keeps the results keep accepts and drops the rest.
+func openResultStore(path string, resume bool, keep func(Result) bool) (*resultStore, []Result, error) {
+	s := &resultStore{done: map[cellKey]bool{}}
+	var prev []Result
+	if resume {
+		all, err := readResultsLog(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, r := range all {
+			if keep(r) {
+				prev = append(prev, r)
+				s.done[cellKey{r.Config, r.Mode, r.Concurrency}] = true
+			}
+		}
+		if len(prev) != len(all) {
+			if err := rewriteResultsLog(path, prev); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.f = f
+	return s, prev, nil
+}
+
+func (s *resultStore) add(r Result) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+// has reports whether a (config, mode, concurrency) cell finished in the run being resumed.
+func (s *resultStore) has(cfgName, mode string, conc int) bool {
+	return s.done[cellKey{cfgName, mode, conc}]
+}
+
+func (s *resultStore) Close() error {
+	return s.f.Close()
+}
+
+func readResultsLog(path string) ([]Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []Result
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 256*1024*1024)
+	for scanner.Scan() {
+		var r Result
+		// a line cut short by a crash is skipped; its cell simply runs again
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out, scanner.Err()
+}
+
+func rewriteResultsLog(path string, results []Result) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, r := range results {
+		if err := enc.Encode(r); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// recordRequestLog is the log path stored with results, relative when inside the results dir.
+func recordRequestLog(outRoot, path string) string {
+	if path == "" {
+		return ""
+	}
+	if rel, err := filepath.Rel(outRoot, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return rel
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// resolveRequestLog returns the request log the results were written with, "" when none was recorded.
+func resolveRequestLog(outRoot string, results []Result) string {
+	for _, r := range results {
+		if r.RequestLog == "" {
+			continue
+		}
+		if filepath.IsAbs(r.RequestLog) {
+			return r.RequestLog
+		}
+		return filepath.Join(outRoot, r.RequestLog)
+	}
+	return ""
+}
+
+// pruneRequestLog drops the log entries of unfinished cells in place and returns how many it dropped.
+func pruneRequestLog(path string, store *resultStore) (int, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return 0, nil
+	}
+	tmp := path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	z, err := newLogCompressor(out, path)
+	if err != nil {
+		out.Close()
+		return 0, err
+	}
+	w := bufio.NewWriterSize(z, 1<<20)
+	enc, err := newLogEncoder(w, path)
+	if err != nil {
+		out.Close()
+		return 0, err
+	}
+	dropped := 0
+	err = readLogEntries(path, func(e logEntry) {
+		if !store.has(e.Config, e.Mode, e.Concurrency) {
+			dropped++
+			return
+		}
+		enc.encode(e)
+	})
+	if err == nil {
+		err = enc.flush()
+	}
+	if err != nil {
+		out.Close()
+		return 0, err
+	}
+	if err := w.Flush(); err != nil {
+		out.Close()
+		return 0, err
+	}
+	if err := z.Close(); err != nil {
+		out.Close()
+		return 0, err
+	}
+	if err := out.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return 0, fmt.Errorf("replace request log: %w", err)
+	}
+	return dropped, nil
+}
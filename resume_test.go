@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestLog(t *testing.T, path string, entries []logEntry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	z, err := newLogCompressor(f, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bufio.NewWriter(z)
+	enc, err := newLogEncoder(w, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if err := enc.encode(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResultStoreResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), resultsLogName)
+	store, prev, err := openResultStore(path, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prev) != 0 {
+		t.Fatalf("new store has %d results", len(prev))
+	}
+	for _, r := range []Result{
+		{Config: "a", Mode: "blocks", Concurrency: 1, Seed: 7},
+		{Config: "a", Mode: "verify", Concurrency: 1},
+		{Config: "b", Mode: "blocks", Concurrency: 4},
+	} {
+		if err := store.add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Close()
+
+	// a crash in the middle of a write leaves a partial line
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"config":"b","mode":"accounts","concur`)
+	f.Close()
+
+	all, err := readResultsLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].Seed != 7 {
+		t.Fatalf("read %d results (seed %d), want 3 with seed 7", len(all), all[0].Seed)
+	}
+
+	store, prev, err = openResultStore(path, true, func(r Result) bool { return r.Mode != "verify" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if len(prev) != 2 {
+		t.Fatalf("resumed with %d results, want 2", len(prev))
+	}
+	if !store.has("a", "blocks", 1) || !store.has("b", "blocks", 4) || store.has("a", "verify", 1) || store.has("b", "accounts", 4) {
+		t.Fatalf("finished cells %v", store.done)
+	}
+	// the dropped result and the partial line are gone from the file
+	if all, _ := readResultsLog(path); len(all) != 2 {
+		t.Fatalf("%d results left in the log, want 2", len(all))
+	}
+}
+
+func TestPruneRequestLog(t *testing.T) {
+	store := &resultStore{done: map[cellKey]bool{{"a", "blocks", 1}: true}}
+	entries := []logEntry{
+		{Config: "a", Mode: "blocks", Concurrency: 1, Request: "GetBlock", OK: true},
+		{Config: "a", Mode: "blocks", Concurrency: 2, Request: "GetBlock", OK: true},
+		{Config: "a", Mode: "blocks", Concurrency: 1, Request: "GetBlock", Error: "timeout"},
+		{Config: "b", Mode: "blocks", Concurrency: 1, Request: "GetBlock", OK: true},
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"requests.jsonl", "requests.jsonl.gz", "requests.bin.zst"} {
+		path := filepath.Join(dir, name)
+		writeTestLog(t, path, entries)
+		dropped, err := pruneRequestLog(path, store)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if dropped != 2 {
+			t.Errorf("%s: dropped %d, want 2", name, dropped)
+		}
+		var kept []logEntry
+		if err := readLogEntries(path, func(e logEntry) { kept = append(kept, e) }); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(kept) != 2 || kept[1].Error != "timeout" {
+			t.Errorf("%s: kept %+v", name, kept)
+		}
+	}
+
+	if dropped, err := pruneRequestLog(filepath.Join(dir, "missing.jsonl"), store); err != nil || dropped != 0 {
+		t.Fatalf("missing log: dropped %d, err %v", dropped, err)
+	}
+}
+
+func TestRequestLogPath(t *testing.T) {
+	out := filepath.Join("results", "run")
+	inside := filepath.Join(out, "requests.jsonl")
+	if got := recordRequestLog(out, inside); got != "requests.jsonl" {
+		t.Fatalf("log in the results dir recorded as %q", got)
+	}
+	outside := filepath.Join("logs", "r.bin")
+	rec := recordRequestLog(out, outside)
+	if !filepath.IsAbs(rec) {
+		t.Fatalf("log outside the results dir recorded as %q", rec)
+	}
+	if recordRequestLog(out, "") != "" {
+		t.Fatal("disabled log recorded")
+	}
+
+	// resumed from another place: the relative path follows the results dir
+	moved := filepath.Join("elsewhere", "run")
+	if got := resolveRequestLog(moved, []Result{{}, {RequestLog: "requests.jsonl"}}); got != filepath.Join(moved, "requests.jsonl") {
+		t.Fatalf("resolved %q", got)
+	}
+	if got := resolveRequestLog(moved, []Result{{RequestLog: rec}}); got != rec {
+		t.Fatalf("resolved %q, want %q", got, rec)
+	}
+	if got := resolveRequestLog(moved, []Result{{}}); got != "" {
+		t.Fatalf("resolved %q without a recorded log", got)
+	}
+}
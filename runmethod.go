@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteapi"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// MethodStats is what the runmethod workload saw of the TVM: exit codes and how calls failed.
+type MethodStats struct {
+	ExitCodes   map[string]int `json:"exit_codes,omitempty"`
+	GasFailures int            `json:"gas_failures,omitempty"`
+	VMFailures  int            `json:"vm_failures,omitempty"`
+}
+
+type methodCall struct {
+	Account ton.AccountID
+	Method  string
+	Args    []string
+	Stack   tlb.VmStack
+}
+
+// discoveryProbes are get-methods used to detect wallets and jettons among warmed-up accounts.
+var discoveryProbes = []string{"seqno", "get_wallet_data", "get_jetton_data"}
+
+// loadMethodCalls reads "<account> <method> [arg ...]" lines; args are integers or addresses.
+func loadMethodCalls(path string) ([]methodCall, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []methodCall
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid line '%s': expected <account> <method> [args]", line)
+		}
+		addr, err := ton.ParseAccountID(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid address '%s': %w", fields[0], err)
+		}
+		call := methodCall{Account: addr, Method: fields[1], Args: fields[2:]}
+		for _, arg := range call.Args {
+			v, err := parseStackArg(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid arg '%s': %w", arg, err)
+			}
+			call.Stack = append(call.Stack, v)
+		}
+		out = append(out, call)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func parseStackArg(arg string) (tlb.VmStackValue, error) {
+	if !strings.HasPrefix(arg, "addr:") {
+		if n, ok := new(big.Int).SetString(arg, 0); ok {
+			if n.IsInt64() {
+				return tlb.VmStackValue{SumType: "VmStkTinyInt", VmStkTinyInt: n.Int64()}, nil
+			}
+			return tlb.VmStackValue{SumType: "VmStkInt", VmStkInt: tlb.Int257(*n)}, nil
+		}
+	}
+	addr, err := ton.ParseAccountID(strings.TrimPrefix(arg, "addr:"))
+	if err != nil {
+		return tlb.VmStackValue{}, err
+	}
+	return tlb.TlbStructToVmCellSlice(addr.ToMsgAddress())
+}
+
+func discoverMethodCalls(api *liteapi.Client, accounts []ton.AccountID, timeout time.Duration, limit int) []methodCall {
+	sample := accounts
+	if limit > 0 && len(sample) > limit {
+		sample = sample[:limit]
+	}
+	fmt.Printf("discovering get-method targets (accounts=%d, probes=%s)\n", len(sample), strings.Join(discoveryProbes, ","))
+	var mu sync.Mutex
+	var out []methodCall
+	runJobs(len(sample), 16, func(i int) error {
+		for _, method := range discoveryProbes {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			code, _, err := api.RunSmcMethod(ctx, sample[i], method, tlb.VmStack{})
+			cancel()
+			if err != nil {
+				if errors.Is(err, liteapi.ErrAccountNotFound) {
+					return nil
+				}
+				continue
+			}
+			if code == 0 || code == 1 {
+				mu.Lock()
+				out = append(out, methodCall{Account: sample[i], Method: method})
+				mu.Unlock()
+			}
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Method != out[j].Method {
+			return out[i].Method < out[j].Method
+		}
+		return out[i].Account.String() < out[j].Account.String()
+	})
+	byMethod := map[string]int{}
+	for _, c := range out {
+		byMethod[c.Method]++
+	}
+	fmt.Printf("discovered %d get-method targets: %v\n", len(out), byMethod)
+	return out
+}
+
+// isGasExitCode reports TVM out-of-gas exit codes (-14 is reported as 13 by some nodes).
+func isGasExitCode(code int32) bool {
+	return code == 13 || code == -14
+}
+
+func runMethodTest(env *runEnv, clients *clientSet, cfgName, targets string, calls []methodCall, conc int, timeout time.Duration, duration time.Duration, logger *reqLogger, rng *lockedRand) Result {
+	fmt.Printf("runmethod: concurrency=%d, total=%d\n", conc, len(calls))
+	start := time.Now()
+	var mu sync.Mutex
+	exitCodes := map[string]int{}
+	gasFailures := 0
+	vmFailures := 0
+	notFound := 0
+	work := clients.wrap(func(api *liteapi.Client, i int) (err error) {
+		span := env.job(cfgName, targets, string(ModeRunMethod), conc)
+		defer span.end(&err)
+		// a fixed run calls every listed method once per pass; a timed run samples them
+		call := calls[i%len(calls)]
+		if duration > 0 {
+			call = calls[rng.stream().Intn(len(calls))]
+		}
+		span.set("ton.account", call.Account.ToRaw())
+		span.set("ton.method", call.Method)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		t0 := time.Now()
+		code, _, err := api.RunSmcMethod(ctx, call.Account, call.Method, call.Stack)
+		if errors.Is(err, liteapi.ErrAccountNotFound) {
+			// an answer about the account, not a VM run: counted apart from exit codes
+			env.logRequest(logger, span, cfgName, targets, string(ModeRunMethod), conc, "RunSmcMethod:"+call.Method, t0, 0, nil)
+			mu.Lock()
+			exitCodes["account_not_found"]++
+			notFound++
+			mu.Unlock()
+			return nil
+		}
+		if err != nil {
+			env.logRequest(logger, span, cfgName, targets, string(ModeRunMethod), conc, "RunSmcMethod:"+call.Method, t0, 0, err)
+			return err
+		}
+		exit := int32(code)
+		env.logRequestExit(logger, span, cfgName, targets, string(ModeRunMethod), conc, "RunSmcMethod:"+call.Method, t0, 0, int(exit), nil)
+		mu.Lock()
+		exitCodes[strconv.Itoa(int(exit))]++
+		switch {
+		case exit == 0 || exit == 1:
+		case isGasExitCode(exit):
+			gasFailures++
+		default:
+			vmFailures++
+		}
+		mu.Unlock()
+		return nil
+	})
+
+	jr := env.runWorkload(cfgName, string(ModeRunMethod), len(calls), conc, duration, work)
+	res := env.finishResult(jr, string(ModeRunMethod), conc, len(calls), duration, start)
+	res.ExitCodes = exitCodes
+	res.GasFailures = gasFailures
+	res.VMFailures = vmFailures
+	res.NotFound = notFound
+	clients.apply(&res)
+	return res
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tonkeeper/tongo/tlb"
+)
+
+const testRawAddr = "0:83dfd552e63729b472fcbcc8c45ebcc6691702558b68ec7527e1ba403a0f31a8"
+
+func TestParseStackArg(t *testing.T) {
+	tests := []struct {
+		in   string
+		want tlb.SumType
+		ok   bool
+	}{
+		{"0", "VmStkTinyInt", true},
+		{"-17", "VmStkTinyInt", true},
+		{"0x1f", "VmStkTinyInt", true},
+		{"0x10000000000000000", "VmStkInt", true},
+		{"addr:" + testRawAddr, "VmStkSlice", true},
+		{testRawAddr, "VmStkSlice", true},
+		{"addr:12", "", false},
+		{"wallet", "", false},
+	}
+	for _, tt := range tests {
+		v, err := parseStackArg(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseStackArg(%q): err %v, want ok %v", tt.in, err, tt.ok)
+			continue
+		}
+		if tt.ok && v.SumType != tt.want {
+			t.Errorf("parseStackArg(%q) = %s, want %s", tt.in, v.SumType, tt.want)
+		}
+	}
+
+	v, _ := parseStackArg("0x1f")
+	if v.VmStkTinyInt != 31 {
+		t.Errorf("0x1f = %d", v.VmStkTinyInt)
+	}
+	v, _ = parseStackArg("0x10000000000000000")
+	if n := big.Int(v.VmStkInt); n.Cmp(new(big.Int).Lsh(big.NewInt(1), 64)) != 0 {
+		t.Errorf("2^64 = %s", n.String())
+	}
+}
+
+func TestLoadMethodCalls(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	calls, err := loadMethodCalls(write("ok.txt", "# wallets\n\n"+testRawAddr+" seqno\n  "+testRawAddr+" get_wallet_address addr:"+testRawAddr+" 7\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("got %d calls, want 2", len(calls))
+	}
+	if calls[0].Method != "seqno" || len(calls[0].Stack) != 0 {
+		t.Errorf("call 0: %s with %d args", calls[0].Method, len(calls[0].Stack))
+	}
+	if c := calls[1]; c.Method != "get_wallet_address" || len(c.Stack) != 2 || c.Stack[0].SumType != "VmStkSlice" || c.Stack[1].SumType != "VmStkTinyInt" {
+		t.Errorf("call 1: %s %v", c.Method, c.Stack)
+	}
+	if calls[0].Account.ToRaw() != testRawAddr {
+		t.Errorf("account %s", calls[0].Account.ToRaw())
+	}
+
+	for name, body := range map[string]string{
+		"no method": testRawAddr + "\n",
+		"bad addr":  "EQxyz seqno\n",
+		"bad arg":   testRawAddr + " get x:1\n",
+	} {
+		if _, err := loadMethodCalls(write("bad.txt", body)); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	if _, err := loadMethodCalls(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("missing file: accepted")
+	}
+}
+
+func TestIsGasExitCode(t *testing.T) {
+	for code, want := range map[int32]bool{13: true, -14: true, 0: false, 1: false, -13: false, 14: false} {
+		if got := isGasExitCode(code); got != want {
+			t.Errorf("isGasExitCode(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestFormatExitCodes(t *testing.T) {
+	got := formatExitCodes(map[string]int{"account_not_found": 2, "13": 1, "-14": 3, "0": 9})
+	if want := "-14:3 0:9 13:1 account_not_found:2"; got != want {
+		t.Errorf("formatExitCodes = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteapi"
+	"github.com/tonkeeper/tongo/liteapi/pool"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// sendDestinations is how many nonexistent wallets external messages are spread over.
+const sendDestinations = 64
+
+// SendStats is how the liteserver answered the externals of a send-mode result.
+type SendStats struct {
+	Accepted     int `json:"accepted,omitempty"`
+	Rejected     int `json:"rejected,omitempty"`
+	BackPressure int `json:"back_pressure,omitempty"`
+}
+
+// unpayableMessage builds a wallet-v3-shaped external with a random signature. dest must not exist: an uninit
+// account cannot accept an external without a StateInit, so nothing is ever paid.
+func unpayableMessage(dest ton.AccountID, seqno uint32) ([]byte, error) {
+	body := boc.NewCell()
+	var sig [64]byte
+	rand.Read(sig[:])
+	if err := body.WriteBytes(sig[:]); err != nil {
+		return nil, err
+	}
+	if err := body.WriteUint(698983191, 32); err != nil { // subwallet id
+		return nil, err
+	}
+	if err := body.WriteUint(uint64(time.Now().Add(time.Minute).Unix()), 32); err != nil {
+		return nil, err
+	}
+	if err := body.WriteUint(uint64(seqno), 32); err != nil {
+		return nil, err
+	}
+
+	msg := boc.NewCell()
+	// ext_in_msg_info$10 src:addr_none$00 dest:addr_std$10 anycast:nothing$0
+	if err := msg.WriteUint(0b10_00_10_0, 7); err != nil {
+		return nil, err
+	}
+	if err := msg.WriteInt(int64(dest.Workchain), 8); err != nil {
+		return nil, err
+	}
+	if err := msg.WriteBytes(dest.Address[:]); err != nil {
+		return nil, err
+	}
+	// import_fee:0, init:nothing, body in a ref
+	if err := msg.WriteUint(0b0000_0_1, 6); err != nil {
+		return nil, err
+	}
+	if err := msg.AddRef(body); err != nil {
+		return nil, err
+	}
+	payload, err := msg.ToBoc()
+	if err != nil {
+		return nil, err
+	}
+	return payload, liteapi.VerifySendMessagePayload(payload)
+}
+
+// pickSendDestinations generates random basechain addresses, checked not to exist unless running against the mock.
+func pickSendDestinations(api *liteapi.Client, n int, timeout time.Duration, verify bool, rng *lockedRand) ([]ton.AccountID, error) {
+	out := make([]ton.AccountID, 0, n)
+	for len(out) < n {
+		var dest ton.AccountID
+		rng.Read(dest.Address[:])
+		if verify {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			state, err := api.GetAccountState(ctx, dest)
+			cancel()
+			if err != nil {
+				return nil, fmt.Errorf("check destination %s: %w", dest.ToRaw(), err)
+			}
+			if state.Account.SumType != "AccountNone" {
+				continue
+			}
+		}
+		out = append(out, dest)
+	}
+	return out, nil
+}
+
+// isBackPressure reports errors meaning the server could not keep up, as opposed to a rejection.
+func isBackPressure(err error) bool {
+	if errors.Is(err, pool.ErrNoConnections) {
+		return true
+	}
+	class := classifyError(err.Error())
+	if isNotFound(class) {
+		return false
+	}
+	switch class {
+	case "timeout", "ls_timeout", "rate_limited", "no_connections", "conn_reset", "broken_pipe", "eof":
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "not connected")
+}
+
+// runSendTest pushes unpayable externals through SendMessage; rejections are counted by code, not as errors.
+func runSendTest(env *runEnv, clients *clientSet, cfgName, targets string, dests []ton.AccountID, total int, conc int, timeout time.Duration, duration time.Duration, logger *reqLogger, rng *lockedRand) Result {
+	mode := string(ModeSend)
+	fmt.Printf("%s: concurrency=%d, total=%d, destinations=%d\n", mode, conc, total, len(dests))
+	start := time.Now()
+	var mu sync.Mutex
+	codes := map[string]int{}
+	accepted, rejected, backPressure := 0, 0, 0
+	var bytes int64
+	work := clients.wrap(func(api *liteapi.Client, i int) (err error) {
+		span := env.job(cfgName, targets, mode, conc)
+		defer span.end(&err)
+		dest := dests[rng.stream().Intn(len(dests))]
+		span.set("ton.account", dest.ToRaw())
+		payload, err := unpayableMessage(dest, uint32(i))
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		t0 := time.Now()
+		status, err := api.SendMessage(ctx, payload)
+		var lsErr liteclient.LiteServerErrorC
+		switch {
+		case err == nil:
+			env.logRequest(logger, span, cfgName, targets, mode, conc, "SendMessage", t0, 0, nil)
+			mu.Lock()
+			codes["status:"+strconv.Itoa(int(status))]++
+			accepted++
+			bytes += int64(len(payload))
+			mu.Unlock()
+			return nil
+		case errors.As(err, &lsErr) && !isBackPressure(err):
+			env.logRequestExit(logger, span, cfgName, targets, mode, conc, "SendMessage", t0, 0, int(int32(lsErr.Code)), nil)
+			mu.Lock()
+			codes["error:"+strconv.Itoa(int(int32(lsErr.Code)))]++
+			rejected++
+			bytes += int64(len(payload))
+			mu.Unlock()
+			return nil
+		default:
+			env.logRequest(logger, span, cfgName, targets, mode, conc, "SendMessage", t0, 0, err)
+			if isBackPressure(err) {
+				mu.Lock()
+				backPressure++
+				mu.Unlock()
+			}
+			return err
+		}
+	})
+
+	jr := env.runWorkload(cfgName, mode, total, conc, duration, work)
+	res := env.finishResult(jr, mode, conc, total, duration, start)
+	res.ExitCodes = codes
+	res.Accepted = accepted
+	res.Rejected = rejected
+	res.BackPressure = backPressure
+	res.Bytes = bytes
+	applyThroughput(&res)
+	clients.apply(&res)
+	return res
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// servedFiles are the files of a results dir the control API hands out.
+var servedFiles = map[string]bool{
+	"report.html":        true,
+	"summary.json":       true,
+	"summary.csv":        true,
+	"summary.md":         true,
+	"errors.csv":         true,
+	"errors.json":        true,
+	"error_samples.json": true,
+	"payloads.csv":       true,
+	"freshness.json":     true,
+	"soak.jsonl":         true,
+	"series.lp":          true,
+	"series.om":          true,
+}
+
+// scenarioFlags are the flags a run spec or coordinator may set; none names a file or remote address.
+var scenarioFlags = map[string]bool{
+	"mode": true, "concurrency": true, "steps": true, "step-duration": true, "duration": true, "timeout": true,
+	"blocks": true, "blocks-random": true, "blocks-refresh": true, "age-buckets": true,
+	"accounts-count": true, "accounts-warmup": true, "accounts-warmup-blocks": true, "accounts-shuffle": true,
+	"methods-discover": true, "tx-pages": true, "tx-page-size": true, "config-params": true, "proof-clients": true,
+	"send-count": true, "clients": true, "session": true, "think": true, "profile": true, "profile-rate": true,
+	"seed": true, "soak-interval": true, "soak-drift": true, "freshness": true, "verify-count": true,
+	"connect-rate": true, "connect-count": true, "connect-storm": true, "mock": true, "histograms": true,
+	"slo-p99": true, "slo-error-rate": true, "slo-min-rps": true,
+	"report-max-points": true, "request-log-format": true, "request-log-compress": true,
+	"trace-slowest": true, "trace-errors": true, "error-samples": true,
+	"retries": true, "max-connections": true, "workers-per-conn": true, "pool-strategy": true, "proof": true,
+}
+
+// runLog is a run's console output, kept in full so late readers can replay it.
+type runLog struct {
+	mu      sync.Mutex
+	buf     []byte
+	scanned int
+	results string
+	done    bool
+	changed chan struct{}
+}
+
+func newRunLog() *runLog {
+	return &runLog{changed: make(chan struct{})}
+}
+
+func (l *runLog) Write(p []byte) (int, error) {
+	os.Stdout.Write(p)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.buf, p...)
+	for l.results == "" {
+		i := bytes.IndexByte(l.buf[l.scanned:], '\n')
+		if i < 0 {
+			break
+		}
+		line := string(l.buf[l.scanned : l.scanned+i])
+		l.scanned += i + 1
+		if dir, ok := strings.CutPrefix(line, "Results: "); ok {
+			l.results = strings.TrimSpace(dir)
+		}
+	}
+	close(l.changed)
+	l.changed = make(chan struct{})
+	return len(p), nil
+}
+
+func (l *runLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.done = true
+	close(l.changed)
+}
+
+// since returns the output after offset, whether the run has ended, and a channel closed on the next write.
+func (l *runLog) since(offset int) ([]byte, bool, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf[min(offset, len(l.buf)):], l.done, l.changed
+}
+
+// resultsDir is the results dir the run announced on its first lines, if it got that far.
+func (l *runLog) resultsDir() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.results
+}
+
+// serverRun is one run started through the API. State is running, done, failed, slo_failed or stopped.
+type serverRun struct {
+	ID       string            `json:"id"`
+	Spec     map[string]string `json:"spec"`
+	Args     []string          `json:"args"`
+	State    string            `json:"state"`
+	Error    string            `json:"error,omitempty"`
+	Started  time.Time         `json:"started"`
+	Finished *time.Time        `json:"finished,omitempty"`
+	Results  string            `json:"results,omitempty"`
+
+	cmd *exec.Cmd
+	log *runLog
+}
+
+// controlServer runs one load test at a time in a child process and serves its results dirs.
+type controlServer struct {
+	out     string
+	configs []configItem
+	mu      sync.Mutex
+	runs    []*serverRun
+}
+
+// runServe is the `ls-load serve` subcommand.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", envOr("LS_LOAD_SERVE_LISTEN", "127.0.0.1:8080"), "Address the control API listens on")
+	token := fs.String("token", envOr("LS_LOAD_SERVE_TOKEN", ""), "Bearer token every request must carry; required unless --listen is a loopback address")
+	outDir := fs.String("out", envOr("LS_LOAD_OUT", "results"), "Output directory for runs started through the API")
+	configsStr := fs.String("configs", envOr("LS_LOAD_CONFIGS", "config.json"), "Comma-separated config paths or globs (optional alias: name=path) that run specs pick from by name")
+	fs.Parse(args)
+
+	if *token == "" && !isLoopbackAddr(*listen) {
+		exitf("serve: --token (or LS_LOAD_SERVE_TOKEN) is required to listen on %s", *listen)
+	}
+	configs, err := resolveConfigPaths(*configsStr)
+	if err != nil {
+		exitf("serve: invalid configs: %v", err)
+	}
+	s := &controlServer{out: *outDir, configs: configs}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", s.handleStart)
+	mux.HandleFunc("GET /runs", s.handleRuns)
+	mux.HandleFunc("GET /runs/{id}", s.handleRun)
+	mux.HandleFunc("GET /runs/{id}/log", s.handleLog)
+	mux.HandleFunc("POST /runs/{id}/stop", s.handleStop)
+	mux.HandleFunc("GET /results", s.handleResults)
+	mux.HandleFunc("GET /results/{dir}/{file}", s.handleResultFile)
+	fmt.Printf("Control API listening on %s, results in %s\n", *listen, *outDir)
+	if err := http.ListenAndServe(*listen, requireToken(*token, mux)); err != nil {
+		exitf("serve: %v", err)
+	}
+}
+
+// isLoopbackAddr reports whether a listen address only accepts local connections.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// requireToken rejects requests without "Authorization: Bearer <token>"; an empty token lets everything through.
+func requireToken(token string, h http.Handler) http.Handler {
+	if token == "" {
+		return h
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// specArgs turns a run spec into flags; configs picks configs of the server, all of them by default.
+func (s *controlServer) specArgs(spec map[string]any) (map[string]string, []string, error) {
+	values := map[string]string{}
+	names := make([]string, 0, len(spec))
+	for name, v := range spec {
+		if name != "configs" && !scenarioFlags[name] {
+			if flag.Lookup(name) == nil {
+				return nil, nil, fmt.Errorf("unknown flag %q", name)
+			}
+			return nil, nil, fmt.Errorf("flag %q can't be set by a run spec", name)
+		}
+		value, err := specValue(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", name, err)
+		}
+		values[name] = value
+		names = append(names, name)
+	}
+	configs, err := s.pickConfigs(values["configs"])
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(names)
+	args := []string{"--configs=" + configs}
+	for _, name := range names {
+		if name != "configs" {
+			args = append(args, "--"+name+"="+values[name])
+		}
+	}
+	return values, args, nil
+}
+
+// pickConfigs turns comma-separated config names into a --configs value of name=path pairs.
+func (s *controlServer) pickConfigs(names string) (string, error) {
+	picked := s.configs
+	if strings.TrimSpace(names) != "" {
+		byName := map[string]configItem{}
+		for _, c := range s.configs {
+			byName[c.Name] = c
+		}
+		picked = nil
+		for _, name := range strings.Split(names, ",") {
+			c, ok := byName[strings.TrimSpace(name)]
+			if !ok {
+				return "", fmt.Errorf("unknown config %q", name)
+			}
+			picked = append(picked, c)
+		}
+	}
+	parts := make([]string, len(picked))
+	for i, c := range picked {
+		parts[i] = c.Name + "=" + c.Path
+	}
+	return strings.Join(parts, ","), nil
+}
+
+func specValue(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		parts := make([]string, len(v))
+		for i, e := range v {
+			s, err := specValue(e)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+func (s *controlServer) handleStart(w http.ResponseWriter, r *http.Request) {
+	var spec map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	values, args, err := s.specArgs(spec)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, run := range s.runs {
+		if run.State == "running" {
+			http.Error(w, "run "+run.ID+" is in progress", http.StatusConflict)
+			return
+		}
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	run := &serverRun{
+		ID:      strconv.Itoa(len(s.runs) + 1),
+		Spec:    values,
+		Args:    args,
+		State:   "running",
+		Started: time.Now().UTC(),
+		log:     newRunLog(),
+	}
+	run.cmd = exec.Command(exe, append(args, "--out="+s.out)...)
+	run.cmd.Stdout = run.log
+	run.cmd.Stderr = run.log
+	if err := run.cmd.Start(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.runs = append(s.runs, run)
+	fmt.Printf("serve: run %s started: %s\n", run.ID, strings.Join(args, " "))
+	go s.wait(run)
+	w.WriteHeader(http.StatusCreated)
+	writeHTTPJSON(w, s.view(run))
+}
+
+func (s *controlServer) wait(run *serverRun) {
+	err := run.cmd.Wait()
+	run.log.close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	run.Finished = &now
+	switch {
+	case run.State == "stopped":
+	case run.cmd.ProcessState.ExitCode() == 1 && run.reported():
+		// the run finished and wrote its reports, but a check failed
+		run.State = "slo_failed"
+	case err != nil:
+		run.State = "failed"
+		run.Error = err.Error()
+	default:
+		run.State = "done"
+	}
+	fmt.Printf("serve: run %s %s\n", run.ID, run.State)
+}
+
+// reported reports whether the run wrote its summary, which tells failed checks from setup errors (both exit 1).
+func (run *serverRun) reported() bool {
+	dir := run.log.resultsDir()
+	if dir == "" {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(dir, "summary.md"))
+	return err == nil && !info.ModTime().Before(run.Started.Truncate(time.Second))
+}
+
+// view is a copy of run for JSON, its results dir named under /results. The caller holds s.mu.
+func (s *controlServer) view(run *serverRun) serverRun {
+	v := *run
+	if dir := run.log.resultsDir(); dir != "" {
+		v.Results = filepath.Base(dir)
+	}
+	return v
+}
+
+func (s *controlServer) find(id string) *serverRun {
+	for _, run := range s.runs {
+		if run.ID == id {
+			return run
+		}
+	}
+	return nil
+}
+
+func (s *controlServer) handleRuns(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	out := make([]serverRun, 0, len(s.runs))
+	for i := len(s.runs) - 1; i >= 0; i-- {
+		out = append(out, s.view(s.runs[i]))
+	}
+	s.mu.Unlock()
+	writeHTTPJSON(w, out)
+}
+
+func (s *controlServer) handleRun(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run := s.find(r.PathValue("id"))
+	if run == nil {
+		http.NotFound(w, r)
+		return
+	}
+	writeHTTPJSON(w, s.view(run))
+}
+
+// handleLog streams the run's console output, following it until it ends unless follow=false.
+func (s *controlServer) handleLog(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	run := s.find(r.PathValue("id"))
+	s.mu.Unlock()
+	if run == nil {
+		http.NotFound(w, r)
+		return
+	}
+	follow := r.URL.Query().Get("follow") != "false"
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	flusher, _ := w.(http.Flusher)
+	offset := 0
+	for {
+		chunk, done, changed := run.log.since(offset)
+		if len(chunk) > 0 {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			offset += len(chunk)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if done || !follow {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleStop kills the run; its finished cells can be continued with --resume.
+func (s *controlServer) handleStop(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run := s.find(r.PathValue("id"))
+	if run == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if run.State != "running" {
+		http.Error(w, "run is "+run.State, http.StatusConflict)
+		return
+	}
+	if err := run.cmd.Process.Kill(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	run.State = "stopped"
+	writeHTTPJSON(w, s.view(run))
+}
+
+// resultsEntry describes one results dir under out.
+type resultsEntry struct {
+	Name     string    `json:"name"`
+	Modified time.Time `json:"modified"`
+	Files    []string  `json:"files"`
+}
+
+// handleResults lists the results dirs, newest first, with the files of each that can be fetched.
+func (s *controlServer) handleResults(w http.ResponseWriter, r *http.Request) {
+	entries, err := os.ReadDir(s.out)
+	if err != nil && !os.IsNotExist(err) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out := []resultsEntry{}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		entry := resultsEntry{Name: e.Name(), Modified: info.ModTime().UTC(), Files: []string{}}
+		files, _ := os.ReadDir(filepath.Join(s.out, e.Name()))
+		for _, f := range files {
+			if servedFiles[f.Name()] {
+				entry.Files = append(entry.Files, f.Name())
+			}
+		}
+		out = append(out, entry)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name > out[j].Name })
+	writeHTTPJSON(w, out)
+}
+
+func (s *controlServer) handleResultFile(w http.ResponseWriter, r *http.Request) {
+	dir, file := r.PathValue("dir"), r.PathValue("file")
+	if !servedFiles[file] || dir == "." || dir == ".." || strings.ContainsAny(dir, `/\`) {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, filepath.Join(s.out, dir, file))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestServeRunStates(t *testing.T) {
+	tests := []struct {
+		script string
+		want   string
+	}{
+		{`echo "Results: $DIR"; touch "$DIR/summary.md"`, "done"},
+		{`echo "Results: $DIR"; touch "$DIR/summary.md"; exit 1`, "slo_failed"},
+		{`echo "Results: $DIR"; exit 1`, "failed"},
+		{`exit 1`, "failed"},
+		{`echo "Results: $DIR"; touch "$DIR/summary.md"; exit 2`, "failed"},
+	}
+	s := &controlServer{}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		run := &serverRun{State: "running", Started: time.Now().UTC(), log: newRunLog()}
+		run.cmd = exec.Command("sh", "-c", tt.script)
+		run.cmd.Env = []string{"DIR=" + filepath.ToSlash(dir)}
+		run.cmd.Stdout = run.log
+		if err := run.cmd.Start(); err != nil {
+			t.Skipf("no shell: %v", err)
+		}
+		s.wait(run)
+		if run.State != tt.want {
+			t.Errorf("%s: state %s, want %s", tt.script, run.State, tt.want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/liteapi"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+// defaultSessionScript is a wallet-like visit: head, balance, seqno, recent history.
+const defaultSessionScript = "masterchain,account,method:seqno,transactions"
+
+// thinkTime is a distribution of pauses a virtual user takes before a step.
+type thinkTime struct {
+	Spec string
+	kind string
+	a, b time.Duration
+}
+
+// parseThinkTime reads none, const:D, uniform:MIN-MAX or exp:MEAN.
+func parseThinkTime(spec string) (thinkTime, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	t := thinkTime{Spec: spec}
+	kind, arg, _ := strings.Cut(spec, ":")
+	t.kind = kind
+	switch kind {
+	case "", "none":
+		t.kind = "none"
+	case "const", "exp":
+		d, err := time.ParseDuration(arg)
+		if err != nil || d < 0 {
+			return t, fmt.Errorf("invalid think time %q", spec)
+		}
+		t.a = d
+	case "uniform":
+		lo, hi, ok := strings.Cut(arg, "-")
+		a, err := time.ParseDuration(lo)
+		b, err2 := time.ParseDuration(hi)
+		if !ok || err != nil || err2 != nil || a < 0 || b < a {
+			return t, fmt.Errorf("invalid think time %q, expected uniform:MIN-MAX", spec)
+		}
+		t.a, t.b = a, b
+	default:
+		return t, fmt.Errorf("unknown think time %q", spec)
+	}
+	return t, nil
+}
+
+func (t thinkTime) sample(r *jobRand) time.Duration {
+	switch t.kind {
+	case "const":
+		return t.a
+	case "uniform":
+		return t.a + time.Duration(r.Float64()*float64(t.b-t.a))
+	case "exp":
+		return time.Duration(-math.Log(1-r.Float64()) * float64(t.a))
+	}
+	return 0
+}
+
+// sessionStep is one request of a virtual-user script; Think is the pause before it.
+type sessionStep struct {
+	Name   string
+	Method string
+	Think  thinkTime
+}
+
+// parseSessionScript reads comma-separated steps, each optionally with ~DIST overriding the think time.
+func parseSessionScript(spec string, think thinkTime) ([]sessionStep, error) {
+	var steps []sessionStep
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, dist, hasDist := strings.Cut(part, "~")
+		st := sessionStep{Name: name, Think: think}
+		if hasDist {
+			t, err := parseThinkTime(dist)
+			if err != nil {
+				return nil, err
+			}
+			st.Think = t
+		}
+		switch {
+		case name == "masterchain", name == "account", name == "transactions", name == "shards":
+		case strings.HasPrefix(name, "method:") && len(name) > len("method:"):
+			st.Method = strings.TrimPrefix(name, "method:")
+		default:
+			return nil, fmt.Errorf("unknown session step %q", name)
+		}
+		steps = append(steps, st)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("empty session script")
+	}
+	return steps, nil
+}
+
+// SessionStats is how the virtual users of a session-mode result spent their time, per step and overall.
+type SessionStats struct {
+	SessionSteps       []sessionStepStat `json:"session_steps,omitempty"`
+	SessionActiveP50Ms float64           `json:"session_active_p50_ms,omitempty"`
+	SessionActiveP95Ms float64           `json:"session_active_p95_ms,omitempty"`
+	SessionThinkAvgMs  float64           `json:"session_think_avg_ms,omitempty"`
+	SessionRequestRate float64           `json:"session_request_rate,omitempty"`
+}
+
+type sessionStepStat struct {
+	Step     string  `json:"step"`
+	Requests int     `json:"requests"`
+	Errors   int     `json:"errors"`
+	AvgMs    float64 `json:"avg_ms"`
+	P50Ms    float64 `json:"p50_ms"`
+	P95Ms    float64 `json:"p95_ms"`
+	P99Ms    float64 `json:"p99_ms"`
+}
+
+// sessionRecorder collects per-step latencies and the active (think-free) time of completed sessions.
+type sessionRecorder struct {
+	mu      sync.Mutex
+	steps   [][]int64
+	errors  []int
+	active  []int64
+	thinkMs int64
+}
+
+func newSessionRecorder(n int) *sessionRecorder {
+	return &sessionRecorder{steps: make([][]int64, n), errors: make([]int, n)}
+}
+
+func (s *sessionRecorder) step(k int, latencyMs int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.errors[k]++
+		return
+	}
+	s.steps[k] = append(s.steps[k], latencyMs)
+}
+
+func (s *sessionRecorder) session(activeMs, thinkMs int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = append(s.active, activeMs)
+	s.thinkMs += thinkMs
+}
+
+// sessionVisit is what a session has learned so far, shared by its later steps.
+type sessionVisit struct {
+	addr   ton.AccountID
+	master *ton.BlockIDExt
+	state  *tlb.ShardAccount
+}
+
+// runSessionTest runs the script as one session per job; latency and RPS are per session, think time included.
+func runSessionTest(env *runEnv, clients *clientSet, cfgName, targets string, accounts []ton.AccountID, steps []sessionStep, conc int, timeout time.Duration, duration time.Duration, logger *reqLogger, rng *lockedRand) Result {
+	mode := string(ModeSession)
+	fmt.Printf("%s: users=%d, accounts=%d, steps=%d\n", mode, conc, len(accounts), len(steps))
+	start := time.Now()
+	rec := newSessionRecorder(len(steps))
+	work := clients.wrap(func(api *liteapi.Client, i int) (err error) {
+		span := env.job(cfgName, targets, mode, conc)
+		defer span.end(&err)
+		log := func(req string, t0 time.Time, respBytes int, err error) {
+			env.logRequest(logger, span, cfgName, targets, mode, conc, req, t0, respBytes, err)
+		}
+		r := rng.stream()
+		v := &sessionVisit{addr: accounts[r.Intn(len(accounts))]}
+		span.set("ton.account", v.addr.ToRaw())
+		var active, think int64
+		for k, st := range steps {
+			if k > 0 {
+				d := st.Think.sample(r)
+				time.Sleep(d)
+				think += d.Milliseconds()
+			}
+			t0 := time.Now()
+			err := st.run(api, v, timeout, log)
+			ms := time.Since(t0).Milliseconds()
+			rec.step(k, ms, err)
+			if err != nil {
+				return err
+			}
+			active += ms
+		}
+		rec.session(active, think)
+		return nil
+	})
+
+	jr := env.runWorkload(cfgName, mode, len(accounts), conc, duration, work)
+	res := env.finishResult(jr, mode, conc, len(accounts), duration, start)
+	requests := 0
+	for k, st := range steps {
+		lat := rec.steps[k]
+		stat := sessionStepStat{Step: st.label(), Requests: len(lat) + rec.errors[k], Errors: rec.errors[k]}
+		stat.AvgMs, stat.P50Ms, _, stat.P95Ms, stat.P99Ms, _ = computeMetrics(lat, len(lat))
+		res.SessionSteps = append(res.SessionSteps, stat)
+		requests += stat.Requests
+	}
+	_, res.SessionActiveP50Ms, _, res.SessionActiveP95Ms, _, _ = computeMetrics(rec.active, len(rec.active))
+	if len(rec.active) > 0 {
+		res.SessionThinkAvgMs = float64(rec.thinkMs) / float64(len(rec.active))
+	}
+	if res.Duration > 0 {
+		res.SessionRequestRate = float64(requests) / res.Duration.Seconds()
+	}
+	clients.apply(&res)
+	return res
+}
+
+func (st sessionStep) label() string {
+	if st.Method != "" {
+		return "method:" + st.Method
+	}
+	return st.Name
+}
+
+// run performs the step, fetching whatever an earlier step would have provided when the script skipped it.
+func (st sessionStep) run(api *liteapi.Client, v *sessionVisit, timeout time.Duration, log func(req string, t0 time.Time, respBytes int, err error)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	head := func() error {
+		if v.master != nil {
+			return nil
+		}
+		t0 := time.Now()
+		info, err := api.GetMasterchainInfo(ctx)
+		log("GetMasterchainInfo", t0, 0, err)
+		if err != nil {
+			return err
+		}
+		id := info.Last.ToBlockIdExt()
+		v.master = &id
+		return nil
+	}
+	account := func() error {
+		if v.state != nil {
+			return nil
+		}
+		// like a wallet, read the account at the head the session already knows
+		a := api
+		if v.master != nil {
+			a = api.WithBlock(*v.master)
+		}
+		t0 := time.Now()
+		state, err := a.GetAccountState(ctx, v.addr)
+		log("GetAccountState", t0, 0, err)
+		if err != nil {
+			return err
+		}
+		v.state = &state
+		return nil
+	}
+	switch {
+	case st.Name == "masterchain":
+		v.master = nil
+		return head()
+	case st.Name == "account":
+		v.state = nil
+		return account()
+	case st.Name == "shards":
+		if err := head(); err != nil {
+			return err
+		}
+		t0 := time.Now()
+		_, err := api.GetAllShardsInfo(ctx, *v.master)
+		log("GetAllShardsInfo", t0, 0, err)
+		return err
+	case st.Method != "":
+		t0 := time.Now()
+		_, _, err := api.RunSmcMethod(ctx, v.addr, st.Method, tlb.VmStack{})
+		if errors.Is(err, liteapi.ErrAccountNotFound) {
+			err = nil
+		}
+		log("RunSmcMethod:"+st.Method, t0, 0, err)
+		return err
+	case st.Name == "transactions":
+		if err := account(); err != nil {
+			return err
+		}
+		if v.state.LastTransLt == 0 {
+			return nil
+		}
+		t0 := time.Now()
+		raw, err := api.GetTransactionsRaw(ctx, maxTxPageSize, v.addr, v.state.LastTransLt, ton.Bits256(v.state.LastTransHash))
+		log("GetTransactions", t0, len(raw.Transactions), err)
+		return err
+	}
+	return fmt.Errorf("unknown session step %q", st.Name)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseThinkTime(t *testing.T) {
+	tests := []struct {
+		spec string
+		kind string
+		a, b time.Duration
+		ok   bool
+	}{
+		{"", "none", 0, 0, true},
+		{"none", "none", 0, 0, true},
+		{"const:2s", "const", 2 * time.Second, 0, true},
+		{" EXP:500ms ", "exp", 500 * time.Millisecond, 0, true},
+		{"uniform:1s-3s", "uniform", time.Second, 3 * time.Second, true},
+		{"uniform:1s-1s", "uniform", time.Second, time.Second, true},
+		{"const:0s", "const", 0, 0, true},
+		{"const", "", 0, 0, false},
+		{"const:-1s", "", 0, 0, false},
+		{"exp:x", "", 0, 0, false},
+		{"uniform:3s-1s", "", 0, 0, false},
+		{"uniform:1s", "", 0, 0, false},
+		{"uniform:-1s-2s", "", 0, 0, false},
+		{"normal:1s", "", 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseThinkTime(tt.spec)
+		if (err == nil) != tt.ok {
+			t.Errorf("parseThinkTime(%q): err %v, want ok %v", tt.spec, err, tt.ok)
+			continue
+		}
+		if tt.ok && (got.kind != tt.kind || got.a != tt.a || got.b != tt.b) {
+			t.Errorf("parseThinkTime(%q) = %s %s-%s", tt.spec, got.kind, got.a, got.b)
+		}
+	}
+}
+
+func TestThinkTimeSample(t *testing.T) {
+	r := newLockedRand(1).stream()
+	uniform, _ := parseThinkTime("uniform:1s-2s")
+	exp, _ := parseThinkTime("exp:100ms")
+	none, _ := parseThinkTime("none")
+	var sum time.Duration
+	const n = 10000
+	for i := 0; i < n; i++ {
+		if d := uniform.sample(r); d < time.Second || d > 2*time.Second {
+			t.Fatalf("uniform sample %s", d)
+		}
+		d := exp.sample(r)
+		if d < 0 {
+			t.Fatalf("exp sample %s", d)
+		}
+		sum += d
+		if d := none.sample(r); d != 0 {
+			t.Fatalf("none sample %s", d)
+		}
+	}
+	if mean := sum / n; mean < 90*time.Millisecond || mean > 110*time.Millisecond {
+		t.Fatalf("exp mean %s, want about 100ms", mean)
+	}
+}
+
+func TestParseSessionScript(t *testing.T) {
+	think, _ := parseThinkTime("const:1s")
+	steps, err := parseSessionScript(" masterchain, account~none ,method:get_wallet_data~uniform:0s-2s,,transactions,shards", think)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		label string
+		think string
+	}{
+		{"masterchain", "const"},
+		{"account", "none"},
+		{"method:get_wallet_data", "uniform"},
+		{"transactions", "const"},
+		{"shards", "const"},
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("%d steps, want %d", len(steps), len(want))
+	}
+	for i, w := range want {
+		if steps[i].label() != w.label || steps[i].Think.kind != w.think {
+			t.Errorf("step %d: %s think %s, want %s think %s", i, steps[i].label(), steps[i].Think.kind, w.label, w.think)
+		}
+	}
+	if steps[2].Method != "get_wallet_data" {
+		t.Errorf("method %q", steps[2].Method)
+	}
+
+	if _, err := parseSessionScript(defaultSessionScript, think); err != nil {
+		t.Errorf("default script: %v", err)
+	}
+	for _, spec := range []string{"", " , ", "method:", "blocks", "account~exp:x", "masterchain,Account"} {
+		if _, err := parseSessionScript(spec, think); err == nil {
+			t.Errorf("parseSessionScript(%q) accepted", spec)
+		}
+	}
+}
@@ -4,8 +4,9 @@ import (
 	"bufio"
 	"context"
 	cryptorand "crypto/rand"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
 	mathrand "math/rand"
 	"os"
@@ -19,6 +20,8 @@ import (
 )
 
 type logEntry struct {
+	// t is the request start; the writer formats it into Ts so workers don't pay for it.
+	t           time.Time
 	Ts          string `json:"ts"`
 	Config      string `json:"config"`
 	Targets     string `json:"targets,omitempty"`
@@ -28,6 +31,7 @@ type logEntry struct {
 	RespBytes   int    `json:"resp_bytes,omitempty"`
 	OK          bool   `json:"ok"`
 	LatencyMs   int64  `json:"latency_ms"`
+	ExitCode    int    `json:"exit_code,omitempty"`
 	Error       string `json:"error,omitempty"`
 }
 
@@ -35,12 +39,26 @@ type reqLogger struct {
 	ch chan logEntry
 	wg sync.WaitGroup
 	w  *bufio.Writer
+	z  io.WriteCloser
 	f  *os.File
+
+	// entries the writer could not keep up with, by cell; workers never wait on the log
+	mu      sync.Mutex
+	drops   map[cellKey]int
+	dropped int
 }
 
+// lockedRand is the run's seeded randomness; concurrent jobs take a stream each, independent of scheduling.
 type lockedRand struct {
-	mu sync.Mutex
-	r  *mathrand.Rand
+	mu      sync.Mutex
+	r       *mathrand.Rand
+	seed    int64
+	streams uint64
+}
+
+// jobRand is a small splitmix64 generator owned by one job.
+type jobRand struct {
+	state uint64
 }
 
 type blockRange struct {
@@ -60,7 +78,6 @@ type blockPicker struct {
 	lastRefresh time.Time
 	latest      int32
 	mu          sync.Mutex
-	rng         *lockedRand
 }
 
 type jobRun struct {
@@ -74,17 +91,57 @@ type jobRun struct {
 	seriesP95   []float64
 	seriesP99   []float64
 	seriesStart int64
+	// seriesTarget is the profile's target level per second, when a load profile shaped the run.
+	seriesTarget []float64
+	// soakIntervals are the checkpoints of a soak run.
+	soakIntervals []soakInterval
+	// seriesHist holds per-second latency histograms, kept only with --histograms.
+	seriesHist [][]histBin
+	// waited is the time spent at the agent start barrier; it does not count towards the run.
+	waited time.Duration
 }
 
-func newLockedRand() *lockedRand {
-	var seed int64
+// randomSeed picks a seed for runs without --seed; it is recorded so the run can be repeated.
+func randomSeed() int64 {
 	var buf [8]byte
 	if _, err := cryptorand.Read(buf[:]); err == nil {
-		seed = int64(binaryBigEndian(buf[:]))
-	} else {
-		seed = time.Now().UnixNano()
+		return int64(binaryBigEndian(buf[:]))
 	}
-	return &lockedRand{r: mathrand.New(mathrand.NewSource(seed))}
+	return time.Now().UnixNano()
+}
+
+func newLockedRand(seed int64) *lockedRand {
+	return &lockedRand{r: mathrand.New(mathrand.NewSource(seed)), seed: seed}
+}
+
+// stream returns the next job's generator, mixed from (seed, k) so the k-th job gets the same stream every run.
+func (lr *lockedRand) stream() *jobRand {
+	k := atomic.AddUint64(&lr.streams, 1)
+	return &jobRand{state: splitmix(uint64(lr.seed) ^ splitmix(k))}
+}
+
+func (r *jobRand) next() uint64 {
+	r.state += 0x9e3779b97f4a7c15
+	return splitmix(r.state)
+}
+
+// splitmix is the SplitMix64 finalizer.
+func splitmix(z uint64) uint64 {
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Float64 returns a value in [0, 1).
+func (r *jobRand) Float64() float64 {
+	return float64(r.next()>>11) / (1 << 53)
+}
+
+func (r *jobRand) Intn(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return int(r.next() % uint64(n))
 }
 
 func (lr *lockedRand) Intn(n int) int {
@@ -97,6 +154,12 @@ func (lr *lockedRand) Intn(n int) int {
 	return v
 }
 
+func (lr *lockedRand) Read(b []byte) {
+	lr.mu.Lock()
+	lr.r.Read(b)
+	lr.mu.Unlock()
+}
+
 func binaryBigEndian(b []byte) uint64 {
 	var v uint64
 	for _, c := range b {
@@ -105,7 +168,7 @@ func binaryBigEndian(b []byte) uint64 {
 	return v
 }
 
-func newBlockPicker(api *liteapi.Client, br blockRange, refresh time.Duration, rng *lockedRand) *blockPicker {
+func newBlockPicker(api *liteapi.Client, br blockRange, refresh time.Duration) *blockPicker {
 	return &blockPicker{
 		api:     api,
 		mode:    br.mode,
@@ -113,11 +176,11 @@ func newBlockPicker(api *liteapi.Client, br blockRange, refresh time.Duration, r
 		to:      br.to,
 		window:  br.count,
 		refresh: refresh,
-		rng:     rng,
 	}
 }
 
-func (p *blockPicker) pick(ctx context.Context) (int32, error) {
+// pick returns a random seqno of the picker's range, drawn from the job's stream r.
+func (p *blockPicker) pick(ctx context.Context, r *jobRand) (int32, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	if p.mode == "range" {
@@ -125,7 +188,7 @@ func (p *blockPicker) pick(ctx context.Context) (int32, error) {
 		if span <= 1 {
 			return p.from, nil
 		}
-		seq := p.from + int32(p.rng.Intn(span))
+		seq := p.from + int32(r.Intn(span))
 		return seq, nil
 	}
 	now := time.Now()
@@ -145,20 +208,18 @@ func (p *blockPicker) pick(ctx context.Context) (int32, error) {
 	if span <= 1 {
 		return p.latest, nil
 	}
-	seq := start + int32(p.rng.Intn(span))
+	seq := start + int32(r.Intn(span))
 	return seq, nil
 }
 
-func generateRandomAccounts(n int) ([]ton.AccountID, error) {
+func generateRandomAccounts(n int, rng *lockedRand) ([]ton.AccountID, error) {
 	if n <= 0 {
 		return nil, fmt.Errorf("invalid accounts count")
 	}
 	out := make([]ton.AccountID, 0, n)
 	for i := 0; i < n; i++ {
 		var addr [32]byte
-		if _, err := cryptorand.Read(addr[:]); err != nil {
-			return nil, err
-		}
+		rng.Read(addr[:])
 		out = append(out, ton.AccountID{Workchain: 0, Address: addr})
 	}
 	return out, nil
@@ -229,7 +290,8 @@ func warmupAccounts(api *liteapi.Client, timeout time.Duration, want int, mcBloc
 		wc   int32
 		addr [32]byte
 	}
-	seen := make(map[accountKey]ton.AccountID, want)
+	seen := make(map[accountKey]bool, want)
+	var out []ton.AccountID
 	for _, seq := range seqs {
 		if len(seen) >= want {
 			break
@@ -265,21 +327,18 @@ func warmupAccounts(api *liteapi.Client, timeout time.Duration, want int, mcBloc
 				var addr [32]byte
 				copy(addr[:], key[:])
 				k := accountKey{wc: wc, addr: addr}
-				if _, ok := seen[k]; ok {
+				if seen[k] {
 					continue
 				}
-				seen[k] = ton.AccountID{Workchain: wc, Address: addr}
+				seen[k] = true
+				out = append(out, ton.AccountID{Workchain: wc, Address: addr})
 			}
 		}
 	}
 
-	if len(seen) == 0 {
+	if len(out) == 0 {
 		return nil, fmt.Errorf("warmup collected zero accounts")
 	}
-	out := make([]ton.AccountID, 0, len(seen))
-	for _, v := range seen {
-		out = append(out, v)
-	}
 	if len(out) > want {
 		out = out[:want]
 	}
@@ -313,30 +372,37 @@ func buildBlockSeqs(api *liteapi.Client, br blockRange) ([]int32, error) {
 	return seqs, nil
 }
 
-func runBlockTest(api *liteapi.Client, cfgName, targets string, seqs []int32, conc int, timeout time.Duration, duration time.Duration, logger *reqLogger, randomBlocks bool, blocksRefresh time.Duration, rng *lockedRand, br blockRange) Result {
-	fmt.Printf("blocks: concurrency=%d, total=%d\n", conc, len(seqs))
+func runBlockTest(env *runEnv, clients *clientSet, cfgName, targets, mode string, seqs []int32, conc int, timeout time.Duration, duration time.Duration, logger *reqLogger, randomBlocks bool, blocksRefresh time.Duration, rng *lockedRand, br blockRange) Result {
+	fmt.Printf("%s: concurrency=%d, total=%d\n", mode, conc, len(seqs))
 	start := time.Now()
 	var picker *blockPicker
 	if randomBlocks {
-		picker = newBlockPicker(api, br, blocksRefresh, rng)
+		picker = newBlockPicker(clients.primary(), br, blocksRefresh)
 	}
-	work := func(i int) error {
+	var notFound int64
+	work := clients.wrap(func(api *liteapi.Client, i int) (err error) {
+		span := env.job(cfgName, targets, mode, conc)
+		defer span.end(&err)
 		seq := seqs[i]
 		if picker != nil {
 			ctx, cancel := context.WithTimeout(context.Background(), timeout)
-			ps, err := picker.pick(ctx)
+			ps, err := picker.pick(ctx, rng.stream())
 			cancel()
 			if err != nil {
 				return err
 			}
 			seq = ps
 		}
+		span.set("ton.block.seqno", seq)
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 		t0 := time.Now()
 		block, err := api.WaitMasterchainBlock(ctx, uint32(seq), 15*time.Second)
-		logRequest(logger, cfgName, targets, string(ModeBlocks), conc, "WaitMasterchainBlock", t0, 0, err)
+		env.logRequest(logger, span, cfgName, targets, mode, conc, "WaitMasterchainBlock", t0, 0, err)
 		if err != nil {
+			if isBlockUnavailable(classifyError(err.Error())) {
+				atomic.AddInt64(&notFound, 1)
+			}
 			return err
 		}
 		t1 := time.Now()
@@ -345,38 +411,21 @@ func runBlockTest(api *liteapi.Client, cfgName, targets string, seqs []int32, co
 		if err == nil {
 			respBytes = len(raw.Data)
 		}
-		logRequest(logger, cfgName, targets, string(ModeBlocks), conc, "GetBlockRaw", t1, respBytes, err)
+		env.logRequest(logger, span, cfgName, targets, mode, conc, "GetBlockRaw", t1, respBytes, err)
+		if err != nil && isBlockUnavailable(classifyError(err.Error())) {
+			atomic.AddInt64(&notFound, 1)
+		}
 		return err
-	}
+	})
 
-	var jr jobRun
-	if duration > 0 {
-		jr = runTimedJobs(len(seqs), conc, duration, work)
-	} else {
-		jr = runJobs(len(seqs), conc, work)
-	}
-	res := jr.result
-	res.Mode = string(ModeBlocks)
-	res.Concurrency = conc
-	if duration > 0 {
-		res.Total = res.Success + res.Errors
-		res.SeriesSec = jr.seriesSec
-		res.SeriesRPS = jr.seriesRPS
-		res.SeriesErr = jr.seriesErr
-		res.SeriesP50 = jr.seriesP50
-		res.SeriesP90 = jr.seriesP90
-		res.SeriesP95 = jr.seriesP95
-		res.SeriesP99 = jr.seriesP99
-		res.SeriesStart = jr.seriesStart
-	} else {
-		res.Total = len(seqs)
-	}
-	res.Duration = time.Since(start)
-	applyMetrics(&res, jr.durations)
+	jr := env.runWorkload(cfgName, mode, len(seqs), conc, duration, work)
+	res := env.finishResult(jr, mode, conc, len(seqs), duration, start)
+	res.NotFound = int(notFound)
+	clients.apply(&res)
 	return res
 }
 
-func runAccountTest(api *liteapi.Client, cfgName, targets string, accounts []ton.AccountID, conc int, timeout time.Duration, duration time.Duration, logger *reqLogger, randomPick bool, rng *lockedRand) Result {
+func runAccountTest(env *runEnv, clients *clientSet, cfgName, targets string, accounts []ton.AccountID, conc int, timeout time.Duration, duration time.Duration, logger *reqLogger, randomPick bool, rng *lockedRand) Result {
 	fmt.Printf("accounts: concurrency=%d, total=%d\n", conc, len(accounts))
 	start := time.Now()
 	var master ton.BlockIDExt
@@ -384,48 +433,96 @@ func runAccountTest(api *liteapi.Client, cfgName, targets string, accounts []ton
 	{
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		t0 := time.Now()
-		info, err := api.GetMasterchainInfo(ctx)
+		info, err := clients.primary().GetMasterchainInfo(ctx)
 		if err != nil {
 			masterErr = err
 		} else {
 			master = info.Last.ToBlockIdExt()
 		}
-		logRequest(logger, cfgName, targets, string(ModeAccounts), conc, "GetMasterchainInfo", t0, 0, masterErr)
+		env.logRequest(logger, nil, cfgName, targets, string(ModeAccounts), conc, "GetMasterchainInfo", t0, 0, masterErr)
 		cancel()
 	}
-	targetClient := api
-	if masterErr == nil {
-		targetClient = api.WithBlock(master)
-	}
-	work := func(i int) error {
+	work := clients.wrap(func(api *liteapi.Client, i int) (err error) {
+		span := env.job(cfgName, targets, string(ModeAccounts), conc)
+		defer span.end(&err)
 		idx := i
 		if randomPick {
-			idx = rng.Intn(len(accounts))
+			idx = rng.stream().Intn(len(accounts))
 		}
 		addr := accounts[idx]
+		span.set("ton.account", addr.ToRaw())
 		if masterErr != nil {
 			return masterErr
 		}
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 		t0 := time.Now()
-		raw, err := targetClient.GetAccountStateRaw(ctx, addr)
+		raw, err := api.WithBlock(master).GetAccountStateRaw(ctx, addr)
 		respBytes := 0
 		if err == nil {
 			respBytes = len(raw.State) + len(raw.Proof) + len(raw.ShardProof)
 		}
-		logRequest(logger, cfgName, targets, string(ModeAccounts), conc, "GetAccountStateRaw", t0, respBytes, err)
+		env.logRequest(logger, span, cfgName, targets, string(ModeAccounts), conc, "GetAccountStateRaw", t0, respBytes, err)
 		return err
+	})
+
+	jr := env.runWorkload(cfgName, string(ModeAccounts), len(accounts), conc, duration, work)
+	res := env.finishResult(jr, string(ModeAccounts), conc, len(accounts), duration, start)
+	clients.apply(&res)
+	return res
+}
+
+// runEnv is the per-run state every shooter gets, set up once from flags; nil fields are off.
+type runEnv struct {
+	// shape scales every timed run; rateShape is the rate it scales, 0 scales the worker count
+	shape     *loadProfile
+	rateShape float64
+	soak      *soakCheckpoints
+	barrier   *barrierClient
+	payloads  *payloadRecorder
+	// tracer is set from --trace; nil leaves the jobs untraced
+	tracer *traceExporter
+	// samples is set from --error-samples; nil keeps no samples
+	samples *errorSampler
+	// histograms keeps latency histograms (overall and per second) in results so runs can be merged
+	histograms bool
+}
+
+// job starts a job's root span; with error samples but no tracing the span only carries the params.
+func (e *runEnv) job(cfg, targets, mode string, conc int) *traceSpan {
+	if e.tracer == nil && e.samples != nil {
+		return &traceSpan{}
 	}
+	return e.tracer.job(cfg, targets, mode, conc)
+}
+
+// runWorkload runs fn over itemCount items, timed when duration > 0, after the agent's start barrier.
+func (e *runEnv) runWorkload(cfgName, mode string, itemCount, conc int, duration time.Duration, fn func(i int) error) jobRun {
+	waited := e.barrier.wait(cfgName, mode, conc)
+	jr := e.runUnsynced(cfgName, mode, itemCount, conc, duration, fn)
+	jr.waited = waited
+	return jr
+}
 
-	var jr jobRun
+func (e *runEnv) runUnsynced(cfgName, mode string, itemCount, conc int, duration time.Duration, fn func(i int) error) jobRun {
+	if duration > 0 && e.soak != nil {
+		return e.soak.run(cfgName, mode, itemCount, conc, duration, fn)
+	}
+	if duration > 0 && e.shape != nil {
+		if e.rateShape > 0 {
+			return runShapedRate(itemCount, e.rateShape, conc, duration, e.shape, fn)
+		}
+		return runShapedJobs(itemCount, conc, duration, e.shape, fn)
+	}
 	if duration > 0 {
-		jr = runTimedJobs(len(accounts), conc, duration, work)
-	} else {
-		jr = runJobs(len(accounts), conc, work)
+		return runTimedJobs(itemCount, conc, duration, fn)
 	}
+	return runJobs(itemCount, conc, fn)
+}
+
+func (e *runEnv) finishResult(jr jobRun, mode string, conc, itemCount int, duration time.Duration, start time.Time) Result {
 	res := jr.result
-	res.Mode = string(ModeAccounts)
+	res.Mode = mode
 	res.Concurrency = conc
 	if duration > 0 {
 		res.Total = res.Success + res.Errors
@@ -437,11 +534,29 @@ func runAccountTest(api *liteapi.Client, cfgName, targets string, accounts []ton
 		res.SeriesP95 = jr.seriesP95
 		res.SeriesP99 = jr.seriesP99
 		res.SeriesStart = jr.seriesStart
+		res.SeriesTarget = jr.seriesTarget
+		if jr.seriesTarget != nil {
+			res.Profile = e.shape.Spec
+			res.ProfileUnit = "workers"
+			if e.rateShape > 0 {
+				res.ProfileUnit = "rps"
+			}
+		}
+		if len(jr.soakIntervals) > 0 {
+			res.SoakIntervals = jr.soakIntervals
+			res.SoakDrift = e.soak.summary(jr.soakIntervals)
+		}
+		if e.histograms {
+			res.SeriesHist = jr.seriesHist
+		}
 	} else {
-		res.Total = len(accounts)
+		res.Total = itemCount
 	}
-	res.Duration = time.Since(start)
+	res.Duration = time.Since(start) - jr.waited
 	applyMetrics(&res, jr.durations)
+	if e.histograms {
+		res.Hist = histogramOf(jr.durations)
+	}
 	return res
 }
 
@@ -493,6 +608,90 @@ func runJobs(total, conc int, fn func(i int) error) jobRun {
 	}
 }
 
+// jobRecorder collects outcomes of a timed run into totals and per-second series.
+type jobRecorder struct {
+	start     time.Time
+	buckets   int
+	mu        sync.Mutex
+	durations []int64
+	successes int64
+	errors    int64
+	perSec    [][]int64
+	perSecMu  []sync.Mutex
+	okCounts  []int64
+	errCounts []int64
+}
+
+func newJobRecorder(start time.Time, duration time.Duration) *jobRecorder {
+	buckets := int(math.Ceil(duration.Seconds()))
+	if buckets < 1 {
+		buckets = 1
+	}
+	return &jobRecorder{
+		start:     start,
+		buckets:   buckets,
+		durations: make([]int64, 0, buckets*10),
+		perSec:    make([][]int64, buckets),
+		perSecMu:  make([]sync.Mutex, buckets),
+		okCounts:  make([]int64, buckets),
+		errCounts: make([]int64, buckets),
+	}
+}
+
+// record files one job by the second it finished in.
+func (r *jobRecorder) record(d int64, err error) {
+	sec := int(time.Since(r.start).Seconds())
+	if sec >= 0 && sec < r.buckets {
+		if err != nil {
+			atomic.AddInt64(&r.errCounts[sec], 1)
+		} else {
+			atomic.AddInt64(&r.okCounts[sec], 1)
+			r.perSecMu[sec].Lock()
+			r.perSec[sec] = append(r.perSec[sec], d)
+			r.perSecMu[sec].Unlock()
+		}
+	}
+
+	r.mu.Lock()
+	if err != nil {
+		r.durations = append(r.durations, -1)
+		r.errors++
+	} else {
+		r.durations = append(r.durations, d)
+		r.successes++
+	}
+	r.mu.Unlock()
+}
+
+func (r *jobRecorder) jobRun() jobRun {
+	seriesSec := make([]int, r.buckets)
+	// kept in results only with --histograms, see finishResult
+	seriesHist := make([][]histBin, r.buckets)
+	for i, sec := range r.perSec {
+		seriesHist[i] = histogramOf(sec)
+	}
+	seriesP50, seriesP90, seriesP95, seriesP99 := percentileSeries(r.perSec)
+	for i := 0; i < r.buckets; i++ {
+		seriesSec[i] = i + 1
+	}
+	return jobRun{
+		seriesHist: seriesHist,
+		result: Result{
+			Success: int(r.successes),
+			Errors:  int(r.errors),
+		},
+		durations:   r.durations,
+		seriesSec:   seriesSec,
+		seriesRPS:   countsToFloat64(r.okCounts),
+		seriesErr:   countsToFloat64(r.errCounts),
+		seriesP50:   seriesP50,
+		seriesP90:   seriesP90,
+		seriesP95:   seriesP95,
+		seriesP99:   seriesP99,
+		seriesStart: r.start.UTC().UnixMilli(),
+	}
+}
+
 func runTimedJobs(itemCount, conc int, duration time.Duration, fn func(i int) error) jobRun {
 	if itemCount <= 0 {
 		return jobRun{result: Result{Errors: 1}}
@@ -501,21 +700,10 @@ func runTimedJobs(itemCount, conc int, duration time.Duration, fn func(i int) er
 		conc = 1
 	}
 
-	var successes int64
-	var errors int64
 	var idx uint64
 	start := time.Now()
 	deadline := start.Add(duration)
-	buckets := int(math.Ceil(duration.Seconds()))
-	if buckets < 1 {
-		buckets = 1
-	}
-	var mu sync.Mutex
-	perSec := make([][]int64, buckets)
-	perSecMu := make([]sync.Mutex, buckets)
-	durations := make([]int64, 0, conc*10)
-	okCounts := make([]int64, buckets)
-	errCounts := make([]int64, buckets)
+	rec := newJobRecorder(start, duration)
 	var wg sync.WaitGroup
 	for w := 0; w < conc; w++ {
 		wg.Add(1)
@@ -528,79 +716,108 @@ func runTimedJobs(itemCount, conc int, duration time.Duration, fn func(i int) er
 				i := int(atomic.AddUint64(&idx, 1)-1) % itemCount
 				t0 := time.Now()
 				err := fn(i)
-				d := time.Since(t0).Milliseconds()
-
-				sec := int(time.Since(start).Seconds())
-				if sec >= 0 && sec < buckets {
-					if err != nil {
-						atomic.AddInt64(&errCounts[sec], 1)
-					} else {
-						atomic.AddInt64(&okCounts[sec], 1)
-						perSecMu[sec].Lock()
-						perSec[sec] = append(perSec[sec], d)
-						perSecMu[sec].Unlock()
-					}
-				}
-
-				mu.Lock()
-				if err != nil {
-					durations = append(durations, -1)
-					atomic.AddInt64(&errors, 1)
-				} else {
-					durations = append(durations, d)
-					atomic.AddInt64(&successes, 1)
-				}
-				mu.Unlock()
+				rec.record(time.Since(t0).Milliseconds(), err)
 			}
 		}()
 	}
 	wg.Wait()
+	return rec.jobRun()
+}
 
-	seriesSec := make([]int, buckets)
-	seriesRPS := countsToFloat64(okCounts)
-	seriesErr := countsToFloat64(errCounts)
-	seriesP50, seriesP90, seriesP95, seriesP99 := percentileSeries(perSec)
-	for i := 0; i < buckets; i++ {
-		seriesSec[i] = i + 1
+// errSaturated is recorded for paced launches skipped because maxInFlight jobs were still running.
+var errSaturated = errors.New("load generator saturated: too many jobs in flight")
+
+// runPacedJobs starts fn at rate per second, up to maxInFlight at once, until total launches or duration.
+func runPacedJobs(rate float64, total, maxInFlight int, duration time.Duration, fn func(i int) error) jobRun {
+	if rate <= 0 {
+		return jobRun{result: Result{Errors: 1}}
 	}
+	return runRateJobs(func(time.Duration) float64 { return rate }, total, maxInFlight, duration, fn)
+}
 
-	return jobRun{
-		result: Result{
-			Success: int(successes),
-			Errors:  int(errors),
-		},
-		durations:   durations,
-		seriesSec:   seriesSec,
-		seriesRPS:   seriesRPS,
-		seriesErr:   seriesErr,
-		seriesP50:   seriesP50,
-		seriesP90:   seriesP90,
-		seriesP95:   seriesP95,
-		seriesP99:   seriesP99,
-		seriesStart: start.UTC().UnixMilli(),
+// runRateJobs is runPacedJobs with a rate that may change over the run; rateAt gets the time since start.
+// Without a duration the rate must be constant, since the run length is derived from it.
+func runRateJobs(rateAt func(elapsed time.Duration) float64, total, maxInFlight int, duration time.Duration, fn func(i int) error) jobRun {
+	if total <= 0 && duration <= 0 {
+		return jobRun{result: Result{Errors: 1}}
+	}
+	if maxInFlight <= 0 {
+		maxInFlight = 1
+	}
+	start := time.Now()
+	span := duration
+	if span <= 0 {
+		span = time.Duration(float64(total) / rateAt(0) * float64(time.Second))
+	}
+	rec := newJobRecorder(start, span)
+	sem := make(chan struct{}, maxInFlight)
+	var wg sync.WaitGroup
+	next := start
+	for i := 0; total <= 0 || i < total; i++ {
+		if duration > 0 && !next.Before(start.Add(duration)) {
+			break
+		}
+		time.Sleep(time.Until(next))
+		next = next.Add(time.Duration(float64(time.Second) / rateAt(next.Sub(start))))
+		select {
+		case sem <- struct{}{}:
+		default:
+			rec.record(0, errSaturated)
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			t0 := time.Now()
+			err := fn(i)
+			rec.record(time.Since(t0).Milliseconds(), err)
+		}(i)
 	}
+	wg.Wait()
+	return rec.jobRun()
 }
 
 // metrics helpers moved to metrics.go
 
-func newReqLogger(path string) (*reqLogger, error) {
-	f, err := os.Create(path)
+// newReqLogger creates the log at path, or appends to it when appendMode is set (used by --resume).
+// The format follows the path: .bin is the binary column log, anything else JSONL.
+func newReqLogger(path string, appendMode bool) (*reqLogger, error) {
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendMode {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	z, err := newLogCompressor(f, path)
 	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w := bufio.NewWriterSize(z, 1<<20)
+	enc, err := newLogEncoder(w, path)
+	if err != nil {
+		f.Close()
 		return nil, err
 	}
 	l := &reqLogger{
-		ch: make(chan logEntry, 10000),
-		w:  bufio.NewWriterSize(f, 1<<20),
-		f:  f,
+		ch:    make(chan logEntry, 1<<16),
+		w:     w,
+		z:     z,
+		f:     f,
+		drops: map[cellKey]int{},
 	}
 	l.wg.Add(1)
 	go func() {
 		defer l.wg.Done()
-		enc := json.NewEncoder(l.w)
 		for entry := range l.ch {
-			_ = enc.Encode(entry)
+			_ = enc.encode(entry)
 		}
+		_ = enc.flush()
 		_ = l.w.Flush()
+		_ = l.z.Close()
 		_ = l.f.Close()
 	}()
 	return l, nil
@@ -612,14 +829,44 @@ func (l *reqLogger) Close() {
 	}
 	close(l.ch)
 	l.wg.Wait()
+	if l.dropped > 0 {
+		fmt.Printf("Per-request log: dropped %d entries the writer could not keep up with\n", l.dropped)
+	}
+}
+
+func (l *reqLogger) drop(cfg, mode string, conc int) {
+	l.mu.Lock()
+	l.drops[cellKey{cfg, mode, conc}]++
+	l.dropped++
+	l.mu.Unlock()
+}
+
+// droppedIn is the number of entries of a cell that did not make it into the log.
+func (l *reqLogger) droppedIn(cfg, mode string, conc int) int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.drops[cellKey{cfg, mode, conc}]
+}
+
+func (e *runEnv) logRequest(l *reqLogger, span *traceSpan, cfg, targets, mode string, conc int, req string, start time.Time, respBytes int, err error) {
+	e.logRequestExit(l, span, cfg, targets, mode, conc, req, start, respBytes, 0, err)
 }
 
-func logRequest(l *reqLogger, cfg, targets, mode string, conc int, req string, start time.Time, respBytes int, err error) {
+// logRequestExit is logRequest for calls that also carry a TVM exit code.
+func (e *runEnv) logRequestExit(l *reqLogger, span *traceSpan, cfg, targets, mode string, conc int, req string, start time.Time, respBytes int, exitCode int, err error) {
+	if respBytes > 0 {
+		e.payloads.add(cfg, mode, conc, req, start, respBytes)
+	}
+	span.request(req, start, respBytes, exitCode, err)
+	e.samples.add(span, cfg, targets, mode, conc, req, start, err)
 	if l == nil {
 		return
 	}
 	entry := logEntry{
-		Ts:          start.UTC().Format(time.RFC3339Nano),
+		t:           start,
 		Config:      cfg,
 		Targets:     targets,
 		Mode:        mode,
@@ -628,9 +875,14 @@ func logRequest(l *reqLogger, cfg, targets, mode string, conc int, req string, s
 		RespBytes:   respBytes,
 		OK:          err == nil,
 		LatencyMs:   time.Since(start).Milliseconds(),
+		ExitCode:    exitCode,
 	}
 	if err != nil {
 		entry.Error = err.Error()
 	}
-	l.ch <- entry
+	select {
+	case l.ch <- entry:
+	default:
+		l.drop(cfg, mode, conc)
+	}
 }
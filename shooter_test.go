@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestLockedRandStreams(t *testing.T) {
+	draw := func(r *jobRand, n int) []uint64 {
+		out := make([]uint64, n)
+		for i := range out {
+			out[i] = r.next()
+		}
+		return out
+	}
+	const n = 1000
+	a, b := newLockedRand(42), newLockedRand(42)
+	var streams [][]uint64
+	for k := 0; k < 4; k++ {
+		sa, sb := draw(a.stream(), n), draw(b.stream(), n)
+		for i := range sa {
+			if sa[i] != sb[i] {
+				t.Fatalf("stream %d differs at draw %d for the same seed", k, i)
+			}
+		}
+		streams = append(streams, sa)
+	}
+
+	// streams of one seed share no values, so none is a shifted copy of another
+	seen := map[uint64]int{}
+	for k, s := range streams {
+		for _, v := range s {
+			if prev, ok := seen[v]; ok && prev != k {
+				t.Fatalf("streams %d and %d overlap", prev, k)
+			}
+			seen[v] = k
+		}
+	}
+
+	other := draw(newLockedRand(43).stream(), n)
+	if other[0] == streams[0][0] && other[1] == streams[0][1] {
+		t.Fatal("seeds 42 and 43 start the same stream")
+	}
+}
+
+func TestJobRandRange(t *testing.T) {
+	r := newLockedRand(1).stream()
+	counts := make([]int, 5)
+	for i := 0; i < 10000; i++ {
+		if f := r.Float64(); f < 0 || f >= 1 {
+			t.Fatalf("Float64 = %v", f)
+		}
+		counts[r.Intn(5)]++
+	}
+	for v, c := range counts {
+		if c < 1800 || c > 2200 {
+			t.Fatalf("Intn(5) drew %d %d times out of 10000", v, c)
+		}
+	}
+	if r.Intn(0) != 0 || r.Intn(-3) != 0 {
+		t.Fatal("Intn of an empty range")
+	}
+}
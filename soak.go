@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// soakReservoir bounds the latency and payload samples a soak run keeps for its overall percentiles.
+const soakReservoir = 100000
+
+// An interval has an error burst when its error rate is soakBurstFactor times the baseline's
+// and at least soakBurstMin above it.
+const (
+	soakBurstFactor = 2
+	soakBurstMin    = 0.01
+)
+
+// SoakStats is the interval checkpoints of a soak result and the drift found between them.
+type SoakStats struct {
+	SoakIntervals []soakInterval `json:"soak_intervals,omitempty"`
+	SoakDrift     []string       `json:"soak_drift,omitempty"`
+}
+
+// soakInterval is one checkpoint of a soak run, as appended to soak.jsonl.
+// Drift lists what moved past the tolerance compared with the first interval: latency, rps or errors.
+type soakInterval struct {
+	Config      string   `json:"config"`
+	Mode        string   `json:"mode"`
+	Concurrency int      `json:"concurrency"`
+	Index       int      `json:"interval"`
+	StartMs     int64    `json:"start_ms"`
+	EndMs       int64    `json:"end_ms"`
+	Success     int      `json:"success"`
+	Errors      int      `json:"errors"`
+	ErrorRate   float64  `json:"error_rate"`
+	RPS         float64  `json:"rps"`
+	AvgMs       float64  `json:"avg_ms"`
+	P50Ms       float64  `json:"p50_ms"`
+	P95Ms       float64  `json:"p95_ms"`
+	P99Ms       float64  `json:"p99_ms"`
+	MaxMs       float64  `json:"max_ms"`
+	Drift       []string `json:"drift,omitempty"`
+}
+
+// soakCheckpoints splits every timed run into intervals and appends each interval's summary
+// to an append-only file as soon as it ends, so a long run keeps only one interval of samples in memory.
+type soakCheckpoints struct {
+	interval  time.Duration
+	tolerance float64
+	rng       *lockedRand
+	mu        sync.Mutex
+	f         *os.File
+}
+
+func newSoakCheckpoints(path string, interval time.Duration, tolerance float64, rng *lockedRand) (*soakCheckpoints, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &soakCheckpoints{interval: interval, tolerance: tolerance, rng: rng, f: f}, nil
+}
+
+func (s *soakCheckpoints) write(iv soakInterval) error {
+	line, err := json.Marshal(iv)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+func (s *soakCheckpoints) Close() error {
+	return s.f.Close()
+}
+
+// run is runTimedJobs with its jobs recorded per checkpoint interval. The workers run through the whole
+// duration; counts and per-second series cover the run, overall percentiles come from a reservoir sample of
+// soakReservoir latencies.
+func (s *soakCheckpoints) run(cfgName, mode string, itemCount, conc int, duration time.Duration, fn func(i int) error) jobRun {
+	if itemCount <= 0 {
+		return jobRun{result: Result{Errors: 1}}
+	}
+	conc = max(conc, 1)
+	start := time.Now()
+	rec := &soakRecorder{s: s, cfgName: cfgName, mode: mode, conc: conc, end: start.Add(duration), rnd: s.rng.stream()}
+	rec.out.seriesStart = start.UTC().UnixMilli()
+	rec.begin(start)
+
+	done := make(chan struct{})
+	go func() {
+		// cuts intervals in which no job finishes
+		for {
+			rec.mu.Lock()
+			next, last := rec.ivEnd, !rec.ivEnd.Before(rec.end)
+			rec.mu.Unlock()
+			if last {
+				return
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Until(next)):
+				rec.mu.Lock()
+				rec.advance(time.Now())
+				rec.mu.Unlock()
+			}
+		}
+	}()
+
+	var idx uint64
+	var wg sync.WaitGroup
+	for w := 0; w < conc; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(rec.end) {
+				i := int(atomic.AddUint64(&idx, 1)-1) % itemCount
+				t0 := time.Now()
+				err := fn(i)
+				rec.record(time.Since(t0).Milliseconds(), err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	now := time.Now()
+	rec.advance(now)
+	rec.cut(now)
+	return rec.out
+}
+
+// soakRecorder files the jobs of one soak run into the interval they finished in.
+type soakRecorder struct {
+	s       *soakCheckpoints
+	cfgName string
+	mode    string
+	conc    int
+	end     time.Time
+	rnd     *jobRand
+
+	mu      sync.Mutex
+	cur     *jobRecorder
+	ivStart time.Time
+	ivEnd   time.Time
+	out     jobRun
+	seen    int64
+}
+
+// begin starts the next interval at t; a tail shorter than a second joins it rather than becoming an interval.
+func (r *soakRecorder) begin(t time.Time) {
+	end := t.Add(r.s.interval)
+	if r.end.Sub(end) < time.Second {
+		end = r.end
+	}
+	r.ivStart, r.ivEnd = t, end
+	r.cur = newJobRecorder(t, end.Sub(t))
+}
+
+func (r *soakRecorder) record(d int64, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.advance(time.Now())
+	r.cur.record(d, err)
+}
+
+// advance closes every interval that ended by now. The last interval stays open until the workers stop.
+func (r *soakRecorder) advance(now time.Time) {
+	for !now.Before(r.ivEnd) && r.ivEnd.Before(r.end) {
+		end := r.ivEnd
+		r.cut(end)
+		r.begin(end)
+	}
+}
+
+// cut summarizes the current interval, writes its checkpoint and adds it to the run.
+func (r *soakRecorder) cut(end time.Time) {
+	jr := r.cur.jobRun()
+	elapsed := end.Sub(r.ivStart)
+	iv := soakInterval{
+		Config:      r.cfgName,
+		Mode:        r.mode,
+		Concurrency: r.conc,
+		Index:       len(r.out.soakIntervals) + 1,
+		StartMs:     r.ivStart.UTC().UnixMilli(),
+		EndMs:       end.UTC().UnixMilli(),
+		Success:     jr.result.Success,
+		Errors:      jr.result.Errors,
+	}
+	if n := iv.Success + iv.Errors; n > 0 {
+		iv.ErrorRate = float64(iv.Errors) / float64(n)
+	}
+	iv.RPS = float64(iv.Success) / elapsed.Seconds()
+	iv.AvgMs, iv.P50Ms, _, iv.P95Ms, iv.P99Ms, iv.MaxMs = computeMetrics(jr.durations, iv.Success)
+	if len(r.out.soakIntervals) > 0 {
+		iv.Drift = r.s.drift(r.out.soakIntervals[0], iv)
+	}
+	r.out.soakIntervals = append(r.out.soakIntervals, iv)
+	if err := r.s.write(iv); err != nil {
+		fmt.Printf("soak checkpoint write failed: %v\n", err)
+	}
+	fmt.Printf("  soak %s c%d #%d: rps=%.2f p95=%.1fms err=%.2f%%%s\n",
+		r.mode, r.conc, iv.Index, iv.RPS, iv.P95Ms, iv.ErrorRate*100, driftSuffix(iv.Drift))
+
+	out := &r.out
+	out.result.Success += jr.result.Success
+	out.result.Errors += jr.result.Errors
+	for _, d := range jr.durations {
+		if d < 0 {
+			continue
+		}
+		out.durations = reservoirAdd(out.durations, r.seen, d, r.rnd)
+		r.seen++
+	}
+	offset := len(out.seriesSec)
+	for k := range jr.seriesSec {
+		out.seriesSec = append(out.seriesSec, offset+k+1)
+	}
+	out.seriesRPS = append(out.seriesRPS, jr.seriesRPS...)
+	out.seriesErr = append(out.seriesErr, jr.seriesErr...)
+	out.seriesP50 = append(out.seriesP50, jr.seriesP50...)
+	out.seriesP90 = append(out.seriesP90, jr.seriesP90...)
+	out.seriesP95 = append(out.seriesP95, jr.seriesP95...)
+	out.seriesP99 = append(out.seriesP99, jr.seriesP99...)
+	out.seriesHist = append(out.seriesHist, jr.seriesHist...)
+}
+
+// drift compares one interval against the baseline (first) interval.
+func (s *soakCheckpoints) drift(base, iv soakInterval) []string {
+	var flags []string
+	if base.P95Ms > 0 && iv.P95Ms > base.P95Ms*(1+s.tolerance) {
+		flags = append(flags, "latency")
+	}
+	if base.RPS > 0 && iv.RPS < base.RPS*(1-s.tolerance) {
+		flags = append(flags, "rps")
+	}
+	if iv.ErrorRate >= base.ErrorRate*soakBurstFactor && iv.ErrorRate >= base.ErrorRate+soakBurstMin {
+		flags = append(flags, "errors")
+	}
+	return flags
+}
+
+// summary turns the intervals of one run into drift findings: fitted trends of P95 and RPS that
+// moved more than the tolerance over the run, and intervals with error bursts.
+func (s *soakCheckpoints) summary(intervals []soakInterval) []string {
+	if len(intervals) < 2 {
+		return nil
+	}
+	base := intervals[0]
+	var out []string
+	if len(intervals) >= 3 {
+		p95 := make([]float64, len(intervals))
+		rps := make([]float64, len(intervals))
+		for i, iv := range intervals {
+			p95[i], rps[i] = iv.P95Ms, iv.RPS
+		}
+		span := float64(len(intervals) - 1)
+		if rise := linearSlope(p95) * span; base.P95Ms > 0 && rise > base.P95Ms*s.tolerance {
+			out = append(out, fmt.Sprintf("latency trending up: fitted p95 +%.0f%% over %d intervals (baseline %.1fms)",
+				rise/base.P95Ms*100, len(intervals), base.P95Ms))
+		}
+		if drop := -linearSlope(rps) * span; base.RPS > 0 && drop > base.RPS*s.tolerance {
+			out = append(out, fmt.Sprintf("rps degrading: fitted rps -%.0f%% over %d intervals (baseline %.1f)",
+				drop/base.RPS*100, len(intervals), base.RPS))
+		}
+	}
+	var slow, starved, bursts []int
+	worst := base
+	for _, iv := range intervals[1:] {
+		for _, f := range iv.Drift {
+			switch f {
+			case "latency":
+				slow = append(slow, iv.Index)
+			case "rps":
+				starved = append(starved, iv.Index)
+			case "errors":
+				bursts = append(bursts, iv.Index)
+				if iv.ErrorRate > worst.ErrorRate {
+					worst = iv
+				}
+			}
+		}
+	}
+	if len(slow) > 0 {
+		out = append(out, fmt.Sprintf("p95 above baseline +%.0f%% in intervals %s", s.tolerance*100, joinInts(slow)))
+	}
+	if len(starved) > 0 {
+		out = append(out, fmt.Sprintf("rps below baseline -%.0f%% in intervals %s", s.tolerance*100, joinInts(starved)))
+	}
+	if len(bursts) > 0 {
+		out = append(out, fmt.Sprintf("error bursts in intervals %s (worst %.2f%% at #%d, baseline %.2f%%)",
+			joinInts(bursts), worst.ErrorRate*100, worst.Index, base.ErrorRate*100))
+	}
+	return out
+}
+
+// linearSlope is the least-squares slope of ys over their indexes.
+func linearSlope(ys []float64) float64 {
+	n := float64(len(ys))
+	var sx, sy, sxy, sxx float64
+	for i, y := range ys {
+		x := float64(i)
+		sx += x
+		sy += y
+		sxy += x * y
+		sxx += x * x
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0
+	}
+	return (n*sxy - sx*sy) / den
+}
+
+// reservoirAdd keeps a uniform sample of at most soakReservoir values; seen counts values offered before v.
+func reservoirAdd(sample []int64, seen int64, v int64, rnd *jobRand) []int64 {
+	if len(sample) < soakReservoir {
+		return append(sample, v)
+	}
+	if j := rnd.Intn(int(seen + 1)); j < soakReservoir {
+		sample[j] = v
+	}
+	return sample
+}
+
+func driftSuffix(flags []string) string {
+	if len(flags) == 0 {
+		return ""
+	}
+	return " drift=" + strings.Join(flags, ",")
+}
+
+func joinInts(vals []int) string {
+	parts := make([]string, len(vals))
+	for i, v := range vals {
+		parts[i] = fmt.Sprintf("#%d", v)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinearSlope(t *testing.T) {
+	tests := []struct {
+		ys   []float64
+		want float64
+	}{
+		{nil, 0},
+		{[]float64{7}, 0},
+		{[]float64{3, 3, 3}, 0},
+		{[]float64{1, 2, 3, 4}, 1},
+		{[]float64{10, 8, 6}, -2},
+		{[]float64{1, 3, 2, 4}, 0.8},
+	}
+	for _, tt := range tests {
+		if got := linearSlope(tt.ys); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("linearSlope(%v) = %f, want %f", tt.ys, got, tt.want)
+		}
+	}
+}
+
+func TestSoakDrift(t *testing.T) {
+	s := &soakCheckpoints{tolerance: 0.2}
+	base := soakInterval{P95Ms: 100, RPS: 50, ErrorRate: 0.01}
+	tests := []struct {
+		name string
+		iv   soakInterval
+		want []string
+	}{
+		{"steady", soakInterval{P95Ms: 119, RPS: 41, ErrorRate: 0.015}, nil},
+		{"slow", soakInterval{P95Ms: 121, RPS: 50, ErrorRate: 0.01}, []string{"latency"}},
+		{"starved", soakInterval{P95Ms: 100, RPS: 39, ErrorRate: 0.01}, []string{"rps"}},
+		// twice the baseline but not a point above it
+		{"small burst", soakInterval{P95Ms: 100, RPS: 50, ErrorRate: 0.019}, nil},
+		{"burst", soakInterval{P95Ms: 100, RPS: 50, ErrorRate: 0.05}, []string{"errors"}},
+		{"all", soakInterval{P95Ms: 200, RPS: 10, ErrorRate: 0.5}, []string{"latency", "rps", "errors"}},
+	}
+	for _, tt := range tests {
+		if got := s.drift(base, tt.iv); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: drift %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// without errors in the baseline, any interval with 1% errors is a burst
+	if got := s.drift(soakInterval{RPS: 50}, soakInterval{RPS: 50, ErrorRate: 0.01}); !reflect.DeepEqual(got, []string{"errors"}) {
+		t.Errorf("burst over a clean baseline: %v", got)
+	}
+}
+
+func TestSoakSummary(t *testing.T) {
+	s := &soakCheckpoints{tolerance: 0.2}
+	if got := s.summary([]soakInterval{{Index: 1, P95Ms: 10, RPS: 10}}); got != nil {
+		t.Fatalf("one interval: %v", got)
+	}
+	// p95 creeps up 10% per interval: no single interval is flagged until the 3rd, the trend is
+	var ivs []soakInterval
+	for i := 0; i < 4; i++ {
+		iv := soakInterval{Index: i + 1, P95Ms: 100 + 10*float64(i), RPS: 50}
+		if i > 0 {
+			iv.Drift = s.drift(ivs[0], iv)
+		}
+		ivs = append(ivs, iv)
+	}
+	got := strings.Join(s.summary(ivs), "\n")
+	if !strings.Contains(got, "latency trending up: fitted p95 +30% over 4 intervals") {
+		t.Errorf("summary without the trend:\n%s", got)
+	}
+	if !strings.Contains(got, "p95 above baseline +20% in intervals #4") {
+		t.Errorf("summary without the slow interval:\n%s", got)
+	}
+	if strings.Contains(got, "rps") {
+		t.Errorf("flat rps flagged:\n%s", got)
+	}
+}
+
+func TestReservoirAddSeeded(t *testing.T) {
+	fill := func(seed int64) []int64 {
+		rnd := newLockedRand(seed).stream()
+		var sample []int64
+		for i := int64(0); i < 2*soakReservoir; i++ {
+			sample = reservoirAdd(sample, i, i, rnd)
+		}
+		return sample
+	}
+	a, b := fill(1), fill(1)
+	if len(a) != soakReservoir {
+		t.Fatalf("sample of %d, want %d", len(a), soakReservoir)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Fatal("the same seed sampled differently")
+	}
+	if reflect.DeepEqual(a, fill(2)) {
+		t.Fatal("different seeds sampled the same")
+	}
+}
+
+func TestSoakRunIntervals(t *testing.T) {
+	s, err := newSoakCheckpoints(filepath.Join(t.TempDir(), "soak.jsonl"), time.Second, 0.2, newLockedRand(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	errFail := errors.New("fail")
+	jr := s.run("c", "blocks", 10, 2, 2200*time.Millisecond, func(i int) error {
+		time.Sleep(5 * time.Millisecond)
+		if i == 0 {
+			return errFail
+		}
+		return nil
+	})
+	// the 200ms tail joins the second interval
+	if len(jr.soakIntervals) != 2 {
+		t.Fatalf("%d intervals, want 2", len(jr.soakIntervals))
+	}
+	first, second := jr.soakIntervals[0], jr.soakIntervals[1]
+	if first.EndMs != second.StartMs || first.EndMs-first.StartMs != 1000 {
+		t.Fatalf("intervals %d-%d and %d-%d are not contiguous", first.StartMs, first.EndMs, second.StartMs, second.EndMs)
+	}
+	if first.Success == 0 || second.Success == 0 {
+		t.Fatalf("empty interval: %+v %+v", first, second)
+	}
+	if jr.result.Success != first.Success+second.Success || jr.result.Errors != first.Errors+second.Errors {
+		t.Fatalf("run %d/%d, intervals %d/%d and %d/%d", jr.result.Success, jr.result.Errors, first.Success, first.Errors, second.Success, second.Errors)
+	}
+	if len(jr.seriesSec) != 3 || len(jr.seriesRPS) != 3 {
+		t.Fatalf("series of %d seconds, want 1 + 2", len(jr.seriesSec))
+	}
+}
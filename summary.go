@@ -0,0 +1,202 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// summaryTopErrors is how many error groups the Markdown and terminal summaries list.
+const summaryTopErrors = 10
+
+// summaryCheck is a pass/fail outcome of the run: consistency mismatches, soak drift and SLOs.
+type summaryCheck struct {
+	OK     bool
+	Name   string
+	Detail string
+}
+
+// sloLimits are the --slo-* thresholds every config/mode/concurrency cell is checked against; zero is off.
+type sloLimits struct {
+	P99Ms     float64
+	ErrorRate float64
+	MinRPS    float64
+}
+
+func (l sloLimits) set() bool {
+	return l.P99Ms > 0 || l.ErrorRate > 0 || l.MinRPS > 0
+}
+
+// check returns the SLO outcome of one cell, with every limit it was held to.
+func (l sloLimits) check(r Result) summaryCheck {
+	c := summaryCheck{OK: true, Name: fmt.Sprintf("slo %s %s c%d", r.Config, r.Mode, r.Concurrency)}
+	var parts []string
+	add := func(ok bool, format string, args ...any) {
+		if !ok {
+			c.OK = false
+			format += " ✗"
+		}
+		parts = append(parts, fmt.Sprintf(format, args...))
+	}
+	if l.P99Ms > 0 {
+		add(r.P99Ms <= l.P99Ms, "p99 %.1fms (max %.1f)", r.P99Ms, l.P99Ms)
+	}
+	if l.ErrorRate > 0 {
+		rate := 0.0
+		if r.Total > 0 {
+			rate = float64(r.Errors) / float64(r.Total)
+		}
+		add(rate <= l.ErrorRate, "errors %.2f%% (max %.2f%%)", rate*100, l.ErrorRate*100)
+	}
+	if l.MinRPS > 0 {
+		add(r.RPS >= l.MinRPS, "rps %.2f (min %.2f)", r.RPS, l.MinRPS)
+	}
+	c.Detail = strings.Join(parts, ", ")
+	return c
+}
+
+func summaryChecks(results []Result, slo sloLimits) []summaryCheck {
+	var out []summaryCheck
+	for _, r := range results {
+		if slo.set() && r.Mode != string(ModeVerify) {
+			out = append(out, slo.check(r))
+		}
+		if r.Mode == string(ModeVerify) {
+			out = append(out, summaryCheck{
+				OK:     r.VerifyMismatches == 0,
+				Name:   "consistency " + r.Config,
+				Detail: fmt.Sprintf("%d mismatches of %d compared", r.VerifyMismatches, r.VerifyChecked),
+			})
+		}
+		if len(r.SoakIntervals) > 0 {
+			c := summaryCheck{OK: len(r.SoakDrift) == 0, Name: fmt.Sprintf("soak %s %s c%d", r.Config, r.Mode, r.Concurrency), Detail: "no drift"}
+			if !c.OK {
+				c.Detail = strings.Join(r.SoakDrift, "; ")
+			}
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// writeMarkdownSummary writes summary.md: the results, the sanity comparison, the top errors and the checks
+// as GitHub-flavored Markdown, for pasting into PRs and incident docs.
+func writeMarkdownSummary(path string, results []Result, errorsSummary []errorSummaryEntry, checks []summaryCheck) error {
+	var b strings.Builder
+	b.WriteString("# Load test summary\n\n")
+	b.WriteString("Generated at " + time.Now().Format(time.RFC3339) + "\n")
+
+	b.WriteString("\n## Results\n")
+	for _, cfg := range uniqueConfigs(results) {
+		b.WriteString("\n### " + mdEsc(cfg) + "\n\n")
+		var rows []Result
+		for _, r := range results {
+			if r.Config == cfg {
+				rows = append(rows, r)
+			}
+		}
+		if rows[0].Targets != "" {
+			b.WriteString("Targets: `" + rows[0].Targets + "`\n\n")
+		}
+		b.WriteString("| Mode | Conc | Total | OK | Err | RPS | MB/s | Avg ms | P50 | P90 | P95 | P99 | Max |\n")
+		b.WriteString("|---|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|---:|\n")
+		for _, r := range rows {
+			fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | %.2f | %.2f | %.1f | %.1f | %.1f | %.1f | %.1f | %.1f |\n",
+				mdEsc(r.Mode), r.Concurrency, r.Total, r.Success, r.Errors, r.RPS, r.MBps, r.AvgMs, r.P50Ms, r.P90Ms, r.P95Ms, r.P99Ms, r.MaxMs)
+		}
+	}
+
+	if groups := sanityGroups(results); len(groups) > 0 {
+		b.WriteString("\n## Sanity check\n\nRPS and latency across configs for identical mode/concurrency, against the best config.\n")
+		for _, g := range groups {
+			b.WriteString("\n**" + mdEsc(g.Mode) + " · concurrency " + strconv.Itoa(g.Conc) + "**\n\n")
+			b.WriteString("| Config | RPS | Avg ms | P50 | ΔRPS vs best | ΔAvg vs best |\n")
+			b.WriteString("|---|---:|---:|---:|---:|---:|\n")
+			for _, r := range g.Results {
+				fmt.Fprintf(&b, "| %s | %.2f | %.1f | %.1f | %+.0f%% | %+.0f%% |\n",
+					mdEsc(r.Config), r.RPS, r.AvgMs, r.P50Ms, percentDelta(r.RPS, g.BestRPS), percentDelta(r.AvgMs, g.BestAvg))
+			}
+		}
+	}
+
+	if len(errorsSummary) > 0 {
+		b.WriteString("\n## Top errors\n\n")
+		b.WriteString("| Count | Config | Mode | Conc | Request | Code | Error |\n")
+		b.WriteString("|---:|---|---|---:|---|---|---|\n")
+		for _, e := range errorsSummary[:min(len(errorsSummary), summaryTopErrors)] {
+			fmt.Fprintf(&b, "| %d | %s | %s | %d | %s | `%s` | %s |\n",
+				e.Count, mdEsc(e.Config), mdEsc(e.Mode), e.Concurrency, mdEsc(e.Request), e.Code, mdEsc(truncateText(e.Error, 160)))
+		}
+		if len(errorsSummary) > summaryTopErrors {
+			fmt.Fprintf(&b, "\n%d more error groups in `errors.csv`.\n", len(errorsSummary)-summaryTopErrors)
+		}
+	}
+
+	if len(checks) > 0 {
+		b.WriteString("\n## Checks\n\n")
+		for _, c := range checks {
+			verdict := "✅ pass"
+			if !c.OK {
+				verdict = "❌ fail"
+			}
+			b.WriteString("- " + verdict + " — " + mdEsc(c.Name) + ": " + mdEsc(c.Detail) + "\n")
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+var mdReplacer = strings.NewReplacer("|", `\|`, "\r", " ", "\n", " ")
+
+func mdEsc(s string) string {
+	return mdReplacer.Replace(s)
+}
+
+func truncateText(s string, n int) string {
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// printRunSummary prints a compact version of summary.md at the end of a run.
+func printRunSummary(w io.Writer, results []Result, errorsSummary []errorSummaryEntry, checks []summaryCheck) {
+	fmt.Fprintln(w, "\n== Summary ==")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "config\tmode\tconc\tok\terr\trps\tp50\tp95\tp99")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%.1f\t%.1f\t%.1f\t%.1f\n",
+			r.Config, r.Mode, r.Concurrency, r.Success, r.Errors, r.RPS, r.P50Ms, r.P95Ms, r.P99Ms)
+	}
+	tw.Flush()
+	if len(errorsSummary) > 0 {
+		fmt.Fprintln(w, "Top errors:")
+		for _, e := range errorsSummary[:min(len(errorsSummary), 3)] {
+			fmt.Fprintf(w, "  %d× %s %s c%d %s [%s] %s\n", e.Count, e.Config, e.Mode, e.Concurrency, e.Request, e.Code, truncateText(e.Error, 100))
+		}
+	}
+	printChecks(w, checks)
+}
+
+func printChecks(w io.Writer, checks []summaryCheck) {
+	for _, c := range checks {
+		verdict := "PASS"
+		if !c.OK {
+			verdict = "FAIL"
+		}
+		fmt.Fprintf(w, "%s %s: %s\n", verdict, c.Name, c.Detail)
+	}
+}
+
+// checksFailed reports whether any check failed; the run then exits with status 1 once its reports are written.
+func checksFailed(checks []summaryCheck) bool {
+	for _, c := range checks {
+		if !c.OK {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package main
+
+import "testing"
+
+func TestSLOCheck(t *testing.T) {
+	r := Result{Config: "a", Mode: "blocks", Concurrency: 10, Total: 1000, Errors: 20, RPS: 50, P99Ms: 120}
+	tests := []struct {
+		name string
+		slo  sloLimits
+		ok   bool
+	}{
+		{"p99 within", sloLimits{P99Ms: 120}, true},
+		{"p99 over", sloLimits{P99Ms: 119.9}, false},
+		{"error rate within", sloLimits{ErrorRate: 0.02}, true},
+		{"error rate over", sloLimits{ErrorRate: 0.019}, false},
+		{"rps within", sloLimits{MinRPS: 50}, true},
+		{"rps under", sloLimits{MinRPS: 50.1}, false},
+		{"one of three fails", sloLimits{P99Ms: 200, ErrorRate: 0.5, MinRPS: 60}, false},
+		{"all pass", sloLimits{P99Ms: 200, ErrorRate: 0.5, MinRPS: 10}, true},
+	}
+	for _, tt := range tests {
+		if c := tt.slo.check(r); c.OK != tt.ok {
+			t.Errorf("%s: ok = %v (%s), want %v", tt.name, c.OK, c.Detail, tt.ok)
+		}
+	}
+
+	empty := Result{Mode: "blocks"}
+	if c := (sloLimits{ErrorRate: 0.01}).check(empty); !c.OK {
+		t.Errorf("a cell without requests failed the error rate: %s", c.Detail)
+	}
+}
+
+func TestSummaryChecks(t *testing.T) {
+	ok := Result{Config: "a", Mode: "blocks", Concurrency: 5, Total: 10, RPS: 100, P99Ms: 10}
+	slow := ok
+	slow.P99Ms = 500
+	verify := Result{Config: "a", Mode: string(ModeVerify)}
+	verify.VerifyChecked = 10
+	verify.VerifyMismatches = 1
+
+	if checks := summaryChecks([]Result{ok, slow}, sloLimits{}); len(checks) != 0 {
+		t.Fatalf("checks without SLOs: %+v", checks)
+	}
+	checks := summaryChecks([]Result{ok, slow, verify}, sloLimits{P99Ms: 100})
+	if len(checks) != 3 {
+		t.Fatalf("got %d checks, want 3 (verify is not held to SLOs)", len(checks))
+	}
+	if !checks[0].OK || checks[1].OK || checks[2].OK {
+		t.Fatalf("verdicts %+v", checks)
+	}
+}
+
+func TestChecksFailed(t *testing.T) {
+	if checksFailed(nil) {
+		t.Error("no checks failed")
+	}
+	if checksFailed([]summaryCheck{{OK: true}, {OK: true}}) {
+		t.Error("passing checks failed")
+	}
+	if !checksFailed([]summaryCheck{{OK: true}, {OK: false}}) {
+		t.Error("a failed check passed")
+	}
+}
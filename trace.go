@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	traceBatch     = 512
+	traceFlushTick = time.Second
+	// tracePostTimeout bounds one export, so a stalled collector cannot hold up the end of the run.
+	tracePostTimeout = 10 * time.Second
+	// traceThresholdTick is how often a cell's tail-sampling threshold is recomputed from its histogram.
+	traceThresholdTick = time.Second
+)
+
+// traceExporter sends finished job traces as OTLP/JSON, either posted to a collector or appended to a file
+// one export request per line. Jobs hand their traces over without waiting: when the exporter falls behind,
+// traces are dropped and counted.
+type traceExporter struct {
+	endpoint string
+	client   *http.Client
+	f        *os.File
+	w        *bufio.Writer
+	resource []otlpAttr
+
+	// tail sampling: keep the slowest percent of each cell's jobs, and failing jobs when keepErrors is set
+	slowest    float64
+	keepErrors bool
+	mu         sync.Mutex
+	cells      map[cellKey]*traceCell
+
+	ch      chan []otlpSpan
+	wg      sync.WaitGroup
+	dropped int64
+	sent    int64
+	failed  error
+}
+
+// traceCell is the latency histogram of a cell's jobs so far and the bucket from which jobs are kept,
+// derived from it every traceThresholdTick.
+type traceCell struct {
+	bins      map[int]int64
+	total     int64
+	threshold int
+	until     time.Time
+}
+
+// newTraceExporter exports to target: an http(s) URL of an OTLP/HTTP collector (the path defaults to
+// /v1/traces) or a file path, appended to when appendMode is set (used by --resume).
+func newTraceExporter(target string, appendMode bool, slowest float64, keepErrors bool, resource map[string]string) (*traceExporter, error) {
+	t := &traceExporter{
+		slowest:    slowest,
+		keepErrors: keepErrors,
+		cells:      map[cellKey]*traceCell{},
+		ch:         make(chan []otlpSpan, 4096),
+	}
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, err
+		}
+		if u.Path == "" || u.Path == "/" {
+			u.Path = "/v1/traces"
+		}
+		t.endpoint = u.String()
+		t.client = &http.Client{Timeout: tracePostTimeout}
+	} else {
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if appendMode {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		f, err := os.OpenFile(target, flags, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		t.f = f
+		t.w = bufio.NewWriter(f)
+	}
+	t.resource = []otlpAttr{strAttr("service.name", "ls-load")}
+	keys := make([]string, 0, len(resource))
+	for k := range resource {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		t.resource = append(t.resource, strAttr(k, resource[k]))
+	}
+	t.wg.Add(1)
+	go t.loop()
+	return t, nil
+}
+
+func (t *traceExporter) loop() {
+	defer t.wg.Done()
+	tick := time.NewTicker(traceFlushTick)
+	defer tick.Stop()
+	var batch []otlpSpan
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.export(batch); err != nil && t.failed == nil {
+			t.failed = err
+			fmt.Printf("trace export failed: %v\n", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case spans, ok := <-t.ch:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, spans...)
+			if len(batch) >= traceBatch {
+				flush()
+			}
+		case <-tick.C:
+			flush()
+		}
+	}
+}
+
+func (t *traceExporter) export(spans []otlpSpan) error {
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: t.resource},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "ls-load"}, Spans: spans}},
+	}}})
+	if err != nil {
+		return err
+	}
+	if t.w != nil {
+		t.w.Write(body)
+		t.sent += int64(len(spans))
+		return t.w.WriteByte('\n')
+	}
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", t.endpoint, resp.Status)
+	}
+	t.sent += int64(len(spans))
+	return nil
+}
+
+// Close sends the traces still queued.
+func (t *traceExporter) Close() {
+	if t == nil {
+		return
+	}
+	close(t.ch)
+	t.wg.Wait()
+	if t.f != nil {
+		t.w.Flush()
+		t.f.Close()
+	}
+	fmt.Printf("Traces: exported %d spans", t.sent)
+	if t.dropped > 0 {
+		fmt.Printf(", dropped %d traces the exporter could not keep up with", t.dropped)
+	}
+	fmt.Println()
+}
+
+// keep is the tail-sampling decision for a finished job. A job is kept when fewer than the slowest
+// percent of the cell's jobs were slower, as of the cell's last threshold update.
+func (t *traceExporter) keep(cell cellKey, now time.Time, d time.Duration, failed bool) bool {
+	if failed && t.keepErrors {
+		return true
+	}
+	if t.slowest >= 100 {
+		return true
+	}
+	b := histBucket(d.Milliseconds())
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.cells[cell]
+	if c == nil {
+		c = &traceCell{bins: map[int]int64{}}
+		t.cells[cell] = c
+	}
+	c.bins[b]++
+	c.total++
+	if !now.Before(c.until) {
+		c.threshold = c.keepFrom(t.slowest)
+		c.until = now.Add(traceThresholdTick)
+	}
+	return b >= c.threshold
+}
+
+// keepFrom is the lowest bucket with fewer than the slowest percent of the cell's jobs above it.
+func (c *traceCell) keepFrom(slowest float64) int {
+	bins := make([]int, 0, len(c.bins))
+	for b := range c.bins {
+		bins = append(bins, b)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(bins)))
+	limit := slowest / 100 * float64(c.total)
+	threshold := math.MaxInt
+	var slower int64
+	for _, b := range bins {
+		if float64(slower) >= limit {
+			break
+		}
+		threshold = b
+		slower += c.bins[b]
+	}
+	return threshold
+}
+
+// traceSpan is the root span of one job. Requests the job makes become its child spans; the whole trace
+// is exported when the job ends, if the sampler keeps it.
+type traceSpan struct {
+	t        *traceExporter
+	cell     cellKey
+	root     otlpSpan
+	start    time.Time
+	mu       sync.Mutex
+	children []otlpSpan
+	// params are the set attributes as key, value pairs, kept for error samples
+	params []string
+}
+
+// job starts the root span of a job. It returns nil when tracing is off; every traceSpan method is nil-safe.
+func (t *traceExporter) job(cfg, targets, mode string, conc int) *traceSpan {
+	if t == nil {
+		return nil
+	}
+	var traceID [16]byte
+	binary.BigEndian.PutUint64(traceID[:8], rand.Uint64())
+	binary.BigEndian.PutUint64(traceID[8:], rand.Uint64())
+	s := &traceSpan{t: t, cell: cellKey{cfg, mode, conc}, start: time.Now()}
+	s.root = otlpSpan{
+		TraceID: hex.EncodeToString(traceID[:]),
+		SpanID:  newSpanID(),
+		Name:    mode + " job",
+		Kind:    otlpKindInternal,
+		Attributes: []otlpAttr{
+			strAttr("ls_load.config", cfg),
+			strAttr("ls_load.mode", mode),
+			intAttr("ls_load.concurrency", int64(conc)),
+		},
+	}
+	if targets != "" {
+		s.root.Attributes = append(s.root.Attributes, strAttr("ls_load.targets", targets))
+	}
+	return s
+}
+
+// set adds an attribute describing the job's input, e.g. the block seqno or account.
+func (s *traceSpan) set(key string, v any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.params = append(s.params, key, fmt.Sprint(v))
+	if s.t == nil {
+		return
+	}
+	switch v := v.(type) {
+	case int:
+		s.root.Attributes = append(s.root.Attributes, intAttr(key, int64(v)))
+	case int32:
+		s.root.Attributes = append(s.root.Attributes, intAttr(key, int64(v)))
+	case uint32:
+		s.root.Attributes = append(s.root.Attributes, intAttr(key, int64(v)))
+	case int64:
+		s.root.Attributes = append(s.root.Attributes, intAttr(key, v))
+	default:
+		s.root.Attributes = append(s.root.Attributes, strAttr(key, fmt.Sprint(v)))
+	}
+}
+
+// request records a liteserver call of the job as a child span.
+func (s *traceSpan) request(req string, start time.Time, respBytes, exitCode int, err error) {
+	if s == nil || s.t == nil {
+		return
+	}
+	c := otlpSpan{
+		TraceID:      s.root.TraceID,
+		SpanID:       newSpanID(),
+		ParentSpanID: s.root.SpanID,
+		Name:         req,
+		Kind:         otlpKindClient,
+		Start:        strconv.FormatInt(start.UnixNano(), 10),
+		End:          strconv.FormatInt(time.Now().UnixNano(), 10),
+	}
+	if respBytes > 0 {
+		c.Attributes = append(c.Attributes, intAttr("ls_load.resp_bytes", int64(respBytes)))
+	}
+	if exitCode != 0 {
+		c.Attributes = append(c.Attributes, intAttr("ls_load.exit_code", int64(exitCode)))
+	}
+	if err != nil {
+		c.Attributes = append(c.Attributes, strAttr("error.type", classifyError(err.Error())))
+		c.Status = &otlpStatus{Code: otlpStatusError, Message: err.Error()}
+	}
+	s.mu.Lock()
+	s.children = append(s.children, c)
+	s.mu.Unlock()
+}
+
+// end finishes the job with the error it returned and hands the trace to the exporter if it is sampled.
+func (s *traceSpan) end(errp *error) {
+	if s == nil || s.t == nil {
+		return
+	}
+	now := time.Now()
+	var err error
+	if errp != nil {
+		err = *errp
+	}
+	if !s.t.keep(s.cell, now, now.Sub(s.start), err != nil) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.root.Start = strconv.FormatInt(s.start.UnixNano(), 10)
+	s.root.End = strconv.FormatInt(now.UnixNano(), 10)
+	if err != nil {
+		s.root.Attributes = append(s.root.Attributes, strAttr("error.type", classifyError(err.Error())))
+		s.root.Status = &otlpStatus{Code: otlpStatusError, Message: err.Error()}
+	}
+	spans := append([]otlpSpan{s.root}, s.children...)
+	select {
+	case s.t.ch <- spans:
+	default:
+		s.t.mu.Lock()
+		s.t.dropped++
+		s.t.mu.Unlock()
+	}
+}
+
+// paramMap returns the job's params, nil when none were set.
+func (s *traceSpan) paramMap() map[string]string {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.params) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(s.params)/2)
+	for i := 0; i+1 < len(s.params); i += 2 {
+		m[s.params[i]] = s.params[i+1]
+	}
+	return m
+}
+
+func newSpanID() string {
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], rand.Uint64())
+	return hex.EncodeToString(id[:])
+}
+
+// OTLP/JSON trace export (ExportTraceServiceRequest). IDs are hex and 64-bit integers are strings,
+// as the OTLP JSON mapping requires.
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttr `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+const (
+	otlpKindInternal = 1
+	otlpKindClient   = 3
+	otlpStatusError  = 2
+)
+
+type otlpSpan struct {
+	TraceID      string      `json:"traceId"`
+	SpanID       string      `json:"spanId"`
+	ParentSpanID string      `json:"parentSpanId,omitempty"`
+	Name         string      `json:"name"`
+	Kind         int         `json:"kind"`
+	Start        string      `json:"startTimeUnixNano"`
+	End          string      `json:"endTimeUnixNano"`
+	Attributes   []otlpAttr  `json:"attributes,omitempty"`
+	Status       *otlpStatus `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttr struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	String *string `json:"stringValue,omitempty"`
+	Int    *string `json:"intValue,omitempty"`
+}
+
+func strAttr(key, v string) otlpAttr {
+	return otlpAttr{Key: key, Value: otlpAnyValue{String: &v}}
+}
+
+func intAttr(key string, v int64) otlpAttr {
+	s := strconv.FormatInt(v, 10)
+	return otlpAttr{Key: key, Value: otlpAnyValue{Int: &s}}
+}
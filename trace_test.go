@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTraceKeepFrom(t *testing.T) {
+	c := &traceCell{bins: map[int]int64{}}
+	for ms := int64(1); ms <= 1000; ms++ {
+		c.bins[histBucket(ms)]++
+		c.total++
+	}
+	for _, slowest := range []float64{0, 0.5, 1, 5, 50, 99} {
+		from := c.keepFrom(slowest)
+		// the cached bucket keeps exactly the buckets a scan over the histogram would
+		for b := histBucket(0); b <= histBucket(1000)+1; b++ {
+			var slower int64
+			for bin, n := range c.bins {
+				if bin > b {
+					slower += n
+				}
+			}
+			if want := float64(slower) < slowest/100*float64(c.total); (b >= from) != want {
+				t.Fatalf("slowest %.1f%%: bucket %d kept %v, want %v (threshold %d)", slowest, b, b >= from, want, from)
+			}
+		}
+	}
+}
+
+func TestTraceKeepCached(t *testing.T) {
+	te := &traceExporter{slowest: 10, keepErrors: true, cells: map[cellKey]*traceCell{}}
+	cell := cellKey{Config: "c", Mode: "blocks"}
+	now := time.Unix(0, 0)
+	for i := 1; i <= 100; i++ {
+		te.keep(cell, now, time.Duration(i)*time.Millisecond, false)
+	}
+	// the threshold was set by the first job and holds until the tick
+	if !te.keep(cell, now, time.Millisecond, false) {
+		t.Fatal("threshold moved before the tick")
+	}
+	now = now.Add(traceThresholdTick)
+	if te.keep(cell, now, 10*time.Millisecond, false) {
+		t.Fatal("fast job kept after the threshold update")
+	}
+	if !te.keep(cell, now, 500*time.Millisecond, false) {
+		t.Fatal("slowest job dropped")
+	}
+	if !te.keep(cell, now, time.Millisecond, true) {
+		t.Fatal("failed job dropped with keepErrors")
+	}
+}
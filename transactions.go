@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteapi"
+	"github.com/tonkeeper/tongo/liteclient"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	// maxTxPageSize is the liteserver limit for GetTransactions.
+	maxTxPageSize = 16
+	// txIDSize is the wire size of liteServer.transactionId with account, lt and hash set.
+	txIDSize = 4 + 32 + 8 + 32
+)
+
+type pageStat struct {
+	Page     int     `json:"page"`
+	Requests int     `json:"requests"`
+	Txs      int     `json:"txs"`
+	AvgMs    float64 `json:"avg_ms"`
+	P50Ms    float64 `json:"p50_ms"`
+	P95Ms    float64 `json:"p95_ms"`
+	P99Ms    float64 `json:"p99_ms"`
+}
+
+// pageRecorder collects per-page latencies of history walks, indexed by page depth (1-based).
+type pageRecorder struct {
+	mu        sync.Mutex
+	latencies [][]int64
+	txs       []int
+}
+
+func (p *pageRecorder) add(page int, latencyMs int64, txs int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.latencies) < page {
+		p.latencies = append(p.latencies, nil)
+		p.txs = append(p.txs, 0)
+	}
+	p.latencies[page-1] = append(p.latencies[page-1], latencyMs)
+	p.txs[page-1] += txs
+}
+
+func (p *pageRecorder) stats() []pageStat {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]pageStat, 0, len(p.latencies))
+	for i, vals := range p.latencies {
+		avg, p50, _, p95, p99, _ := computeMetrics(vals, len(vals))
+		out = append(out, pageStat{
+			Page:     i + 1,
+			Requests: len(vals),
+			Txs:      p.txs[i],
+			AvgMs:    avg,
+			P50Ms:    p50,
+			P95Ms:    p95,
+			P99Ms:    p99,
+		})
+	}
+	return out
+}
+
+func lastTransaction(raw liteclient.LiteServerTransactionListC) (tlb.Transaction, int, error) {
+	if len(raw.Transactions) == 0 {
+		return tlb.Transaction{}, 0, nil
+	}
+	cells, err := boc.DeserializeBoc(raw.Transactions)
+	if err != nil {
+		return tlb.Transaction{}, 0, err
+	}
+	var tx tlb.Transaction
+	if err := tlb.Unmarshal(cells[len(cells)-1], &tx); err != nil {
+		return tlb.Transaction{}, 0, err
+	}
+	return tx, len(cells), nil
+}
+
+// runTransactionsTest walks each account's history backwards from its last transaction,
+// pages pages deep with pageSize transactions per GetTransactions call.
+func runTransactionsTest(env *runEnv, clients *clientSet, cfgName, targets string, accounts []ton.AccountID, pages, pageSize int, conc int, timeout time.Duration, duration time.Duration, logger *reqLogger, rng *lockedRand) Result {
+	fmt.Printf("transactions: concurrency=%d, total=%d, pages=%d\n", conc, len(accounts), pages)
+	if pageSize <= 0 || pageSize > maxTxPageSize {
+		pageSize = maxTxPageSize
+	}
+	mode := string(ModeTransactions)
+	start := time.Now()
+	rec := &pageRecorder{}
+	work := clients.wrap(func(api *liteapi.Client, i int) (err error) {
+		span := env.job(cfgName, targets, mode, conc)
+		defer span.end(&err)
+		addr := accounts[rng.stream().Intn(len(accounts))]
+		span.set("ton.account", addr.ToRaw())
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		t0 := time.Now()
+		state, err := api.GetAccountState(ctx, addr)
+		cancel()
+		env.logRequest(logger, span, cfgName, targets, mode, conc, "GetAccountState", t0, 0, err)
+		if err != nil {
+			return err
+		}
+		lt, hash := state.LastTransLt, ton.Bits256(state.LastTransHash)
+		for page := 1; page <= pages && lt != 0; page++ {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			t1 := time.Now()
+			raw, err := api.GetTransactionsRaw(ctx, uint32(pageSize), addr, lt, hash)
+			cancel()
+			env.logRequest(logger, span, cfgName, targets, mode, conc, "GetTransactions", t1, len(raw.Transactions), err)
+			if err != nil {
+				if e, ok := err.(liteclient.LiteServerErrorC); ok && int32(e.Code) == -400 {
+					// history is truncated on this node
+					return nil
+				}
+				return err
+			}
+			tx, n, err := lastTransaction(raw)
+			if err != nil {
+				return err
+			}
+			rec.add(page, time.Since(t1).Milliseconds(), n)
+			if page == 1 && len(raw.Ids) > 0 {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				t2 := time.Now()
+				_, err := api.GetOneTransactionFromBlock(ctx, addr, raw.Ids[0].ToBlockIdExt(), lt)
+				cancel()
+				env.logRequest(logger, span, cfgName, targets, mode, conc, "GetOneTransaction", t2, 0, err)
+				if err != nil {
+					return err
+				}
+			}
+			if n < pageSize {
+				break
+			}
+			lt, hash = tx.PrevTransLt, ton.Bits256(tx.PrevTransHash)
+		}
+		return nil
+	})
+
+	jr := env.runWorkload(cfgName, mode, len(accounts), conc, duration, work)
+	res := env.finishResult(jr, mode, conc, len(accounts), duration, start)
+	res.Pages = rec.stats()
+	clients.apply(&res)
+	return res
+}
+
+// runBlockTransactionsTest lists all transactions of a random shard block (or the masterchain block itself)
+// for masterchain seqnos in the --blocks window, paging ListBlockTransactions until complete.
+func runBlockTransactionsTest(env *runEnv, clients *clientSet, cfgName, targets string, seqs []int32, conc int, timeout time.Duration, duration time.Duration, logger *reqLogger, randomBlocks bool, blocksRefresh time.Duration, rng *lockedRand, br blockRange) Result {
+	mode := string(ModeTransactions) + ":blocks"
+	fmt.Printf("%s: concurrency=%d, total=%d\n", mode, conc, len(seqs))
+	start := time.Now()
+	var picker *blockPicker
+	if randomBlocks {
+		picker = newBlockPicker(clients.primary(), br, blocksRefresh)
+	}
+	rec := &pageRecorder{}
+	work := clients.wrap(func(api *liteapi.Client, i int) (err error) {
+		span := env.job(cfgName, targets, mode, conc)
+		defer span.end(&err)
+		r := rng.stream()
+		seq := seqs[i%len(seqs)]
+		if picker != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			ps, err := picker.pick(ctx, r)
+			cancel()
+			if err != nil {
+				return err
+			}
+			seq = ps
+		}
+		span.set("ton.block.seqno", seq)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		t0 := time.Now()
+		mcBlock, err := api.WaitMasterchainBlock(ctx, uint32(seq), 15*time.Second)
+		cancel()
+		env.logRequest(logger, span, cfgName, targets, mode, conc, "WaitMasterchainBlock", t0, 0, err)
+		if err != nil {
+			return err
+		}
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+		t1 := time.Now()
+		shards, err := api.GetAllShardsInfo(ctx, mcBlock)
+		cancel()
+		env.logRequest(logger, span, cfgName, targets, mode, conc, "GetAllShardsInfo", t1, 0, err)
+		if err != nil {
+			return err
+		}
+		shards = append(shards, mcBlock)
+		block := shards[r.Intn(len(shards))]
+
+		var after *liteclient.LiteServerTransactionId3C
+		for page := 1; ; page++ {
+			listMode := uint32(7)
+			if after != nil {
+				listMode |= 128
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			t2 := time.Now()
+			raw, err := api.ListBlockTransactionsRaw(ctx, block, listMode, 40, after)
+			cancel()
+			env.logRequest(logger, span, cfgName, targets, mode, conc, "ListBlockTransactions", t2, len(raw.Proof)+len(raw.Ids)*txIDSize, err)
+			if err != nil {
+				return err
+			}
+			rec.add(page, time.Since(t2).Milliseconds(), len(raw.Ids))
+			if !raw.Incomplete || len(raw.Ids) == 0 {
+				return nil
+			}
+			last := raw.Ids[len(raw.Ids)-1]
+			if last.Account == nil || last.Lt == nil {
+				return nil
+			}
+			after = &liteclient.LiteServerTransactionId3C{Account: *last.Account, Lt: *last.Lt}
+		}
+	})
+
+	jr := env.runWorkload(cfgName, mode, len(seqs), conc, duration, work)
+	res := env.finishResult(jr, mode, conc, len(seqs), duration, start)
+	res.Pages = rec.stats()
+	clients.apply(&res)
+	return res
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tonkeeper/tongo/boc"
+	"github.com/tonkeeper/tongo/liteapi"
+	"github.com/tonkeeper/tongo/tlb"
+	"github.com/tonkeeper/tongo/ton"
+)
+
+const (
+	// verifyLag is how many masterchain blocks behind the tip the fixed block is,
+	// so a server a few seconds behind still has it.
+	verifyLag = 8
+	// verifyMaxDiffs bounds the mismatch samples kept per config.
+	verifyMaxDiffs = 50
+)
+
+// VerifyStats is how many of a config's answers were compared in verify mode and which differed from the reference.
+type VerifyStats struct {
+	VerifyChecked    int              `json:"verify_checked,omitempty"`
+	VerifyMismatches int              `json:"verify_mismatches,omitempty"`
+	VerifyDiffs      []verifyMismatch `json:"verify_diffs,omitempty"`
+}
+
+type verifyRequest struct {
+	Kind    string
+	Params  string
+	Seqno   uint32
+	Block   ton.BlockIDExt
+	Account ton.AccountID
+	Method  string
+	Stack   tlb.VmStack
+}
+
+// verifyPlan is the fixed request set every config answers. It is built once, on the first config.
+type verifyPlan struct {
+	Ref      ton.BlockIDExt
+	Requests []verifyRequest
+}
+
+type verifyAnswers struct {
+	hashes []string
+	errs   []string
+}
+
+type verifyMismatch struct {
+	Kind       string   `json:"kind"`
+	Params     string   `json:"params"`
+	Hash       string   `json:"hash"`
+	Reference  string   `json:"reference"`
+	RefConfigs []string `json:"ref_configs"`
+}
+
+// buildVerifyPlan picks a fixed masterchain block and up to limit requests of each kind:
+// LookupBlock and GetBlockRaw over the --blocks window, account states and get-methods at the fixed block.
+func buildVerifyPlan(api *liteapi.Client, seqs []int32, accounts []ton.AccountID, calls []methodCall, limit int, timeout time.Duration) (*verifyPlan, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	info, err := api.GetMasterchainInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	refSeqno := info.Last.Seqno
+	if refSeqno > verifyLag {
+		refSeqno -= verifyLag
+	}
+	ref, _, err := api.LookupBlock(ctx, ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: refSeqno}, 1, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("lookup fixed block %d: %w", refSeqno, err)
+	}
+	plan := &verifyPlan{Ref: ref}
+
+	var window []int32
+	for _, s := range seqs {
+		if uint32(s) <= refSeqno {
+			window = append(window, s)
+		}
+	}
+	for _, s := range spread(window, limit) {
+		seqno := uint32(s)
+		plan.Requests = append(plan.Requests, verifyRequest{Kind: "LookupBlock", Params: fmt.Sprintf("seqno=%d", seqno), Seqno: seqno})
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		id, _, err := api.LookupBlock(ctx, ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: seqno}, 1, nil, nil)
+		cancel()
+		if err != nil {
+			continue
+		}
+		plan.Requests = append(plan.Requests, verifyRequest{Kind: "GetBlockRaw", Params: fmt.Sprintf("block=%d:%x", seqno, id.RootHash), Block: id})
+	}
+	for _, a := range spread(accounts, limit) {
+		plan.Requests = append(plan.Requests, verifyRequest{Kind: "GetAccountStateRaw", Params: fmt.Sprintf("account=%s block=%d", a.ToRaw(), ref.Seqno), Account: a})
+	}
+	for _, c := range spread(calls, limit) {
+		params := fmt.Sprintf("account=%s method=%s block=%d", c.Account.ToRaw(), c.Method, ref.Seqno)
+		if len(c.Args) > 0 {
+			params += fmt.Sprintf(" args=%v", c.Args)
+		}
+		plan.Requests = append(plan.Requests, verifyRequest{Kind: "RunSmcMethod", Params: params, Account: c.Account, Method: c.Method, Stack: c.Stack})
+	}
+	return plan, nil
+}
+
+// spread takes up to n items evenly over list.
+func spread[T any](list []T, n int) []T {
+	if n <= 0 || len(list) <= n {
+		return list
+	}
+	out := make([]T, 0, n)
+	for i := 0; i < n; i++ {
+		out = append(out, list[i*len(list)/n])
+	}
+	return out
+}
+
+// cellHashHex hashes a BoC by its root cell, so equal data with a different serialization still matches.
+func cellHashHex(raw []byte) (string, error) {
+	if len(raw) == 0 {
+		return "empty", nil
+	}
+	cells, err := boc.DeserializeBoc(raw)
+	if err != nil {
+		return "", err
+	}
+	h, err := cells[0].Hash()
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h), nil
+}
+
+// answer runs one request and reduces the response to a comparable hash.
+func (q verifyRequest) answer(ctx context.Context, api *liteapi.Client, ref ton.BlockIDExt) (string, int, error) {
+	switch q.Kind {
+	case "LookupBlock":
+		id, _, err := api.LookupBlock(ctx, ton.BlockID{Workchain: -1, Shard: masterchainShard, Seqno: q.Seqno}, 1, nil, nil)
+		if err != nil {
+			return "", 0, err
+		}
+		return fmt.Sprintf("%x:%x", id.RootHash, id.FileHash), 0, nil
+	case "GetBlockRaw":
+		raw, err := api.GetBlockRaw(ctx, q.Block)
+		if err != nil {
+			return "", 0, err
+		}
+		h, err := cellHashHex(raw.Data)
+		return h, len(raw.Data), err
+	case "GetAccountStateRaw":
+		raw, err := api.WithBlock(ref).GetAccountStateRaw(ctx, q.Account)
+		if err != nil {
+			return "", 0, err
+		}
+		h, err := cellHashHex(raw.State)
+		return h, len(raw.State) + len(raw.Proof) + len(raw.ShardProof), err
+	case "RunSmcMethod":
+		code, stack, err := api.WithBlock(ref).RunSmcMethod(ctx, q.Account, q.Method, q.Stack)
+		if errors.Is(err, liteapi.ErrAccountNotFound) {
+			return "account_not_found", 0, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		b, err := stack.MarshalTL()
+		if err != nil {
+			return "", 0, err
+		}
+		h := sha256.Sum256(append(binary.BigEndian.AppendUint32(nil, code), b...))
+		return fmt.Sprintf("exit=%d:%x", int32(code), h), len(b), nil
+	default:
+		return "", 0, fmt.Errorf("unknown verify request %q", q.Kind)
+	}
+}
+
+// runVerifyTest sends every request of the plan once and keeps the answers for compareVerify.
+func runVerifyTest(env *runEnv, clients *clientSet, cfgName, targets string, plan *verifyPlan, conc int, timeout time.Duration, logger *reqLogger) (Result, *verifyAnswers) {
+	mode := string(ModeVerify)
+	fmt.Printf("%s: concurrency=%d, requests=%d, block=%d\n", mode, conc, len(plan.Requests), plan.Ref.Seqno)
+	start := time.Now()
+	ans := &verifyAnswers{hashes: make([]string, len(plan.Requests)), errs: make([]string, len(plan.Requests))}
+	work := clients.wrap(func(api *liteapi.Client, i int) (err error) {
+		span := env.job(cfgName, targets, mode, conc)
+		defer span.end(&err)
+		q := plan.Requests[i]
+		span.set("ton.block.seqno", plan.Ref.Seqno)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		t0 := time.Now()
+		h, n, err := q.answer(ctx, api, plan.Ref)
+		env.logRequest(logger, span, cfgName, targets, mode, conc, q.Kind, t0, n, err)
+		if err != nil {
+			ans.errs[i] = err.Error()
+			return err
+		}
+		ans.hashes[i] = h
+		return nil
+	})
+
+	jr := runJobs(len(plan.Requests), conc, work)
+	res := env.finishResult(jr, mode, conc, len(plan.Requests), 0, start)
+	clients.apply(&res)
+	return res, ans
+}
+
+// compareVerify compares answers per request across configs. The reference answer is the most common
+// one (ties go to the config listed first); configs that answered differently get a mismatch.
+// Errors are not mismatches: they are already counted as errors of the verify result.
+func compareVerify(plan *verifyPlan, order []string, answers map[string]*verifyAnswers) (checked map[string]int, diffs map[string][]verifyMismatch, mismatches map[string]int) {
+	checked, diffs, mismatches = map[string]int{}, map[string][]verifyMismatch{}, map[string]int{}
+	for i, q := range plan.Requests {
+		votes := map[string][]string{}
+		var ref string
+		for _, cfg := range order {
+			a := answers[cfg]
+			if a == nil || a.hashes[i] == "" {
+				continue
+			}
+			h := a.hashes[i]
+			votes[h] = append(votes[h], cfg)
+			if ref == "" || len(votes[h]) > len(votes[ref]) {
+				ref = h
+			}
+		}
+		answered := 0
+		for _, cfgs := range votes {
+			answered += len(cfgs)
+		}
+		if answered < 2 {
+			continue
+		}
+		for h, cfgs := range votes {
+			for _, cfg := range cfgs {
+				checked[cfg]++
+				if h == ref {
+					continue
+				}
+				mismatches[cfg]++
+				if len(diffs[cfg]) < verifyMaxDiffs {
+					diffs[cfg] = append(diffs[cfg], verifyMismatch{Kind: q.Kind, Params: q.Params, Hash: h, Reference: ref, RefConfigs: votes[ref]})
+				}
+			}
+		}
+	}
+	return checked, diffs, mismatches
+}
+
+// applyVerify fills the consistency fields of verify results once every config has answered.
+func applyVerify(results []Result, plan *verifyPlan, order []string, answers map[string]*verifyAnswers) {
+	if plan == nil {
+		return
+	}
+	if len(answers) < 2 {
+		fmt.Printf("verify: only %d config(s) answered, nothing to compare\n", len(answers))
+	}
+	checked, diffs, mismatches := compareVerify(plan, order, answers)
+	for i := range results {
+		r := &results[i]
+		if r.Mode != string(ModeVerify) {
+			continue
+		}
+		r.VerifyChecked = checked[r.Config]
+		r.VerifyMismatches = mismatches[r.Config]
+		r.VerifyDiffs = diffs[r.Config]
+		fmt.Printf("verify: %s checked=%d mismatches=%d errors=%d\n", r.Config, r.VerifyChecked, r.VerifyMismatches, r.Errors)
+	}
+}